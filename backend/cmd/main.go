@@ -1,20 +1,40 @@
 package main
 
 import (
+	"backend/handler/accounthandler"
+	"backend/handler/audithandler"
 	"backend/handler/authhandler"
+	"backend/handler/companyhandler"
+	"backend/handler/imagehandler"
 	"backend/handler/jobhandler"
 	"backend/handler/messagehandler"
+	"backend/handler/orghandler"
+	"backend/pkg/keyring"
+	"backend/pkg/mailer"
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
 	"backend/pkg/model/authmodel"
 	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
 	"backend/pkg/pdfextractor"
+	"backend/pkg/repository/authrepo"
+	"backend/pkg/search"
+	"backend/pkg/service/accountservice"
+	"backend/pkg/service/auditservice"
 	"backend/pkg/service/authservice"
+	"backend/pkg/service/companyservice"
 	"backend/pkg/service/geminiservice"
+	"backend/pkg/service/imageservice"
 	"backend/pkg/service/jobservice"
 	"backend/pkg/service/messageservice"
+	"backend/pkg/service/orgservice"
+	"backend/pkg/storage"
 	"backend/routes"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	firebase "firebase.google.com/go"
 	"github.com/gofiber/fiber/v2"
@@ -50,43 +70,180 @@ func main() {
 		log.Fatal(errorWrapper)
 	}
 
-	if !db.Migrator().HasTable(&authmodel.User{}) {
-		log.Println("Tables do not exist. Running AutoMigrate...")
-		err = db.AutoMigrate(
-			&authmodel.User{},
-			&authmodel.CompanyProfile{},
-			&authmodel.Message{},
-			&authmodel.Notification{},
-			&jobmodel.JobPost{},
-			&jobmodel.JobApplication{},
-			&jobmodel.SavedJob{},
-			&jobmodel.Message{},
-		)
+	// Users from before email verification existed count as verified; see
+	// VerifyExistingEmails below.
+	verificationAdded := db.Migrator().HasTable(&authmodel.User{}) &&
+		!db.Migrator().HasColumn(&authmodel.User{}, "EmailVerified")
+
+	// AutoMigrate is idempotent: it creates missing tables and adds new columns,
+	// so it runs on every start to pick up schema additions.
+	log.Println("Running AutoMigrate...")
+	err = db.AutoMigrate(
+		&authmodel.User{},
+		&authmodel.CompanyProfile{},
+		&authmodel.Message{},
+		&authmodel.Notification{},
+		&authmodel.PasswordResetOTP{},
+		&authmodel.RefreshToken{},
+		&authmodel.LoginThrottle{},
+		&authmodel.LockoutEvent{},
+		&authmodel.MFARecoveryCode{},
+		&authmodel.APIKey{},
+		&jobmodel.JobPost{},
+		&jobmodel.JobApplication{},
+		&jobmodel.JobPostRevision{},
+		&jobmodel.SavedJob{},
+		&jobmodel.Message{},
+		&orgmodel.Organization{},
+		&orgmodel.OrganizationMember{},
+		&orgmodel.OrganizationInvitation{},
+		&auditmodel.AuditLog{},
+		&search.MySQLDocument{},
+	)
+	if err != nil {
+		log.Fatal("failed to auto migrate:", err)
+	}
+	log.Println("AutoMigrate completed.")
+	// mockdata.InsertMockData(db)
+	// --- Service Initialization ---
+	// Emails go out over SMTP.  MAILER=memory keeps them in process instead,
+	// for local development only: password resets and invitations never
+	// reach anyone.
+	var mailSender mailer.IMailer
+	switch kind := os.Getenv("MAILER"); kind {
+	case "", "smtp":
+		smtpMailer, err := mailer.NewSMTPMailerFromEnv()
 		if err != nil {
-			log.Fatal("failed to auto migrate:", err)
+			log.Fatal("failed to configure the SMTP mailer (set MAILER=memory for local development): ", err)
+		}
+		mailSender = smtpMailer
+	case "memory":
+		log.Println("MAILER=memory; emails are only kept in memory")
+		mailSender = mailer.NewMemoryMailer()
+	default:
+		log.Fatalf("unknown MAILER %q; expected smtp or memory", kind)
+	}
+	// Firebase sign-in is optional; it needs a service account credentials file.
+	var firebaseRepo authrepo.IFirebaseRepository
+	if credentialsFile := os.Getenv("FIREBASE_CREDENTIALS_FILE"); credentialsFile != "" {
+		repo, err := authrepo.NewFirebaseRepository(credentialsFile)
+		if err != nil {
+			log.Printf("Firebase sign-in disabled: %v", err)
+		} else {
+			firebaseRepo = repo
 		}
-		log.Println("AutoMigrate completed.")
 	} else {
-		log.Println("Tables already exist. Skipping AutoMigrate.")
+		log.Println("FIREBASE_CREDENTIALS_FILE not set; Firebase sign-in disabled")
 	}
-	// mockdata.InsertMockData(db)
-	// --- Service Initialization ---
-	authService := authservice.NewAuthService(db)
+	tokenKeyring, err := keyring.LoadFromEnv()
+	if err != nil {
+		log.Fatal("failed to load JWT keys:", err)
+	}
+	middleware.UseKeyring(tokenKeyring)
+
+	authService := authservice.NewAuthService(db, mailSender, firebaseRepo, tokenKeyring)
+	middleware.UseSessionValidator(authService) // Lets logout revoke outstanding access tokens
+	middleware.UseEmailVerificationChecker(authService)
+	middleware.UseAPIKeyAuthenticator(authService) // Lets integrations call the API with scoped keys
+	if verificationAdded {
+		if verified, err := authService.VerifyExistingEmails(); err != nil {
+			log.Fatal("failed to mark existing users as verified:", err)
+		} else if verified > 0 {
+			log.Printf("Marked the emails of %d existing users as verified", verified)
+		}
+	}
+	// Nobody can verify an email that is never delivered.
+	verifiedEmailPolicy := middleware.VerifiedEmailPolicyFromEnv(authmodel.UserTypeApplicant, authmodel.UserTypeCompany)
+	if _, inMemory := mailSender.(*mailer.MemoryMailer); inMemory && len(verifiedEmailPolicy) > 0 {
+		log.Fatal("REQUIRE_VERIFIED_EMAIL needs an SMTP mailer; set REQUIRE_VERIFIED_EMAIL=none with MAILER=memory")
+	}
+	middleware.UseVerifiedEmailPolicy(verifiedEmailPolicy...)
 	pdfExtractor := pdfextractor.NewPdfExtractor()
 
 	// Get Gemini API key from environment variable.
 	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
 	geminiEndpoint := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key="
 	geminiService := geminiservice.NewGeminiService(geminiAPIKey, geminiEndpoint) // Inject API Key
-	jobService := jobservice.NewJobService(db, pdfExtractor, geminiService)       // Inject PdfExtractor and GeminiService
+	// Uploaded files (resumes, profile images) are kept on local disk.
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+	fileStorage := storage.NewLocalStorage(uploadDir)
+	// Job search runs on MySQL FULLTEXT by default; SEARCH_BACKEND=memory keeps
+	// the index in process instead, e.g. for a single instance in development.
+	var searchIndex search.IIndex
+	switch backend := os.Getenv("SEARCH_BACKEND"); backend {
+	case "", "mysql":
+		searchIndex = search.NewMySQLIndex(db)
+	case "memory":
+		searchIndex = search.NewMemoryIndex()
+	default:
+		log.Fatalf("unknown SEARCH_BACKEND %q; expected mysql or memory", backend)
+	}
+	jobService := jobservice.NewJobService(db, pdfExtractor, geminiService, fileStorage, searchIndex) // Inject PdfExtractor, GeminiService, Storage and the search index
+	// ApplicantCount used to be left at zero; this also repairs any drift.
+	if fixed, err := jobService.ReconcileApplicantCounts(); err != nil {
+		log.Fatal("failed to reconcile applicant counts:", err)
+	} else if fixed > 0 {
+		log.Printf("Fixed applicant counts of %d job posts", fixed)
+	}
+	if parsed, err := jobService.BackfillSalaries(); err != nil {
+		log.Fatal("failed to backfill salaries:", err)
+	} else if parsed > 0 {
+		log.Printf("Parsed the salary ranges of %d job posts", parsed)
+	}
+	if created, err := jobService.BackfillJobPostRevisions(); err != nil {
+		log.Fatal("failed to backfill job post revisions:", err)
+	} else if created > 0 {
+		log.Printf("Recorded the first revision of %d job posts", created)
+	}
+	if fixed, err := jobService.BackfillJobPostStates(); err != nil {
+		log.Fatal("failed to backfill job post states:", err)
+	} else if fixed > 0 {
+		log.Printf("Marked %d job posts as closed", fixed)
+	}
+	// An empty index is filled from the database: always for the in-process
+	// index, and on the first start with search for MySQL.
+	if indexed, err := searchIndex.Len(); err != nil {
+		log.Fatal("failed to read the search index:", err)
+	} else if indexed == 0 {
+		if count, err := jobService.RebuildSearchIndex(); err != nil {
+			log.Fatal("failed to build the search index:", err)
+		} else if count > 0 {
+			log.Printf("Indexed %d job posts for search", count)
+		}
+	}
+	// Opens scheduled job posts and closes expired ones as their time comes.
+	go jobService.RunScheduler(context.Background(), time.Minute)
 	messageService := messageservice.NewMessageService(db, geminiService, jobService, pdfExtractor)
+	companyService := companyservice.NewCompanyService(db, jobService)
+	if created, err := companyService.BackfillProfiles(); err != nil {
+		log.Fatal("failed to backfill company profiles:", err)
+	} else if created > 0 {
+		log.Printf("Created %d missing company profiles", created)
+	}
+	orgService := orgservice.NewOrgService(db, mailSender)
+	imageService := imageservice.NewImageService(db, fileStorage)
+	accountService := accountservice.NewAccountService(db, fileStorage, authService, imageService, jobService)
+	auditService := auditservice.NewAuditService(db)
+	if created, err := orgService.BackfillOrganizations(); err != nil {
+		log.Fatal("failed to backfill organizations:", err)
+	} else if created > 0 {
+		log.Printf("Created %d missing organizations", created)
+	}
 
 	// Initialize handlers
-	authHandler := authhandler.NewAuthHandler(authService)
-	jobHandler := jobhandler.NewJobHandler(jobService)
-	messageHandler := messagehandler.NewMessageHandler(messageService)
+	authHandler := authhandler.NewAuthHandler(authService, auditService)
+	jobHandler := jobhandler.NewJobHandler(jobService, auditService)
+	messageHandler := messagehandler.NewMessageHandler(messageService, auditService)
+	companyHandler := companyhandler.NewCompanyHandler(companyService)
+	orgHandler := orghandler.NewOrgHandler(orgService)
+	imageHandler := imagehandler.NewImageHandler(imageService)
+	accountHandler := accounthandler.NewAccountHandler(accountService)
+	auditHandler := audithandler.NewAuditHandler(auditService)
 
-	routes.RegisterRoutes(app, authHandler, jobHandler, messageHandler)
+	routes.RegisterRoutes(app, authHandler, jobHandler, messageHandler, companyHandler, orgHandler, imageHandler, accountHandler, auditHandler)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusOK)
@@ -0,0 +1,39 @@
+// Command reconcile repairs denormalized counters that have drifted from the
+// rows they count.  It is safe to run at any time, e.g. from cron:
+//
+//	go run ./cmd/reconcile
+package main
+
+import (
+	"backend/pkg/service/jobservice"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func main() {
+	if err := godotenv.Load(".env"); err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       os.Getenv("DATABASE_CONNECTION_STRING"),
+		DefaultStringSize:         256,
+		DisableDatetimePrecision:  true,
+		DontSupportRenameIndex:    true,
+		DontSupportRenameColumn:   true,
+		SkipInitializeWithVersion: false,
+	}), &gorm.Config{})
+	if err != nil {
+		log.Fatal("failed to connect to the database:", err)
+	}
+
+	fixed, err := jobservice.RecountApplicants(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Applicant counts: fixed %d job posts", fixed)
+}
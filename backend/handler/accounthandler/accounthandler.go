@@ -0,0 +1,85 @@
+package accounthandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/service/accountservice"
+	"backend/pkg/service/authservice"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IAccountHandler interface {
+	ExportData(c *fiber.Ctx) error
+	DeleteAccount(c *fiber.Ctx) error
+}
+
+type AccountHandler struct {
+	AccountService accountservice.IAccountService
+}
+
+func NewAccountHandler(accountService accountservice.IAccountService) *AccountHandler {
+	return &AccountHandler{AccountService: accountService}
+}
+
+// ExportData handles GET /api/user/export.  The response is a ZIP download.
+func (h *AccountHandler) ExportData(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	archive, err := h.AccountService.ExportData(userID)
+	if err != nil {
+		return accountErrorResponse(c, err)
+	}
+
+	filename := fmt.Sprintf("filter-resume-export-%d-%s.zip", userID, time.Now().Format("20060102"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).Send(archive)
+}
+
+// DeleteAccount handles DELETE /api/user.  The body must confirm the request
+// with the password (or a Firebase ID token).
+func (h *AccountHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Password == "" && req.FirebaseIDToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "password or firebase_id_token is required"})
+	}
+
+	if err := h.AccountService.DeleteAccount(userID, &req, c.IP()); err != nil {
+		return accountErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Account deleted successfully"})
+}
+
+// accountErrorResponse maps account service errors to HTTP responses.
+func accountErrorResponse(c *fiber.Ctx, err error) error {
+	var locked *authservice.LockedError
+	switch {
+	case errors.As(err, &locked):
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": locked.Error()})
+	case errors.Is(err, accountservice.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, authservice.ErrIdentityNotConfirmed):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, accountservice.ErrSoleOwner):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process account request"})
+}
@@ -0,0 +1,96 @@
+package audithandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/service/auditservice"
+	"backend/pkg/service/orgservice"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IAuditHandler interface {
+	ListAuditLog(c *fiber.Ctx) error
+}
+
+type AuditHandler struct {
+	AuditService auditservice.IAuditService
+}
+
+func NewAuditHandler(auditService auditservice.IAuditService) *AuditHandler {
+	return &AuditHandler{AuditService: auditService}
+}
+
+// ListAuditLog handles GET /api/audit?actor_id=&action=&target_type=&target_id=&from=&to=&before_id=&limit=
+// from and to are RFC 3339 timestamps.  Pass the id of the last entry as
+// before_id to fetch the next page.
+func (h *AuditHandler) ListAuditLog(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	filter := auditservice.Filter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+	if filter.ActorID, err = queryID(c, "actor_id"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if filter.TargetID, err = queryID(c, "target_id"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if beforeID, err := queryID(c, "before_id"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	} else if beforeID != nil {
+		filter.BeforeID = *beforeID
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid limit"})
+		}
+	}
+
+	entries, err := h.AuditService.List(userID, filter)
+	if err != nil {
+		if errors.Is(err, orgservice.ErrNotOwner) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve audit log"})
+	}
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+func queryID(c *fiber.Ctx, name string) (*uint, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	result := uint(id)
+	return &result, nil
+}
+
+func queryTime(c *fiber.Ctx, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
@@ -0,0 +1,89 @@
+package authhandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/service/authservice"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateAPIKey handles POST /api/user/api-keys.  The key is only ever shown
+// in this response.
+func (h *AuthHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	key, apiKey, err := h.AuthService.CreateAPIKey(userID, &req)
+	if err != nil {
+		return apiKeyErrorResponse(c, err)
+	}
+	entry := userEntry(auditmodel.ActionAPIKeyCreated, &userID)
+	entry.Details = map[string]interface{}{"api_key_id": apiKey.ID, "name": apiKey.Name, "scopes": apiKey.Scopes}
+	h.audit(c, entry)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// ListAPIKeys handles GET /api/user/api-keys
+func (h *AuthHandler) ListAPIKeys(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	keys, err := h.AuthService.ListAPIKeys(userID)
+	if err != nil {
+		return apiKeyErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(keys)
+}
+
+// RevokeAPIKey handles DELETE /api/user/api-keys/:id
+func (h *AuthHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	keyID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid API key ID"})
+	}
+
+	if err := h.AuthService.RevokeAPIKey(userID, uint(keyID)); err != nil {
+		return apiKeyErrorResponse(c, err)
+	}
+	entry := userEntry(auditmodel.ActionAPIKeyRevoked, &userID)
+	entry.Details = map[string]interface{}{"api_key_id": keyID}
+	h.audit(c, entry)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "API key revoked"})
+}
+
+// apiKeyErrorResponse maps API key errors to HTTP responses.
+func apiKeyErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, authservice.ErrAPIKeyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, authservice.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, authservice.ErrAPIKeyNameRequired),
+		errors.Is(err, authservice.ErrInvalidScope),
+		errors.Is(err, authservice.ErrScopesRequired),
+		errors.Is(err, authservice.ErrInvalidKeyExpiry):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, authservice.ErrTooManyAPIKeys):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process API key request"})
+}
@@ -0,0 +1,46 @@
+package authhandler
+
+import (
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/service/auditservice"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// audit records a security-relevant action with the request's IP and user
+// agent.  The actor defaults to the signed-in user.
+func (h *AuthHandler) audit(c *fiber.Ctx, entry auditservice.Entry) {
+	if h.Audit == nil {
+		return
+	}
+	if entry.ActorID == nil {
+		if userID, ok := c.Locals("userID").(uint); ok {
+			entry.ActorID = &userID
+		}
+	}
+	entry.IP = c.IP()
+	entry.UserAgent = c.Get(fiber.HeaderUserAgent)
+	h.Audit.Record(entry)
+}
+
+// userEntry builds an entry for an action on a user account.
+func userEntry(action string, userID *uint) auditservice.Entry {
+	return auditservice.Entry{Action: action, TargetType: auditmodel.TargetUser, TargetID: userID}
+}
+
+// auditLogin records a successful sign-in.
+func (h *AuthHandler) auditLogin(c *fiber.Ctx, user *authmodel.User, method string) {
+	entry := userEntry(auditmodel.ActionLogin, &user.ID)
+	entry.ActorID = &user.ID
+	entry.Details = map[string]interface{}{"method": method}
+	h.audit(c, entry)
+}
+
+// auditLoginFailure records a rejected sign-in.  There is no actor: the
+// caller hasn't proven who they are.
+func (h *AuthHandler) auditLoginFailure(c *fiber.Ctx, action, email string) {
+	entry := userEntry(action, nil)
+	entry.Details = map[string]interface{}{"email": email}
+	h.audit(c, entry)
+}
@@ -1,29 +1,53 @@
 package authhandler
 
 import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
 	"backend/pkg/model/authmodel"
+	"backend/pkg/service/auditservice"
 	"backend/pkg/service/authservice"
 	"errors"
-	"fmt"
+	"log"
+	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type IAuthHandler interface {
 	Register(c *fiber.Ctx) error
 	Login(c *fiber.Ctx) error
+	FirebaseLogin(c *fiber.Ctx) error
+	Refresh(c *fiber.Ctx) error
+	Logout(c *fiber.Ctx) error
+	LogoutAll(c *fiber.Ctx) error
+	JWKS(c *fiber.Ctx) error
 	GetUserProfile(c *fiber.Ctx) error
 	RequestPasswordReset(c *fiber.Ctx) error
+	VerifyOTP(c *fiber.Ctx) error
 	ResetPassword(c *fiber.Ctx) error
+	VerifyMFA(c *fiber.Ctx) error
+	BeginMFAEnrollmentWithChallenge(c *fiber.Ctx) error
+	CompleteMFAEnrollment(c *fiber.Ctx) error
+	BeginMFAEnrollment(c *fiber.Ctx) error
+	ConfirmMFAEnrollment(c *fiber.Ctx) error
+	DisableMFA(c *fiber.Ctx) error
+	RegenerateRecoveryCodes(c *fiber.Ctx) error
+	SetMFAPolicy(c *fiber.Ctx) error
+	VerifyEmail(c *fiber.Ctx) error
+	ResendVerificationEmail(c *fiber.Ctx) error
 	UpdateProfile(c *fiber.Ctx) error
+	CreateAPIKey(c *fiber.Ctx) error
+	ListAPIKeys(c *fiber.Ctx) error
+	RevokeAPIKey(c *fiber.Ctx) error
 }
 type AuthHandler struct {
 	AuthService *authservice.AuthService
+	Audit       auditservice.IAuditService
 }
 
-func NewAuthHandler(authService *authservice.AuthService) *AuthHandler {
-	return &AuthHandler{AuthService: authService}
+func NewAuthHandler(authService *authservice.AuthService, audit auditservice.IAuditService) *AuthHandler {
+	return &AuthHandler{AuthService: authService, Audit: audit}
 }
 
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
@@ -36,11 +60,17 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 
 	if err := h.AuthService.Register(&registerRequest); err != nil {
+		if errors.Is(err, authservice.ErrPasswordRequired) || errors.Is(err, authservice.ErrPasswordTooLong) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	entry := userEntry(auditmodel.ActionRegister, nil)
+	entry.Details = map[string]interface{}{"email": registerRequest.Email, "user_type": registerRequest.UserType}
+	h.audit(c, entry)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "User registered successfully",
 	})
@@ -55,12 +85,23 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// Call the service, which now returns both the token and the user
-	token, user, err := h.AuthService.Login(req.Email, req.Password)
+	tokens, user, err := h.AuthService.Login(req.Email, req.Password, c.IP())
 	if err != nil {
+		var locked *authservice.LockedError
+		if errors.As(err, &locked) {
+			h.auditLoginFailure(c, auditmodel.ActionLoginLocked, req.Email)
+			return lockedResponse(c, locked)
+		}
+		var mfa *authservice.MFARequiredError
+		if errors.As(err, &mfa) {
+			return mfaRequiredResponse(c, mfa)
+		}
 		// Handle service errors (e.g., user not found, invalid credentials)
 		if errors.Is(err, authservice.ErrUserNotFound) {
+			h.auditLoginFailure(c, auditmodel.ActionLoginFailed, req.Email)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
 		} else if errors.Is(err, authservice.ErrInvalidCredentials) {
+			h.auditLoginFailure(c, auditmodel.ActionLoginFailed, req.Email)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -69,35 +110,124 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user not found"})
 	}
 
+	h.auditLogin(c, user, "password")
+	return c.Status(fiber.StatusOK).JSON(loginResponse(tokens, user))
+}
+
+// FirebaseLogin handles POST /auth/firebase
+func (h *AuthHandler) FirebaseLogin(c *fiber.Ctx) error {
+	var req authmodel.FirebaseLoginRequest
+	if err := c.BodyParser(&req); err != nil || req.IDToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "id_token is required"})
+	}
+
+	tokens, user, err := h.AuthService.LoginWithFirebase(&req)
+	if err != nil {
+		var mfa *authservice.MFARequiredError
+		if errors.As(err, &mfa) {
+			return mfaRequiredResponse(c, mfa)
+		}
+		switch {
+		case errors.Is(err, authservice.ErrFirebaseDisabled):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, authservice.ErrInvalidFirebaseToken),
+			errors.Is(err, authservice.ErrFirebaseEmailNotVerified):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, authservice.ErrInvalidUserType),
+			errors.Is(err, authservice.ErrCompanyNameRequired):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign in with Firebase"})
+	}
+
+	h.auditLogin(c, user, "firebase")
+	return c.Status(fiber.StatusOK).JSON(loginResponse(tokens, user))
+}
+
+// loginResponse builds the body returned by every successful sign-in.
+func loginResponse(tokens *authmodel.TokenPair, user *authmodel.User) fiber.Map {
 	type UserResponse struct { //Define a struct that represents User response
-		ID          uint    `json:"id"`
-		Name        string  `json:"name"`
-		Email       string  `json:"email"`
-		Phone       string  `json:"phone"`
-		UserType    string  `json:"user_type"`
-		CompanyName *string `json:"company_name,omitempty"`
+		ID            uint    `json:"id"`
+		Name          string  `json:"name"`
+		Email         string  `json:"email"`
+		Phone         string  `json:"phone"`
+		UserType      string  `json:"user_type"`
+		CompanyName   *string `json:"company_name,omitempty"`
+		EmailVerified bool    `json:"email_verified"`
 	}
 
 	responseUser := UserResponse{
-		ID:          user.ID,
-		Name:        user.Name,
-		Email:       user.Email,
-		Phone:       user.Phone,
-		UserType:    user.UserType,
-		CompanyName: user.CompanyName,
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		UserType:      user.UserType,
+		CompanyName:   user.CompanyName,
+		EmailVerified: user.EmailVerified,
 	}
-	response := fiber.Map{
-		"message": "User logged in successfully",
-		"token":   token,
-		"user":    responseUser, // Include the user data (excluding the password)
+	return fiber.Map{
+		"message":       "User logged in successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          responseUser, // Include the user data (excluding the password)
 	}
+}
 
-	return c.Status(fiber.StatusOK).JSON(response)
+// lockedResponse tells the client how long to wait before trying again.
+func lockedResponse(c *fiber.Ctx, locked *authservice.LockedError) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": locked.Error()})
+}
+
+// Refresh handles POST /auth/refresh
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req authmodel.RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token is required"})
+	}
+
+	tokens, _, err := h.AuthService.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, authservice.ErrInvalidRefreshToken) || errors.Is(err, authservice.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to refresh token"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// Logout handles POST /auth/logout
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req authmodel.RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token is required"})
+	}
+
+	if err := h.AuthService.Logout(req.RefreshToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Logged out successfully"})
+}
+
+// LogoutAll handles POST /api/user/logout-all
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := h.AuthService.LogoutAll(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log out all sessions"})
+	}
+	h.audit(c, userEntry(auditmodel.ActionLogoutAll, &userID))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "All sessions logged out successfully"})
 }
 
 func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 	// 1. Get User ID from JWT (Authentication).
-	userID, err := getUserIDFromToken(c) // Reuse your existing helper function
+	userID, err := middleware.UserIDFromToken(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
@@ -113,6 +243,7 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "At least one field (name or phone) must be provided"})
 	}
 	// 4. Call the Service Layer.
+	before, _ := h.AuthService.GetUserByID(userID)
 	if err := h.AuthService.UpdateProfile(userID, req.Name, req.Phone); err != nil {
 		if errors.Is(err, authservice.ErrUserNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
@@ -120,13 +251,19 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
 	}
 
+	// 5. Record what changed.
+	after, _ := h.AuthService.GetUserByID(userID)
+	entry := userEntry(auditmodel.ActionProfileUpdated, &userID)
+	entry.Changes = auditservice.Diff(before, after, "name", "phone")
+	h.audit(c, entry)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Profile updated successfully"})
 }
 
 // GetUserProfile handles GET /api/user/profile
 func (h *AuthHandler) GetUserProfile(c *fiber.Ctx) error {
 	// 1. Get User ID from JWT (Authentication).
-	userID, err := getUserIDFromToken(c)
+	userID, err := middleware.UserIDFromToken(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
@@ -142,64 +279,159 @@ func (h *AuthHandler) GetUserProfile(c *fiber.Ctx) error {
 	}
 	// 4. Create a Response Structure (DTO - Data Transfer Object).  This is crucial for security and flexibility.
 	type UserProfileResponse struct {
-		ID           uint    `json:"id"`
-		Name         string  `json:"name"`
-		Email        string  `json:"email"`
-		Phone        *string `json:"phone,omitempty"` // Optional field
-		UserType     string  `json:"user_type"`
-		ProfileImage *string `json:"profile_image,omitempty"`
-		CompanyName  *string `json:"company_name,omitempty"`
+		ID            uint    `json:"id"`
+		Name          string  `json:"name"`
+		Email         string  `json:"email"`
+		Phone         *string `json:"phone,omitempty"` // Optional field
+		UserType      string  `json:"user_type"`
+		ProfileImage  *string `json:"profile_image,omitempty"`
+		CompanyName   *string `json:"company_name,omitempty"`
+		EmailVerified bool    `json:"email_verified"`
 	}
 
 	// 5. Map the User data to the Response Structure.
 	response := UserProfileResponse{
-		ID:           user.ID,
-		Name:         user.Name,
-		Email:        user.Email,
-		Phone:        &user.Phone, // Directly assign (it's already a pointer)
-		UserType:     user.UserType,
-		ProfileImage: user.ProfileImage,
-		CompanyName:  user.CompanyName,
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Phone:         &user.Phone, // Directly assign (it's already a pointer)
+		UserType:      user.UserType,
+		ProfileImage:  user.ProfileImage,
+		CompanyName:   user.CompanyName,
+		EmailVerified: user.EmailVerified,
 	}
 
 	// 6. Return the Response.
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
+// JWKS handles GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(c *fiber.Ctx) error {
+	// Verifiers cache this; rotation keeps retired keys published until the
+	// tokens signed with them have expired.
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(h.AuthService.Keyring.JWKS())
+}
+
+// RequestPasswordReset handles POST /auth/request-reset
+func (h *AuthHandler) RequestPasswordReset(c *fiber.Ctx) error {
+	var req authmodel.RequestPasswordResetRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	// Every outcome gets the same response, so the endpoint can't be used to
+	// find out who has an account: an unknown email, a registered one asking
+	// again too soon, and a failed email all look like success.  The service
+	// does the same hashing for all of them and mails in the background, so
+	// they take as long too.
+	user, err := h.AuthService.RequestPasswordReset(req.Email)
+	if err != nil && !errors.Is(err, authservice.ErrUserNotFound) {
+		log.Printf("password reset request failed: %v", err)
+	}
+	// The account is the target, but not the actor: anyone can ask.
+	entry := userEntry(auditmodel.ActionPasswordResetRequested, nil)
+	if user != nil {
+		entry.TargetID = &user.ID
+	}
+	entry.Details = map[string]interface{}{"email": req.Email}
+	h.audit(c, entry)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "If the email is registered, an OTP has been sent"})
+}
+
+// VerifyOTP handles POST /auth/verify-otp
+func (h *AuthHandler) VerifyOTP(c *fiber.Ctx) error {
+	var req authmodel.VerifyOTPRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" || req.OTP == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if _, err := h.AuthService.VerifyOTP(req.Email, req.OTP); err != nil {
+		return otpErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "OTP verified"})
+}
+
+// ResetPassword handles POST /auth/reset-password
 func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
 	var req authmodel.ResetPasswordRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := h.AuthService.ResetPassword(&req); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if req.Email == "" || req.OTP == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email and otp are required"})
+	}
+	user, err := h.AuthService.ResetPassword(&req)
+	if err != nil {
+		if errors.Is(err, authservice.ErrPasswordRequired) || errors.Is(err, authservice.ErrPasswordTooLong) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return otpErrorResponse(c, err)
 	}
+	// The OTP proves the caller owns the account.
+	entry := userEntry(auditmodel.ActionPasswordReset, &user.ID)
+	entry.ActorID = &user.ID
+	entry.Details = map[string]interface{}{"email": req.Email}
+	h.audit(c, entry)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password reset successful"})
 }
 
-// Helper function to get user id from jwt token
-func getUserIDFromToken(c *fiber.Ctx) (uint, error) {
-	user := c.Locals("user") // Get the user object from context (set by middleware)
-	if user == nil {
-		return 0, fmt.Errorf("no user in context")
+// VerifyEmail handles GET /auth/verify-email?token=...  This is the link sent
+// by email, so it needs no authentication.
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token is required"})
 	}
 
-	token, ok := user.(*jwt.Token) // Assert to *jwt.Token  <-- CORRECT TYPE
-	if !ok {
-		return 0, fmt.Errorf("invalid token type")
+	user, err := h.AuthService.VerifyEmail(token)
+	if err != nil {
+		if errors.Is(err, authservice.ErrInvalidVerificationToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
 	}
+	entry := userEntry(auditmodel.ActionEmailVerified, &user.ID)
+	entry.ActorID = &user.ID
+	h.audit(c, entry)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Email verified successfully"})
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims) // Get the claims
-	if !ok {
-		return 0, fmt.Errorf("invalid claims type")
+// ResendVerificationEmail handles POST /api/user/verify-email/resend
+func (h *AuthHandler) ResendVerificationEmail(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	// IMPORTANT:  Always validate the data type before using it!
-	userIDFloat, ok := claims["user_id"].(float64) // JWT IDs are often floats
-	if !ok {
-		return 0, fmt.Errorf("invalid user ID format in token")
+	if err := h.AuthService.SendVerificationEmail(userID); err != nil {
+		switch {
+		case errors.Is(err, authservice.ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		case errors.Is(err, authservice.ErrEmailAlreadyVerified):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, authservice.ErrVerificationRequestTooSoon):
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send verification email"})
 	}
-	userID := uint(userIDFloat) // Convert to uint
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Verification email sent"})
+}
 
-	return userID, nil
+// otpErrorResponse maps password reset errors to HTTP responses.
+func otpErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, authservice.ErrUserNotFound),
+		errors.Is(err, authservice.ErrOTPNotFound),
+		errors.Is(err, authservice.ErrOTPMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid OTP"})
+	case errors.Is(err, authservice.ErrOTPExpired),
+		errors.Is(err, authservice.ErrOTPAlreadyUsed),
+		errors.Is(err, authservice.ErrOTPNotVerified):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, authservice.ErrOTPTooManyAttempts),
+		errors.Is(err, authservice.ErrOTPRequestTooSoon):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process password reset"})
 }
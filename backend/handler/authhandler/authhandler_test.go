@@ -0,0 +1,62 @@
+package authhandler
+
+import (
+	"backend/pkg/service/authservice"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+// fakeFirebase rejects every ID token, remembering the last one it was given.
+type fakeFirebase struct {
+	verified string
+}
+
+func (f *fakeFirebase) VerifyIDToken(idToken string) (*auth.Token, error) {
+	f.verified = idToken
+	return nil, errors.New("token signature is invalid")
+}
+
+func (f *fakeFirebase) GetUser(uid string) (*auth.UserRecord, error) {
+	return nil, errors.New("user not found")
+}
+
+func TestFirebaseLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		firebase *fakeFirebase
+		body     string
+		status   int
+	}{
+		{"invalid token", &fakeFirebase{}, `{"id_token":"forged"}`, fiber.StatusUnauthorized},
+		{"no token", &fakeFirebase{}, `{}`, fiber.StatusBadRequest},
+		{"firebase not configured", nil, `{"id_token":"forged"}`, fiber.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &authservice.AuthService{}
+			if tt.firebase != nil {
+				service.Firebase = tt.firebase
+			}
+			app := fiber.New()
+			app.Post("/auth/firebase", NewAuthHandler(service, nil).FirebaseLogin)
+
+			req := httptest.NewRequest("POST", "/auth/firebase", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status == fiber.StatusUnauthorized && tt.firebase.verified != "forged" {
+				t.Errorf("verifier got %q, want the ID token of the request", tt.firebase.verified)
+			}
+		})
+	}
+}
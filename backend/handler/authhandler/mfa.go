@@ -0,0 +1,200 @@
+package authhandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/service/auditservice"
+	"backend/pkg/service/authservice"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// mfaRequiredResponse is returned by the sign-in endpoints instead of tokens
+// when a second factor is needed.  When mfa_enrolled is false the client has
+// to run the enrolment flow with the mfa_token first.
+func mfaRequiredResponse(c *fiber.Ctx, mfa *authservice.MFARequiredError) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":      "Two-factor authentication required",
+		"mfa_required": true,
+		"mfa_enrolled": mfa.Enrolled,
+		"mfa_token":    mfa.Token,
+	})
+}
+
+// VerifyMFA handles POST /auth/mfa/verify
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var req authmodel.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mfa_token and code or recovery_code are required"})
+	}
+
+	tokens, user, err := h.AuthService.VerifyMFA(req.MFAToken, req.Code, req.RecoveryCode, c.IP())
+	if err != nil {
+		var locked *authservice.LockedError
+		if errors.As(err, &locked) {
+			return lockedResponse(c, locked)
+		}
+		if errors.Is(err, authservice.ErrInvalidMFACode) {
+			h.audit(c, userEntry(auditmodel.ActionMFAFailed, nil))
+		}
+		return mfaErrorResponse(c, err)
+	}
+	h.auditLogin(c, user, "mfa")
+	return c.Status(fiber.StatusOK).JSON(loginResponse(tokens, user))
+}
+
+// BeginMFAEnrollmentWithChallenge handles POST /auth/mfa/enroll, for accounts
+// that must set up 2FA before they can finish logging in.
+func (h *AuthHandler) BeginMFAEnrollmentWithChallenge(c *fiber.Ctx) error {
+	var req authmodel.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mfa_token is required"})
+	}
+
+	enrollment, err := h.AuthService.BeginMFAEnrollmentWithChallenge(req.MFAToken)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(enrollment)
+}
+
+// CompleteMFAEnrollment handles POST /auth/mfa/confirm
+func (h *AuthHandler) CompleteMFAEnrollment(c *fiber.Ctx) error {
+	var req authmodel.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mfa_token and code are required"})
+	}
+
+	tokens, user, codes, err := h.AuthService.CompleteMFAEnrollment(req.MFAToken, req.Code)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+	entry := userEntry(auditmodel.ActionMFAEnabled, &user.ID)
+	entry.ActorID = &user.ID
+	h.audit(c, entry)
+	h.auditLogin(c, user, "mfa")
+	response := loginResponse(tokens, user)
+	response["recovery_codes"] = codes
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// BeginMFAEnrollment handles POST /api/user/mfa/enroll
+func (h *AuthHandler) BeginMFAEnrollment(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	enrollment, err := h.AuthService.BeginMFAEnrollment(userID)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(enrollment)
+}
+
+// ConfirmMFAEnrollment handles POST /api/user/mfa/confirm
+func (h *AuthHandler) ConfirmMFAEnrollment(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code is required"})
+	}
+
+	codes, err := h.AuthService.ConfirmMFAEnrollment(userID, req.Code)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+	h.audit(c, userEntry(auditmodel.ActionMFAEnabled, &userID))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA handles POST /api/user/mfa/disable
+func (h *AuthHandler) DisableMFA(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code or recovery_code is required"})
+	}
+
+	if err := h.AuthService.DisableMFA(userID, req.Code, req.RecoveryCode); err != nil {
+		if errors.Is(err, authservice.ErrInvalidMFACode) {
+			h.audit(c, userEntry(auditmodel.ActionMFAFailed, &userID))
+		}
+		return mfaErrorResponse(c, err)
+	}
+	h.audit(c, userEntry(auditmodel.ActionMFADisabled, &userID))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles POST /api/user/mfa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code is required"})
+	}
+
+	codes, err := h.AuthService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+	h.audit(c, userEntry(auditmodel.ActionRecoveryCodesRegenerated, &userID))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// SetMFAPolicy handles PUT /api/user/mfa/policy
+func (h *AuthHandler) SetMFAPolicy(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.MFAPolicyRequest
+	if err := c.BodyParser(&req); err != nil || req.Required == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "required is required"})
+	}
+
+	if err := h.AuthService.SetMFAPolicy(userID, *req.Required); err != nil {
+		return mfaErrorResponse(c, err)
+	}
+	h.audit(c, auditservice.Entry{
+		Action:     auditmodel.ActionMFAPolicyChanged,
+		TargetType: auditmodel.TargetOrganization,
+		Details:    map[string]interface{}{"mfa_required": *req.Required},
+	})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"mfa_required": *req.Required})
+}
+
+// mfaErrorResponse maps two-factor errors to HTTP responses.
+func mfaErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, authservice.ErrInvalidMFAToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, authservice.ErrInvalidMFACode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, authservice.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, authservice.ErrMFANotAllowed),
+		errors.Is(err, authservice.ErrMFARequiredByPolicy),
+		errors.Is(err, authservice.ErrMFAPolicyOwnerOnly):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, authservice.ErrMFAAlreadyEnabled),
+		errors.Is(err, authservice.ErrMFANotEnrolled),
+		errors.Is(err, authservice.ErrMFAEnrollNotStarted):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process two-factor authentication"})
+}
@@ -0,0 +1,150 @@
+package companyhandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/service/companyservice"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ICompanyHandler interface {
+	GetMyProfile(c *fiber.Ctx) error
+	CreateMyProfile(c *fiber.Ctx) error
+	UpdateMyProfile(c *fiber.Ctx) error
+	DeleteMyProfile(c *fiber.Ctx) error
+	GetCompany(c *fiber.Ctx) error
+}
+
+type CompanyHandler struct {
+	CompanyService companyservice.ICompanyService
+}
+
+func NewCompanyHandler(companyService companyservice.ICompanyService) *CompanyHandler {
+	return &CompanyHandler{CompanyService: companyService}
+}
+
+// GetMyProfile handles GET /api/companies/me
+func (h *CompanyHandler) GetMyProfile(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	profile, err := h.CompanyService.GetProfileByUserID(userID)
+	if err != nil {
+		return companyErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(profile)
+}
+
+// CreateMyProfile handles POST /api/companies/me
+func (h *CompanyHandler) CreateMyProfile(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.CompanyProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	profile, err := h.CompanyService.CreateProfile(userID, &req)
+	if err != nil {
+		return companyErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(profile)
+}
+
+// UpdateMyProfile handles PUT /api/companies/me
+func (h *CompanyHandler) UpdateMyProfile(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req authmodel.CompanyProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.CompanyName == nil && req.Description == nil && req.Logo == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "At least one field (company_name, description or logo) must be provided"})
+	}
+
+	profile, err := h.CompanyService.UpdateProfile(userID, &req)
+	if err != nil {
+		return companyErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(profile)
+}
+
+// DeleteMyProfile handles DELETE /api/companies/me
+func (h *CompanyHandler) DeleteMyProfile(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := h.CompanyService.DeleteProfile(userID); err != nil {
+		return companyErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Company profile deleted successfully"})
+}
+
+// GetCompany handles GET /api/companies/:id.  It is public: anyone can see a
+// company's profile and its open jobs.
+func (h *CompanyHandler) GetCompany(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid company ID"})
+	}
+
+	profile, jobPosts, err := h.CompanyService.GetPublicProfile(uint(id))
+	if err != nil {
+		return companyErrorResponse(c, err)
+	}
+
+	type JobResponse struct {
+		ID          uint   `json:"id"`
+		Title       string `json:"title"`
+		Location    string `json:"location"`
+		SalaryRange string `json:"salary_range"`
+		JobPosition string `json:"job_position"`
+		Quantity    int    `json:"quantity"`
+		jobmodel.Salary
+	}
+	jobs := make([]JobResponse, 0, len(jobPosts))
+	for _, jobPost := range jobPosts {
+		jobs = append(jobs, JobResponse{
+			ID:          jobPost.ID,
+			Title:       jobPost.Title,
+			Location:    jobPost.Location,
+			SalaryRange: jobPost.SalaryRange,
+			JobPosition: jobPost.JobPosition,
+			Quantity:    jobPost.Quantity,
+			Salary:      jobPost.Salary(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"company":   profile,
+		"open_jobs": jobs,
+	})
+}
+
+// companyErrorResponse maps company service errors to HTTP responses.
+func companyErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, companyservice.ErrProfileNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, companyservice.ErrProfileExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, companyservice.ErrNotCompany):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, companyservice.ErrCompanyNameRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process company profile"})
+}
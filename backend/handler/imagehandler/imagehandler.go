@@ -0,0 +1,175 @@
+package imagehandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/service/imageservice"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// formField is the multipart field the image is uploaded in.
+const formField = "image"
+
+type IImageHandler interface {
+	UploadAvatar(c *fiber.Ctx) error
+	DeleteAvatar(c *fiber.Ctx) error
+	GetAvatar(c *fiber.Ctx) error
+	UploadLogo(c *fiber.Ctx) error
+	DeleteLogo(c *fiber.Ctx) error
+	GetLogo(c *fiber.Ctx) error
+}
+
+type ImageHandler struct {
+	ImageService imageservice.IImageService
+}
+
+func NewImageHandler(imageService imageservice.IImageService) *ImageHandler {
+	return &ImageHandler{ImageService: imageService}
+}
+
+// UploadAvatar handles POST /api/user/avatar (multipart, field "image")
+func (h *ImageHandler) UploadAvatar(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	data, err := readUpload(c)
+	if err != nil {
+		return imageErrorResponse(c, err)
+	}
+
+	url, err := h.ImageService.SetAvatar(userID, data)
+	if err != nil {
+		return imageErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"profile_image": url})
+}
+
+// DeleteAvatar handles DELETE /api/user/avatar
+func (h *ImageHandler) DeleteAvatar(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := h.ImageService.DeleteAvatar(userID); err != nil {
+		return imageErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Profile image deleted successfully"})
+}
+
+// GetAvatar handles GET /api/users/:id/avatar?size=small|medium|large
+func (h *ImageHandler) GetAvatar(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	file, contentType, err := h.ImageService.OpenAvatar(uint(id), c.Query("size"))
+	if err != nil {
+		return imageErrorResponse(c, err)
+	}
+	return sendImage(c, file, contentType)
+}
+
+// UploadLogo handles POST /api/companies/me/logo (multipart, field "image")
+func (h *ImageHandler) UploadLogo(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	data, err := readUpload(c)
+	if err != nil {
+		return imageErrorResponse(c, err)
+	}
+
+	profile, err := h.ImageService.SetCompanyLogo(userID, data)
+	if err != nil {
+		return imageErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(profile)
+}
+
+// DeleteLogo handles DELETE /api/companies/me/logo
+func (h *ImageHandler) DeleteLogo(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := h.ImageService.DeleteCompanyLogo(userID); err != nil {
+		return imageErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Logo deleted successfully"})
+}
+
+// GetLogo handles GET /api/companies/:id/logo?size=small|medium|large
+func (h *ImageHandler) GetLogo(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid company ID"})
+	}
+
+	file, contentType, err := h.ImageService.OpenCompanyLogo(uint(id), c.Query("size"))
+	if err != nil {
+		return imageErrorResponse(c, err)
+	}
+	return sendImage(c, file, contentType)
+}
+
+// readUpload reads the uploaded image, refusing anything over the size limit
+// before reading it.
+func readUpload(c *fiber.Ctx) ([]byte, error) {
+	header, err := c.FormFile(formField)
+	if err != nil {
+		return nil, imageservice.ErrInvalidImageInput
+	}
+	if header.Size > imageservice.MaxImageSize {
+		return nil, imageservice.ErrImageTooLarge
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, imageservice.ErrInvalidImageInput
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, imageservice.MaxImageSize+1))
+	if err != nil {
+		return nil, imageservice.ErrInvalidImageInput
+	}
+	return data, nil
+}
+
+// sendImage streams a stored image.  Image URLs change with every upload, so
+// the response can be cached.
+func sendImage(c *fiber.Ctx, file io.ReadCloser, contentType string) error {
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.SendStream(file) // Fiber closes the stream once it is sent
+}
+
+// imageErrorResponse maps image service errors to HTTP responses.
+func imageErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, imageservice.ErrImageNotFound),
+		errors.Is(err, imageservice.ErrUserNotFound),
+		errors.Is(err, imageservice.ErrCompanyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, imageservice.ErrImageTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, imageservice.ErrUnsupportedImage):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, imageservice.ErrImageDimensions),
+		errors.Is(err, imageservice.ErrInvalidImageSize):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, imageservice.ErrInvalidImageInput):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No readable image provided in field \"" + formField + "\""})
+	case errors.Is(err, imageservice.ErrNotCompany):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process image"})
+}
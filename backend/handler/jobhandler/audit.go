@@ -0,0 +1,27 @@
+package jobhandler
+
+import (
+	"backend/pkg/service/auditservice"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// audit adds an entry to the audit log, stamped with the request's IP and
+// user agent.  The actor defaults to the signed-in user.
+func (h *JobHandler) audit(c *fiber.Ctx, entry auditservice.Entry) {
+	if h.Audit == nil {
+		return
+	}
+	if entry.ActorID == nil {
+		if userID, ok := c.Locals("userID").(uint); ok {
+			entry.ActorID = &userID
+		}
+	}
+	entry.IP = c.IP()
+	entry.UserAgent = c.Get(fiber.HeaderUserAgent)
+	h.Audit.Record(entry)
+}
+
+func ref(id uint) *uint {
+	return &id
+}
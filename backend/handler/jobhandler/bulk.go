@@ -0,0 +1,124 @@
+package jobhandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/service/auditservice"
+	"backend/pkg/service/jobservice"
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportJobPosts handles POST /api/jobs/import?dry_run=true
+// The file is either the "file" field of a multipart form or the request
+// body: a CSV file with a header row, or a JSON array of job posts, with the
+// columns or keys of the export.  Nothing is saved when any row is invalid;
+// the response then reports each row's problems with status 422.
+func (h *JobHandler) ImportJobPosts(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+	dryRun, err := strconv.ParseBool(c.Query("dry_run", "false"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "dry_run must be true or false"})
+	}
+
+	data, name, contentType := c.Body(), "", c.Get(fiber.HeaderContentType)
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to read the uploaded file"})
+		}
+		defer f.Close()
+		if data, err = io.ReadAll(f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to read the uploaded file"})
+		}
+		name, contentType = file.Filename, file.Header.Get(fiber.HeaderContentType)
+	} else if strings.HasPrefix(contentType, fiber.MIMEMultipartForm) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file is required"})
+	}
+
+	report, err := h.JobService.ImportJobPosts(userID, data, importFormat(name, contentType, data), dryRun)
+	if err != nil {
+		if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		} else if errors.Is(err, jobservice.ErrImportFormat) || errors.Is(err, jobservice.ErrImportTooLarge) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to import job posts"})
+	}
+	if report.Invalid > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+
+	if report.Imported {
+		h.audit(c, auditservice.Entry{
+			Action:     auditmodel.ActionJobPostsImported,
+			TargetType: auditmodel.TargetJobPost,
+			Details: map[string]interface{}{
+				"created":   report.Created,
+				"updated":   report.Updated,
+				"unchanged": report.Unchanged,
+			},
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// importFormat tells CSV from JSON by the file name, then the content type,
+// then the first character of the file.
+func importFormat(name, contentType string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return jobservice.FormatCSV
+	case ".json":
+		return jobservice.FormatJSON
+	}
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return jobservice.FormatCSV
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		return jobservice.FormatJSON
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return jobservice.FormatJSON
+	}
+	return jobservice.FormatCSV
+}
+
+// ExportJobPosts handles GET /api/jobs/export?format=csv|json
+// Downloads the posts of the caller's organization in the import format, so
+// the file can be edited and imported again.
+func (h *JobHandler) ExportJobPosts(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+	format := strings.ToLower(c.Query("format", jobservice.FormatCSV))
+	if format != jobservice.FormatCSV && format != jobservice.FormatJSON {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be csv or json"})
+	}
+
+	records, err := h.JobService.ExportJobPosts(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export job posts"})
+	}
+
+	c.Attachment("job-posts." + format)
+	if format == jobservice.FormatJSON {
+		return c.Status(fiber.StatusOK).JSON(records)
+	}
+	var buf bytes.Buffer
+	if err := jobservice.WriteJobPostRecordsCSV(&buf, records); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export job posts"})
+	}
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
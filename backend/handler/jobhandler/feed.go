@@ -0,0 +1,152 @@
+package jobhandler
+
+import (
+	"backend/pkg/feed"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/service/jobservice"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	feedTitle = "Filter Resume jobs"
+	feedSize  = 50
+	// Aggregators poll often; a few minutes of staleness is fine for them.
+	feedCacheControl = "public, max-age=300"
+)
+
+// JobFeedRSS handles GET /feeds/jobs/rss (public)
+// The newest open job posts as an RSS 2.0 feed.
+func (h *JobHandler) JobFeedRSS(c *fiber.Ctx) error {
+	return h.sendJobFeed(c, "rss", "application/rss+xml; charset=utf-8", feed.RSS)
+}
+
+// JobFeedAtom handles GET /feeds/jobs/atom (public)
+// The newest open job posts as an Atom feed.
+func (h *JobHandler) JobFeedAtom(c *fiber.Ctx) error {
+	return h.sendJobFeed(c, "atom", "application/atom+xml; charset=utf-8", feed.Atom)
+}
+
+func (h *JobHandler) sendJobFeed(c *fiber.Ctx, name, contentType string, render func(feed.Channel, []feed.Item) ([]byte, error)) error {
+	jobPosts, err := h.JobService.ListFeedJobPosts(feedSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job posts"})
+	}
+	base := feedBaseURL(c)
+	items := make([]feed.Item, 0, len(jobPosts))
+	for i := range jobPosts {
+		items = append(items, feedItem(&jobPosts[i], base))
+	}
+	body, err := render(feed.Channel{
+		Title:       feedTitle,
+		Description: "Open positions posted on Filter Resume",
+		Link:        base,
+		SelfURL:     base + "/feeds/jobs/" + name,
+	}, items)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build the job feed"})
+	}
+	return sendCacheable(c, contentType, body, feed.Updated(items))
+}
+
+// JobPostingJSONLD handles GET /feeds/jobs/:id/jsonld (public)
+// An open job post as schema.org JobPosting JSON-LD, for search engines.
+func (h *JobHandler) JobPostingJSONLD(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errInvalidJobID})
+	}
+	jobPost, err := h.JobService.GetJobPostByID(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job post"})
+	}
+	// Only open posts are published; the rest look the same as missing ones.
+	if jobPost == nil || !jobPost.IsOpen(time.Now()) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": errJobPostNotFound})
+	}
+
+	item := feedItem(jobPost, feedBaseURL(c))
+	body, err := feed.JobPosting(item)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build the job posting"})
+	}
+	return sendCacheable(c, "application/ld+json; charset=utf-8", body, item.Updated)
+}
+
+// feedItem turns a job post into a feed item.  JOB_PAGE_URL is the page of a
+// job post on the web site, with {id} in place of its ID; without it, items
+// link to their JSON-LD.
+func feedItem(jobPost *jobmodel.JobPost, base string) feed.Item {
+	id := strconv.FormatUint(uint64(jobPost.ID), 10)
+	url := base + "/feeds/jobs/" + id + "/jsonld"
+	if page := os.Getenv("JOB_PAGE_URL"); page != "" {
+		url = strings.ReplaceAll(page, "{id}", id)
+	}
+	item := feed.Item{
+		ID:           jobPost.ID,
+		Title:        jobPost.Title,
+		Description:  jobPost.Description,
+		Company:      jobservice.DisplayCompanyName(jobPost),
+		Location:     jobPost.Location,
+		Salary:       jobPost.Salary(),
+		Quantity:     jobPost.Quantity,
+		URL:          url,
+		Published:    jobPost.CreatedAt,
+		Updated:      jobPost.UpdatedAt,
+		ValidThrough: jobPost.ExpiresAt,
+	}
+	if jobPost.PublishAt != nil {
+		item.Published = *jobPost.PublishAt
+	}
+	// Uploaded logos are only served to signed-in users, so only external
+	// logo URLs are shared.
+	if profile := jobPost.CompanyProfile; profile != nil && strings.HasPrefix(profile.Logo, "https://") {
+		item.CompanyLogo = profile.Logo
+	}
+	return item
+}
+
+// feedBaseURL is the public address of the API: APP_BASE_URL, or else the
+// address the request came to.
+func feedBaseURL(c *fiber.Ctx) string {
+	if base := os.Getenv("APP_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return c.BaseURL()
+}
+
+// sendCacheable sends body with an ETag and caching headers, or 304 Not
+// Modified when the client already has it.
+func sendCacheable(c *fiber.Ctx, contentType string, body []byte, modified time.Time) error {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, feedCacheControl)
+	if !modified.IsZero() {
+		c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+// etagMatches reports whether an If-None-Match header names etag.  Weak
+// validators match too, as they should for GET.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
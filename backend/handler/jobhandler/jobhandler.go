@@ -1,7 +1,11 @@
 package jobhandler
 
 import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
 	"backend/pkg/model/jobmodel"
+	"backend/pkg/service/auditservice"
 	"backend/pkg/service/jobservice"
 	"bytes"
 	"errors"
@@ -12,7 +16,6 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
@@ -21,13 +24,22 @@ type IJobHandler interface {
 	GetJobPost(c *fiber.Ctx) error
 	UpdateJobPost(c *fiber.Ctx) error
 	DeleteJobPost(c *fiber.Ctx) error
+	ChangeJobPostState(c *fiber.Ctx) error
+	ListJobPostRevisions(c *fiber.Ctx) error
+	ImportJobPosts(c *fiber.Ctx) error
+	ExportJobPosts(c *fiber.Ctx) error
+	JobFeedRSS(c *fiber.Ctx) error
+	JobFeedAtom(c *fiber.Ctx) error
+	JobPostingJSONLD(c *fiber.Ctx) error
 	ListJobPosts(c *fiber.Ctx) error
+	SearchJobPosts(c *fiber.Ctx) error
 	ListJobPostsByCompany(c *fiber.Ctx) error
 	ListOpenJobPosts(c *fiber.Ctx) error
 	ListClosedJobPosts(c *fiber.Ctx) error
 	CreateJobApplication(c *fiber.Ctx) error
 	GetJobApplication(c *fiber.Ctx) error
 	UpdateJobApplication(c *fiber.Ctx) error
+	WithdrawJobApplication(c *fiber.Ctx) error
 	ListJobApplicationsForJob(c *fiber.Ctx) error
 	ListJobApplicationsForUser(c *fiber.Ctx) error
 	ListJobApplications(c *fiber.Ctx) error
@@ -41,10 +53,11 @@ type IJobHandler interface {
 
 type JobHandler struct {
 	JobService jobservice.IJobService
+	Audit      auditservice.IAuditService
 }
 
-func NewJobHandler(jobService jobservice.IJobService) *JobHandler {
-	return &JobHandler{JobService: jobService}
+func NewJobHandler(jobService jobservice.IJobService, audit auditservice.IAuditService) *JobHandler {
+	return &JobHandler{JobService: jobService, Audit: audit}
 }
 
 const (
@@ -63,10 +76,29 @@ func (h *JobHandler) CreateJobPost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	// The owner always comes from the token, never from the request body.
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+	jobPost.ID = 0
+	jobPost.UserID = userID
+
 	if err := h.JobService.CreateJobPost(&jobPost); err != nil {
+		if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		} else if errors.Is(err, jobservice.ErrInvalidSalary) || isLifecycleError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create job post"})
 	}
 
+	h.audit(c, auditservice.Entry{
+		Action:     auditmodel.ActionJobPostCreated,
+		TargetType: auditmodel.TargetJobPost,
+		TargetID:   &jobPost.ID,
+		Details:    map[string]interface{}{"title": jobPost.Title},
+	})
 	return c.Status(fiber.StatusCreated).JSON(jobPost)
 }
 
@@ -84,6 +116,15 @@ func (h *JobHandler) GetJobPost(c *fiber.Ctx) error {
 	if jobPost == nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job post not found"})
 	}
+	// Drafts and the like only exist for the organization's members.
+	userID, _ := middleware.UserIDFromToken(c)
+	visible, err := h.JobService.VisibleJobPosts([]jobmodel.JobPost{*jobPost}, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job post"})
+	}
+	if len(visible) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job post not found"})
+	}
 
 	return c.Status(fiber.StatusOK).JSON(jobPost)
 }
@@ -99,16 +140,36 @@ func (h *JobHandler) UpdateJobPost(c *fiber.Ctx) error {
 	if err := c.BodyParser(&jobPost); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if field := lifecycleFieldIn(c.Body()); field != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": field + " can't be updated here; use PUT /api/jobs/:id/state"})
+	}
 
 	jobPost.ID = uint(id)
 
-	if err := h.JobService.UpdateJobPost(&jobPost); err != nil {
-		if err == gorm.ErrRecordNotFound {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	before, _ := h.JobService.GetJobPostByID(jobPost.ID)
+	if err := h.JobService.UpdateJobPost(&jobPost, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job post not found"})
+		} else if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		} else if errors.Is(err, jobservice.ErrInvalidSalary) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update job post"})
 	}
 
+	after, _ := h.JobService.GetJobPostByID(jobPost.ID)
+	h.audit(c, auditservice.Entry{
+		Action:     auditmodel.ActionJobPostUpdated,
+		TargetType: auditmodel.TargetJobPost,
+		TargetID:   &jobPost.ID,
+		Changes:    auditservice.Diff(before, after),
+	})
 	return c.Status(fiber.StatusOK).JSON(jobPost)
 }
 
@@ -120,31 +181,53 @@ func (h *JobHandler) DeleteJobPost(c *fiber.Ctx) error {
 	}
 
 	// Get the user ID from the JWT token.
-	userID, err := getUserIDFromToken(c) // You'll need this helper function
+	userID, err := middleware.UserIDFromToken(c) // You'll need this helper function
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
 	}
 
 	// Call the service layer, passing both the job ID and user ID.
+	before, _ := h.JobService.GetJobPostByID(uint(jobID))
 	if err := h.JobService.DeleteJobPost(uint(jobID), userID); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": errJobPostNotFound})
 		} else if errors.Is(err, jobservice.ErrUnauthorized) { // Check for ErrUnauthorized
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": errUnauthorized}) // Or 403 Forbidden
+			return middleware.Forbidden(c)
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": errDeleteJobPost})
 	}
 
+	deleted := auditservice.Entry{
+		Action:     auditmodel.ActionJobPostDeleted,
+		TargetType: auditmodel.TargetJobPost,
+		TargetID:   ref(uint(jobID)),
+	}
+	if before != nil {
+		deleted.Details = map[string]interface{}{"title": before.Title}
+	}
+	h.audit(c, deleted)
+
 	// Return a success message with 200 OK
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Job post deleted successfully"})
 }
 
-// ListJobPosts handles GET /api/jobs
+// ListJobPosts handles GET /api/jobs?q=&location=&job_position=&status=open|closed&company_id=&salary_min=&salary_max=&salary_currency=&salary_period=&created_from=&created_to=&sort=&cursor=&limit=
+// Dates are YYYY-MM-DD or RFC 3339.  Salary bounds are in salary_currency
+// (THB by default) per salary_period (month by default).  The response holds one page of posts;
+// pass next_cursor back as cursor to get the next one.
 func (h *JobHandler) ListJobPosts(c *fiber.Ctx) error {
-	jobPosts, err := h.JobService.ListJobPosts()
+	query, err := parseJobPostQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	page, err := h.JobService.ListJobPosts(query)
 	if err != nil {
+		if errors.Is(err, jobservice.ErrInvalidCursor) || errors.Is(err, jobservice.ErrInvalidSort) || errors.Is(err, jobservice.ErrInvalidSalary) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job posts"})
 	}
+	jobPosts := page.JobPosts
 
 	// Create a response structure that includes the company name and applicant count.
 	type Response struct {
@@ -156,19 +239,17 @@ func (h *JobHandler) ListJobPosts(c *fiber.Ctx) error {
 		JobPosition    string  `json:"job_position"`
 		CompanyName    *string `json:"company_name"` // Use a pointer to handle nil
 		Status         bool    `json:"status"`       // Add the Status field
+		State          string  `json:"state"`
 		Quantity       int     `json:"quantity"`
 		ApplicantCount int64   `json:"applicant_count"` // Add applicant count
 		UserID         uint    `json:"user_id"`
+		jobmodel.Salary
+
+		CompanyProfile *authmodel.CompanyProfile `json:"company_profile"`
 	}
 
 	responseList := make([]Response, 0, len(jobPosts))
 	for _, jobPost := range jobPosts {
-		// Get the applicant count for EACH job post.
-		count, err := h.JobService.CountApplicationsByJobID(jobPost.ID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve applicant count"})
-		}
-
 		// CORRECTED:  Use direct access (Option 1 - Recommended)
 		responseList = append(responseList, Response{
 			ID:             jobPost.ID,
@@ -177,15 +258,22 @@ func (h *JobHandler) ListJobPosts(c *fiber.Ctx) error {
 			Location:       jobPost.Location,
 			SalaryRange:    jobPost.SalaryRange,
 			JobPosition:    jobPost.JobPosition,
-			CompanyName:    jobPost.User.CompanyName, // Direct access
-			Status:         jobPost.Status,           // Include Status
+			CompanyName:    companyName(&jobPost),
+			Status:         jobPost.Status, // Include Status
+			State:          jobPost.State,
 			Quantity:       jobPost.Quantity,
-			ApplicantCount: count, // Add the count
+			ApplicantCount: int64(jobPost.ApplicantCount), // Kept up to date by the job service
 			UserID:         jobPost.UserID,
+			Salary:         jobPost.Salary(),
+			CompanyProfile: jobPost.CompanyProfile,
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(responseList)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"jobs":        responseList,
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
+	})
 }
 
 // ListJobPostsByCompany handles GET /api/jobs/company/:companyId
@@ -196,6 +284,10 @@ func (h *JobHandler) ListJobPostsByCompany(c *fiber.Ctx) error {
 	}
 
 	jobPosts, err := h.JobService.ListJobPostsByCompanyID(uint(userID))
+	if err == nil {
+		viewerID, _ := middleware.UserIDFromToken(c)
+		jobPosts, err = h.JobService.VisibleJobPosts(jobPosts, viewerID)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job posts"})
 	}
@@ -231,7 +323,7 @@ func (h *JobHandler) CreateJobApplication(c *fiber.Ctx) error {
 
 	// Get the user ID from the JWT token (assuming you have authentication middleware)
 	// This is a placeholder.  You MUST get the user ID from your authentication.
-	userID, err := getUserIDFromToken(c) // Replace with your actual auth logic
+	userID, err := middleware.UserIDFromToken(c) // Replace with your actual auth logic
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"}) // Or a better error
 	}
@@ -272,36 +364,48 @@ func (h *JobHandler) CreateJobApplication(c *fiber.Ctx) error {
 	// Call the service to create the application and save the file
 	filePath, err := h.JobService.CreateJobApplication(&application, fileBytes)
 	if err != nil {
+		if errors.Is(err, jobservice.ErrJobPostNotOpen) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()}) // Return specific error
 	}
 
+	h.audit(c, auditservice.Entry{
+		Action:     auditmodel.ActionApplicationSubmitted,
+		TargetType: auditmodel.TargetJobApplication,
+		TargetID:   &application.ID,
+		Details:    map[string]interface{}{"job_id": application.JobID},
+	})
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Application submitted successfully", "resume_file": filePath})
 }
 
-// getUserIDFromToken extracts the user ID from the JWT in the request context.
-func getUserIDFromToken(c *fiber.Ctx) (uint, error) {
-	user := c.Locals("user") // Get the user object from context (set by middleware)
-	if user == nil {
-		return 0, fmt.Errorf("no user in context")
+// companyName prefers the name on the company profile over the one the user
+// registered with.
+func companyName(jobPost *jobmodel.JobPost) *string {
+	if jobPost.CompanyProfile != nil && jobPost.CompanyProfile.CompanyName != "" {
+		return &jobPost.CompanyProfile.CompanyName
 	}
+	return jobPost.User.CompanyName
+}
 
-	token, ok := user.(*jwt.Token) // Assert to *jwt.Token
-	if !ok {
-		return 0, fmt.Errorf("invalid token type")
+// getTargetUserID returns the user a per-user route operates on: the :userId
+// path parameter, or the caller's own ID on the /api/me routes.
+func getTargetUserID(c *fiber.Ctx) (uint, error) {
+	if c.Params("userId") == "" {
+		return middleware.UserIDFromToken(c)
 	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return 0, fmt.Errorf("invalid claims type")
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return 0, err
 	}
+	return uint(userID), nil
+}
 
-	// IMPORTANT:  Always validate the data type before using it!
-	userIDFloat, ok := claims["user_id"].(float64) // JWT IDs are often floats
-	if !ok {
-		return 0, fmt.Errorf("invalid user ID format in token")
-	}
-	userID := uint(userIDFloat) // Convert to uint
-	return userID, nil
+// getUserTypeFromToken returns the user type stored by the auth middleware.
+func getUserTypeFromToken(c *fiber.Ctx) string {
+	userType, _ := c.Locals("userType").(string)
+	return userType
 }
 
 // GetJobApplication handles GET /api/jobs/applications/:id
@@ -311,12 +415,20 @@ func (h *JobHandler) GetJobApplication(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid application ID"})
 	}
 
-	application, err := h.JobService.GetJobApplicationByID(uint(id))
+	userID, err := middleware.UserIDFromToken(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job application"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
 	}
-	if application == nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job application not found"})
+
+	// Only the applicant and the company that owns the job may see it.
+	application, err := h.JobService.AuthorizeApplicationAccess(uint(id), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job application not found"})
+		} else if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job application"})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(application)
@@ -336,16 +448,84 @@ func (h *JobHandler) UpdateJobApplication(c *fiber.Ctx) error {
 
 	application.ID = uint(id)
 
-	if err := h.JobService.UpdateJobApplication(&application); err != nil {
-		if err == gorm.ErrRecordNotFound {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	before, _ := h.JobService.GetJobApplicationByID(application.ID)
+	if err := h.JobService.UpdateJobApplication(&application, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job application not found"})
+		} else if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		} else if errors.Is(err, jobservice.ErrInvalidStatus) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid application status"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update job application"})
 	}
 
+	if before != nil && before.Status != application.Status {
+		h.audit(c, auditservice.Entry{
+			Action:     auditmodel.ActionApplicationStatusChanged,
+			TargetType: auditmodel.TargetJobApplication,
+			TargetID:   &application.ID,
+			Changes:    map[string]auditmodel.Change{"status": {Before: before.Status, After: application.Status}},
+		})
+		// Accepting the last open position closes the post.
+		if application.Status == jobmodel.JobApplicationStatusAccepted {
+			h.auditFilledJobPost(c, &before.JobPost)
+		}
+	}
+
 	return c.Status(fiber.StatusOK).JSON(application)
 }
 
+// auditFilledJobPost records the closing of a job post by the acceptance of
+// its last open position.
+func (h *JobHandler) auditFilledJobPost(c *fiber.Ctx, before *jobmodel.JobPost) {
+	if after, err := h.JobService.GetJobPostByID(before.ID); err == nil && after != nil && after.State != before.State {
+		h.audit(c, auditservice.Entry{
+			Action:     auditmodel.ActionJobPostStateChanged,
+			TargetType: auditmodel.TargetJobPost,
+			TargetID:   &after.ID,
+			Changes:    auditservice.Diff(before, after, "State"),
+			Details:    map[string]interface{}{"reason": "filled"},
+		})
+	}
+}
+
+// WithdrawJobApplication handles DELETE /api/jobs/applications/:id
+func (h *JobHandler) WithdrawJobApplication(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid application ID"})
+	}
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	if err := h.JobService.WithdrawJobApplication(uint(id), userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job application not found"})
+		} else if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		} else if errors.Is(err, jobservice.ErrCannotWithdraw) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to withdraw job application"})
+	}
+
+	applicationID := uint(id)
+	h.audit(c, auditservice.Entry{
+		Action:     auditmodel.ActionApplicationWithdrawn,
+		TargetType: auditmodel.TargetJobApplication,
+		TargetID:   &applicationID,
+	})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Application withdrawn successfully"})
+}
+
 // ListJobApplicationsForJob handles GET /api/jobs/:jobId/applications
 func (h *JobHandler) ListJobApplicationsForJob(c *fiber.Ctx) error {
 	jobID, err := strconv.ParseUint(c.Params("jobId"), 10, 64)
@@ -353,6 +533,19 @@ func (h *JobHandler) ListJobApplicationsForJob(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid job ID"})
 	}
 
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+	if _, err := h.JobService.AuthorizeJobPostAccess(uint(jobID), userID, false); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": errJobPostNotFound})
+		} else if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job post"})
+	}
+
 	applications, err := h.JobService.ListJobApplicationsByJobID(uint(jobID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job applications"})
@@ -371,6 +564,7 @@ func (h *JobHandler) ListJobApplicationsForJob(c *fiber.Ctx) error {
 		UpdatedAt      time.Time `json:"updated_at"`
 		GeminiSummary  string    `json:"gemini_summary,omitempty"`
 		Score          *float64  `json:"score,omitempty"`
+		RevisionID     *uint     `json:"revision_id,omitempty"` // Job post revision the score is based on
 	}
 
 	responseList := make([]ApplicationResponse, 0, len(applications))
@@ -393,30 +587,52 @@ func (h *JobHandler) ListJobApplicationsForJob(c *fiber.Ctx) error {
 			UpdatedAt:      app.UpdatedAt,
 			GeminiSummary:  app.GeminiSummary,
 			Score:          app.Score,
+			RevisionID:     app.RevisionID,
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(responseList)
 }
 
-// ListJobApplicationsForUser handles GET /api/jobs/user/:userId/applications
+// ListJobApplicationsForUser handles GET /api/jobs/user/:userId/applications and GET /api/me/applications
 func (h *JobHandler) ListJobApplicationsForUser(c *fiber.Ctx) error {
-	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	userID, err := getTargetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
-	applications, err := h.JobService.ListJobApplicationsByUserID(uint(userID))
+	loggedInUserID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	if userID == loggedInUserID {
+		applications, err := h.JobService.ListJobApplicationsByUserID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job applications"})
+		}
+		return c.Status(fiber.StatusOK).JSON(applications)
+	}
+
+	// Someone else's history: only a company may look, and only at the
+	// applications that were sent to its organization's job posts.
+	if getUserTypeFromToken(c) != authmodel.UserTypeCompany {
+		return middleware.Forbidden(c)
+	}
+	applications, err := h.JobService.ListJobApplicationsWithFilter("", userID, 0, loggedInUserID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job applications"})
 	}
+	if len(applications) == 0 {
+		return middleware.Forbidden(c)
+	}
 	return c.Status(fiber.StatusOK).JSON(applications)
 }
 
 // Saved Job Handlers
 
-// SaveJob handles POST /api/jobs/user/:userId/save/:jobId
+// SaveJob handles POST /api/jobs/user/:userId/save/:jobId and POST /api/me/saved/:jobId
 func (h *JobHandler) SaveJob(c *fiber.Ctx) error {
-	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	userID, err := getTargetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
@@ -425,7 +641,7 @@ func (h *JobHandler) SaveJob(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid job ID"})
 	}
 
-	if err := h.JobService.SaveJob(uint(userID), uint(jobID)); err != nil {
+	if err := h.JobService.SaveJob(userID, uint(jobID)); err != nil {
 		// Check for the specific duplicate save error.
 		if errors.Is(err, jobservice.ErrDuplicateSave) {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Job already saved"}) // 409 Conflict
@@ -436,9 +652,9 @@ func (h *JobHandler) SaveJob(c *fiber.Ctx) error {
 	return c.Status(http.StatusCreated).JSON(fiber.Map{"message": "Job saved successfully"})
 }
 
-// UnsaveJob handles DELETE /api/jobs/user/:userId/unsave/:jobId
+// UnsaveJob handles DELETE /api/jobs/user/:userId/unsave/:jobId and DELETE /api/me/saved/:jobId
 func (h *JobHandler) UnsaveJob(c *fiber.Ctx) error {
-	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	userID, err := getTargetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
@@ -447,7 +663,7 @@ func (h *JobHandler) UnsaveJob(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid job ID"}) // Corrected error message
 	}
 
-	if err := h.JobService.UnsaveJob(uint(userID), uint(jobID)); err != nil {
+	if err := h.JobService.UnsaveJob(userID, uint(jobID)); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job not saved for this user"})
 		}
@@ -458,16 +674,16 @@ func (h *JobHandler) UnsaveJob(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Job unsaved successfully"})
 }
 
-// ListSavedJobs handles GET /api/jobs/user/:userId/saved
+// ListSavedJobs handles GET /api/jobs/user/:userId/saved and GET /api/me/saved
 // jobhandler/jobhandler.go
 
 func (h *JobHandler) ListSavedJobs(c *fiber.Ctx) error {
-	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	userID, err := getTargetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
-	savedJobs, err := h.JobService.ListSavedJobs(uint(userID))
+	savedJobs, err := h.JobService.ListSavedJobs(userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve saved jobs"})
 	}
@@ -484,8 +700,12 @@ func (h *JobHandler) ListSavedJobs(c *fiber.Ctx) error {
 		JobPosition    string  `json:"job_position"`
 		CompanyName    *string `json:"company_name"` // Pointer to handle NULL
 		Status         bool    `json:"status"`
+		State          string  `json:"state"`
 		Quantity       int     `json:"quantity"`
 		ApplicantCount int64   `json:"applicant_count"`
+		jobmodel.Salary
+
+		CompanyProfile *authmodel.CompanyProfile `json:"company_profile"`
 	}
 
 	responseList := make([]SavedJobResponse, 0, len(savedJobs))
@@ -503,11 +723,6 @@ func (h *JobHandler) ListSavedJobs(c *fiber.Ctx) error {
 			})
 			continue // Skip to the next saved job
 		}
-		//Count applicant
-		count, err := h.JobService.CountApplicationsByJobID(savedJob.JobPost.ID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve applicant count"})
-		}
 		responseList = append(responseList, SavedJobResponse{
 			SavedJobID:     savedJob.ID,    // The SavedJob's ID
 			JobID:          savedJob.JobID, // The JobPost's ID
@@ -517,19 +732,22 @@ func (h *JobHandler) ListSavedJobs(c *fiber.Ctx) error {
 			Location:       savedJob.JobPost.Location,
 			SalaryRange:    savedJob.JobPost.SalaryRange,
 			JobPosition:    savedJob.JobPost.JobPosition,
-			CompanyName:    savedJob.JobPost.User.CompanyName, // Access the preloaded User
+			CompanyName:    companyName(&savedJob.JobPost),
 			Status:         savedJob.JobPost.Status,
+			State:          savedJob.JobPost.State,
 			Quantity:       savedJob.JobPost.Quantity,
-			ApplicantCount: count,
+			ApplicantCount: int64(savedJob.JobPost.ApplicantCount),
+			Salary:         savedJob.JobPost.Salary(),
+			CompanyProfile: savedJob.JobPost.CompanyProfile,
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(responseList)
 }
 
-// CheckIfJobIsSaved handles GET /api/jobs/user/:userId/saved/:jobId
+// CheckIfJobIsSaved handles GET /api/jobs/user/:userId/saved/:jobId and GET /api/me/saved/:jobId
 func (h *JobHandler) CheckIfJobIsSaved(c *fiber.Ctx) error {
-	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	userID, err := getTargetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
@@ -537,7 +755,7 @@ func (h *JobHandler) CheckIfJobIsSaved(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid job ID4"})
 	}
-	isSaved, err := h.JobService.IsJobSaved(uint(userID), uint(jobID))
+	isSaved, err := h.JobService.IsJobSaved(userID, uint(jobID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check if job is saved"})
 	}
@@ -569,7 +787,27 @@ func (h *JobHandler) ListJobApplications(c *fiber.Ctx) error {
 		}
 	}
 
-	applications, err := h.JobService.ListJobApplicationsWithFilter(status, uint(userID), uint(jobID))
+	loggedInUserID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	// Applicants only see their own applications; companies only see
+	// applications to their organization's job posts.
+	var ownerID uint
+	switch getUserTypeFromToken(c) {
+	case authmodel.UserTypeApplicant:
+		if userID != 0 && uint(userID) != loggedInUserID {
+			return middleware.Forbidden(c)
+		}
+		userID = uint64(loggedInUserID)
+	case authmodel.UserTypeCompany:
+		ownerID = loggedInUserID
+	default:
+		return middleware.Forbidden(c)
+	}
+
+	applications, err := h.JobService.ListJobApplicationsWithFilter(status, uint(userID), uint(jobID), ownerID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job applications"})
 	}
@@ -585,6 +823,10 @@ func (h *JobHandler) ListJobPostsByUserID(c *fiber.Ctx) error {
 	}
 
 	jobPosts, err := h.JobService.ListJobPostsByUserID(uint(userID)) // Call the service
+	if err == nil {
+		viewerID, _ := middleware.UserIDFromToken(c)
+		jobPosts, err = h.JobService.VisibleJobPosts(jobPosts, viewerID)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job posts"})
 	}
@@ -599,19 +841,17 @@ func (h *JobHandler) ListJobPostsByUserID(c *fiber.Ctx) error {
 		JobPosition    string  `json:"job_position"`
 		CompanyName    *string `json:"company_name"` // Use a pointer to handle nil
 		Status         bool    `json:"status"`       // Add the Status field
+		State          string  `json:"state"`
 		Quantity       int     `json:"quantity"`
 		ApplicantCount int64   `json:"applicant_count"` // Add applicant count
 		UserID         uint    `json:"user_id"`
+		jobmodel.Salary
+
+		CompanyProfile *authmodel.CompanyProfile `json:"company_profile"`
 	}
 
 	responseList := make([]Response, 0, len(jobPosts))
 	for _, jobPost := range jobPosts {
-		// Get the applicant count for EACH job post.
-		count, err := h.JobService.CountApplicationsByJobID(jobPost.ID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve applicant count"})
-		}
-
 		responseList = append(responseList, Response{
 			ID:             jobPost.ID,
 			Title:          jobPost.Title,
@@ -619,11 +859,14 @@ func (h *JobHandler) ListJobPostsByUserID(c *fiber.Ctx) error {
 			Location:       jobPost.Location,
 			SalaryRange:    jobPost.SalaryRange,
 			JobPosition:    jobPost.JobPosition,
-			CompanyName:    jobPost.User.CompanyName, // Direct Access
+			CompanyName:    companyName(&jobPost),
 			Status:         jobPost.Status,
+			State:          jobPost.State,
 			Quantity:       jobPost.Quantity,
-			ApplicantCount: count,
+			ApplicantCount: int64(jobPost.ApplicantCount),
 			UserID:         jobPost.UserID,
+			Salary:         jobPost.Salary(),
+			CompanyProfile: jobPost.CompanyProfile,
 		})
 	}
 	return c.Status(fiber.StatusOK).JSON(responseList)
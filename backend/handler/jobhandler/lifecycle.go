@@ -0,0 +1,86 @@
+package jobhandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/service/auditservice"
+	"backend/pkg/service/jobservice"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ChangeJobPostState handles PUT /api/jobs/:id/state
+// Body: {"state": "draft|scheduled|open|paused|closed|archived", "publish_at": RFC 3339, "expires_at": RFC 3339}
+// A scheduled post opens at publish_at and an open post closes at expires_at.
+func (h *JobHandler) ChangeJobPostState(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errInvalidJobID})
+	}
+	var req jobmodel.JobPostStateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	before, _ := h.JobService.GetJobPostByID(uint(id))
+	jobPost, err := h.JobService.ChangeJobPostState(uint(id), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": errJobPostNotFound})
+		case errors.Is(err, jobservice.ErrUnauthorized):
+			return middleware.Forbidden(c)
+		case errors.Is(err, jobservice.ErrInvalidStateTransition):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case isLifecycleError(err):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change job post state"})
+	}
+
+	h.audit(c, auditservice.Entry{
+		Action:     auditmodel.ActionJobPostStateChanged,
+		TargetType: auditmodel.TargetJobPost,
+		TargetID:   &jobPost.ID,
+		Changes:    auditservice.Diff(before, jobPost, "State", "PublishAt", "ExpiresAt"),
+	})
+	return c.Status(fiber.StatusOK).JSON(jobPost)
+}
+
+// isLifecycleError reports whether err is a rejected state or schedule.
+func isLifecycleError(err error) bool {
+	return errors.Is(err, jobservice.ErrInvalidState) ||
+		errors.Is(err, jobservice.ErrInvalidStateTransition) ||
+		errors.Is(err, jobservice.ErrInvalidSchedule)
+}
+
+// lifecycleFields are the keys of a job post body that only
+// ChangeJobPostState may set.
+var lifecycleFields = []string{"State", "Status", "PublishAt", "ExpiresAt", "publish_at", "expires_at"}
+
+// lifecycleFieldIn returns the first lifecycle key set in a JSON job post
+// body, matched without regard to case as the body parser does, or "".
+func lifecycleFieldIn(body []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+	for key := range fields {
+		for _, field := range lifecycleFields {
+			if strings.EqualFold(key, field) {
+				return key
+			}
+		}
+	}
+	return ""
+}
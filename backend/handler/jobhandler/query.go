@@ -0,0 +1,96 @@
+package jobhandler
+
+import (
+	"backend/pkg/model/jobmodel"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseJobPostQuery reads the filters of a job post listing from the query
+// string.
+func parseJobPostQuery(c *fiber.Ctx) (jobmodel.JobPostQuery, error) {
+	query := jobmodel.JobPostQuery{
+		Keyword:        c.Query("q"),
+		Location:       c.Query("location"),
+		JobPosition:    c.Query("job_position"),
+		SalaryCurrency: c.Query("salary_currency"),
+		SalaryPeriod:   c.Query("salary_period"),
+		Sort:           c.Query("sort"),
+		Cursor:         c.Query("cursor"),
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "open":
+		open := true
+		query.Status = &open
+	case "closed":
+		closed := false
+		query.Status = &closed
+	default:
+		return query, fmt.Errorf("status must be open or closed")
+	}
+
+	var err error
+	if raw := c.Query("company_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid company_id")
+		}
+		companyID := uint(id)
+		query.CompanyID = &companyID
+	}
+	if query.SalaryMin, err = queryInt(c, "salary_min"); err != nil {
+		return query, err
+	}
+	if query.SalaryMax, err = queryInt(c, "salary_max"); err != nil {
+		return query, err
+	}
+	if query.CreatedFrom, err = queryDate(c, "created_from", false); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = queryDate(c, "created_to", true); err != nil {
+		return query, err
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if query.Limit, err = strconv.Atoi(raw); err != nil || query.Limit < 1 {
+			return query, fmt.Errorf("invalid limit")
+		}
+	}
+	return query, nil
+}
+
+func queryInt(c *fiber.Ctx, name string) (*int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &value, nil
+}
+
+// queryDate reads a YYYY-MM-DD or RFC 3339 parameter.  A plain date used as
+// an upper bound includes the whole day.
+func queryDate(c *fiber.Ctx, name string, endOfDay bool) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected YYYY-MM-DD or an RFC 3339 timestamp", name)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
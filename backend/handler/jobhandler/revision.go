@@ -0,0 +1,97 @@
+package jobhandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/service/auditservice"
+	"backend/pkg/service/jobservice"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// revisionFields are compared between revisions, by their JSON names.
+var revisionFields = []string{
+	"title", "description", "location", "job_position", "quantity", "salary_range",
+	"salary_min", "salary_max", "salary_currency", "salary_period", "salary_negotiable",
+}
+
+// ListJobPostRevisions handles GET /api/jobs/:id/revisions?from=&to=
+// Every revision comes with the fields that changed since the one before it.
+// With from and to, only the changes between those two revision numbers are
+// returned.
+func (h *JobHandler) ListJobPostRevisions(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errInvalidJobID})
+	}
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized})
+	}
+
+	revisions, err := h.JobService.ListJobPostRevisions(uint(id), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": errJobPostNotFound})
+		} else if errors.Is(err, jobservice.ErrUnauthorized) {
+			return middleware.Forbidden(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve job post revisions"})
+	}
+
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, fromErr := findRevision(revisions, c.Query("from"))
+		to, toErr := findRevision(revisions, c.Query("to"))
+		if fromErr != nil || toErr != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from and to must both be revision numbers of this job post"})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"from":    from.Number,
+			"to":      to.Number,
+			"changes": revisionChanges(from, to),
+		})
+	}
+
+	type Response struct {
+		jobmodel.JobPostRevision
+		Changes map[string]auditmodel.Change `json:"changes"` // Since the previous revision; every field for the first
+	}
+	responseList := make([]Response, 0, len(revisions))
+	for i := range revisions {
+		var previous *jobmodel.JobPostRevision
+		if i > 0 {
+			previous = &revisions[i-1]
+		}
+		responseList = append(responseList, Response{
+			JobPostRevision: revisions[i],
+			Changes:         revisionChanges(previous, &revisions[i]),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(responseList)
+}
+
+// revisionChanges lists the fields that differ between two revisions.
+func revisionChanges(before, after *jobmodel.JobPostRevision) map[string]auditmodel.Change {
+	changes := auditservice.Diff(before, after, revisionFields...)
+	if changes == nil {
+		changes = map[string]auditmodel.Change{}
+	}
+	return changes
+}
+
+func findRevision(revisions []jobmodel.JobPostRevision, number string) (*jobmodel.JobPostRevision, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Number == n {
+			return &revisions[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
@@ -0,0 +1,86 @@
+package jobhandler
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/search"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchJobPosts handles GET /api/jobs/search?q=&offset=&limit=
+// Open posts with every word of q in their title, description, position or
+// company name, ranked by relevance.  Pass next_offset back as offset to get
+// the next page.
+func (h *JobHandler) SearchJobPosts(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q is required"})
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid offset"})
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "0"))
+	if err != nil || limit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid limit"})
+	}
+
+	page, err := h.JobService.SearchJobPosts(query, offset, limit)
+	if err != nil {
+		if errors.Is(err, search.ErrEmptyQuery) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to search job posts"})
+	}
+
+	type Response struct {
+		ID             uint              `json:"id"`
+		Title          string            `json:"title"`
+		Description    string            `json:"description"`
+		Location       string            `json:"location"`
+		SalaryRange    string            `json:"salary_range"`
+		JobPosition    string            `json:"job_position"`
+		CompanyName    *string           `json:"company_name"`
+		Status         bool              `json:"status"`
+		Quantity       int               `json:"quantity"`
+		ApplicantCount int64             `json:"applicant_count"`
+		UserID         uint              `json:"user_id"`
+		Score          float64           `json:"score"`
+		Highlights     map[string]string `json:"highlights"` // HTML; matches are wrapped in <mark>
+		jobmodel.Salary
+
+		CompanyProfile *authmodel.CompanyProfile `json:"company_profile"`
+	}
+
+	responseList := make([]Response, 0, len(page.Results))
+	for _, result := range page.Results {
+		jobPost := result.JobPost
+		responseList = append(responseList, Response{
+			ID:             jobPost.ID,
+			Title:          jobPost.Title,
+			Description:    jobPost.Description,
+			Location:       jobPost.Location,
+			SalaryRange:    jobPost.SalaryRange,
+			JobPosition:    jobPost.JobPosition,
+			CompanyName:    companyName(&jobPost),
+			Status:         jobPost.Status,
+			Quantity:       jobPost.Quantity,
+			ApplicantCount: int64(jobPost.ApplicantCount),
+			UserID:         jobPost.UserID,
+			Score:          result.Score,
+			Highlights:     result.Highlights,
+			Salary:         jobPost.Salary(),
+			CompanyProfile: jobPost.CompanyProfile,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"results":     responseList,
+		"total":       page.Total,
+		"next_offset": page.NextOffset,
+	})
+}
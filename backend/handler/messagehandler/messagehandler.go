@@ -2,12 +2,15 @@
 package messagehandler
 
 import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/service/auditservice"
 	"backend/pkg/service/messageservice"
 	"fmt"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type IMessageHandler interface {
@@ -19,10 +22,11 @@ type IMessageHandler interface {
 
 type MessageHandler struct {
 	MessageService messageservice.IMessageService
+	Audit          auditservice.IAuditService
 }
 
-func NewMessageHandler(messageService messageservice.IMessageService) *MessageHandler {
-	return &MessageHandler{MessageService: messageService}
+func NewMessageHandler(messageService messageservice.IMessageService, audit auditservice.IAuditService) *MessageHandler {
+	return &MessageHandler{MessageService: messageService, Audit: audit}
 }
 
 // SendMessage handles POST /api/messages
@@ -53,6 +57,14 @@ func (h *MessageHandler) SendMessage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send message"})
 	}
 
+	h.audit(c, auditservice.Entry{
+		ActorID:    &senderID,
+		Action:     auditmodel.ActionMessageSent,
+		TargetType: auditmodel.TargetMessage,
+		TargetID:   &userMessage.ID,
+		Details:    map[string]interface{}{"receiver_id": req.ReceiverID, "job_id": req.JobID},
+	})
+
 	// Return both messages to the client.
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"user_message": userMessage,
@@ -79,12 +91,12 @@ func (h *MessageHandler) ViewMessages(c *fiber.Ctx) error {
 	}
 
 	// Authorization logic
-	if loggedInUserType == "applicant" {
+	if loggedInUserType == authmodel.UserTypeApplicant {
 		// Applicants can ONLY see their own messages.
 		if uint(userID) != loggedInUserID {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+			return middleware.Forbidden(c)
 		}
-	} else if loggedInUserType == "company" {
+	} else if loggedInUserType == authmodel.UserTypeCompany {
 		// For company users, we use the service layer to check ownership.
 		messages, err := h.MessageService.GetMessagesForUser(uint(userID), loggedInUserID, loggedInUserType) // Pass all info
 		if err != nil {
@@ -94,7 +106,7 @@ func (h *MessageHandler) ViewMessages(c *fiber.Ctx) error {
 
 	} else {
 		// Handle other user types (or deny access)
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden - Invalid user type"})
+		return middleware.Forbidden(c)
 	}
 
 	// If we get here, it's either an applicant viewing their own messages,
@@ -113,6 +125,10 @@ func (h *MessageHandler) GetMessage(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid message ID"})
 	}
+	loggedInUserID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
 	message, err := h.MessageService.GetMessageByID(uint(messageID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve message"})
@@ -120,6 +136,10 @@ func (h *MessageHandler) GetMessage(c *fiber.Ctx) error {
 	if message == nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Message not found"})
 	}
+	// Only the two participants may read a message.
+	if message.SenderID != loggedInUserID && message.ReceiverID != loggedInUserID {
+		return middleware.Forbidden(c)
+	}
 	return c.Status(fiber.StatusOK).JSON(message)
 
 }
@@ -131,8 +151,8 @@ func (h *MessageHandler) RespondToApplicant(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 	senderUserType, ok := c.Locals("userType").(string)
-	if !ok || senderUserType != "company" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	if !ok || senderUserType != authmodel.UserTypeCompany {
+		return middleware.Forbidden(c)
 	}
 
 	// 2. Get the job ID from the URL parameter.
@@ -155,31 +175,12 @@ func (h *MessageHandler) RespondToApplicant(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Failed to send message: %v", err)})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Response sent successfully", "response": responseText})
-}
-
-// Helper function to get user id from jwt token (Same as previous GetUserProfile)
-func getUserIDFromToken(c *fiber.Ctx) (uint, error) {
-	user := c.Locals("user") // Get the user object from context (set by middleware)
-	if user == nil {
-		return 0, fmt.Errorf("no user in context")
-	}
-
-	token, ok := user.(*jwt.Token) // Assert to *jwt.Token  <-- CORRECT TYPE
-	if !ok {
-		return 0, fmt.Errorf("invalid token type")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims) // Get the claims
-	if !ok {
-		return 0, fmt.Errorf("invalid claims type")
-	}
-
-	userIDFloat, ok := claims["user_id"].(float64) // JWT IDs are often floats
-	if !ok {
-		return 0, fmt.Errorf("invalid user ID format in token")
-	}
-	userID := uint(userIDFloat) // Convert to uint
+	h.audit(c, auditservice.Entry{
+		ActorID:    &senderID,
+		Action:     auditmodel.ActionMessageSent,
+		TargetType: auditmodel.TargetMessage,
+		Details:    map[string]interface{}{"receiver_id": req.ReceiverID, "job_id": jobID},
+	})
 
-	return userID, nil
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Response sent successfully", "response": responseText})
 }
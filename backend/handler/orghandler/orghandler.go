@@ -0,0 +1,216 @@
+package orghandler
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/service/orgservice"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IOrgHandler interface {
+	GetMyOrganization(c *fiber.Ctx) error
+	UpdateMyOrganization(c *fiber.Ctx) error
+	ListMembers(c *fiber.Ctx) error
+	UpdateMember(c *fiber.Ctx) error
+	RemoveMember(c *fiber.Ctx) error
+	InviteMember(c *fiber.Ctx) error
+	ListInvitations(c *fiber.Ctx) error
+	RevokeInvitation(c *fiber.Ctx) error
+	AcceptInvitation(c *fiber.Ctx) error
+}
+
+type OrgHandler struct {
+	OrgService orgservice.IOrgService
+}
+
+func NewOrgHandler(orgService orgservice.IOrgService) *OrgHandler {
+	return &OrgHandler{OrgService: orgService}
+}
+
+// GetMyOrganization handles GET /api/org
+func (h *OrgHandler) GetMyOrganization(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	member, err := h.OrgService.GetMembership(userID)
+	if err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"organization": member.Organization,
+		"role":         member.Role,
+	})
+}
+
+// UpdateMyOrganization handles PUT /api/org
+func (h *OrgHandler) UpdateMyOrganization(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req orgmodel.UpdateOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	org, err := h.OrgService.UpdateOrganization(userID, &req)
+	if err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(org)
+}
+
+// ListMembers handles GET /api/org/members
+func (h *OrgHandler) ListMembers(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	members, err := h.OrgService.ListMembers(userID)
+	if err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(members)
+}
+
+// UpdateMember handles PUT /api/org/members/:userId
+func (h *OrgHandler) UpdateMember(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	memberUserID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	var req orgmodel.UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if err := h.OrgService.UpdateMemberRole(userID, uint(memberUserID), req.Role); err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Member role updated successfully"})
+}
+
+// RemoveMember handles DELETE /api/org/members/:userId.  Members can remove
+// themselves to leave the organization.
+func (h *OrgHandler) RemoveMember(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	memberUserID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	if err := h.OrgService.RemoveMember(userID, uint(memberUserID)); err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Member removed successfully"})
+}
+
+// InviteMember handles POST /api/org/invitations
+func (h *OrgHandler) InviteMember(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req orgmodel.InviteMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if !strings.Contains(req.Email, "@") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A valid email is required"})
+	}
+
+	invitation, err := h.OrgService.InviteMember(userID, &req)
+	if err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(invitation)
+}
+
+// ListInvitations handles GET /api/org/invitations
+func (h *OrgHandler) ListInvitations(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	invitations, err := h.OrgService.ListInvitations(userID)
+	if err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(invitations)
+}
+
+// RevokeInvitation handles DELETE /api/org/invitations/:id
+func (h *OrgHandler) RevokeInvitation(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid invitation ID"})
+	}
+
+	if err := h.OrgService.RevokeInvitation(userID, uint(id)); err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitation handles POST /api/org/invitations/accept
+func (h *OrgHandler) AcceptInvitation(c *fiber.Ctx) error {
+	userID, err := middleware.UserIDFromToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	var req orgmodel.AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token is required"})
+	}
+
+	member, err := h.OrgService.AcceptInvitation(userID, req.Token)
+	if err != nil {
+		return orgErrorResponse(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"organization": member.Organization,
+		"role":         member.Role,
+	})
+}
+
+// orgErrorResponse maps organization service errors to HTTP responses.
+func orgErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, orgservice.ErrNoOrganization),
+		errors.Is(err, orgservice.ErrMemberNotFound),
+		errors.Is(err, orgservice.ErrInvitationNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, orgservice.ErrNotOwner),
+		errors.Is(err, orgservice.ErrNotCompany),
+		errors.Is(err, orgservice.ErrInvitationForOther),
+		errors.Is(err, orgservice.ErrEmailNotVerified):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, orgservice.ErrLastOwner),
+		errors.Is(err, orgservice.ErrAlreadyMember):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, orgservice.ErrInvalidRole),
+		errors.Is(err, orgservice.ErrOrganizationNameBlank),
+		errors.Is(err, orgservice.ErrInvalidTemplate):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to process organization request"})
+}
@@ -0,0 +1,294 @@
+// Package feed renders job posts for job aggregators and search engines:
+// RSS 2.0 and Atom feeds, and schema.org JobPosting JSON-LD.
+package feed
+
+import (
+	"backend/pkg/model/jobmodel"
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"strings"
+	"time"
+)
+
+// Item is a job post as it appears in a feed.
+type Item struct {
+	ID           uint
+	Title        string
+	Description  string
+	Company      string
+	CompanyLogo  string // Absolute URL; empty when the company has none
+	Location     string
+	Salary       jobmodel.Salary
+	Quantity     int
+	URL          string // Page of the job post
+	Published    time.Time
+	Updated      time.Time
+	ValidThrough *time.Time
+}
+
+// Channel describes a feed as a whole.
+type Channel struct {
+	Title       string
+	Description string
+	Link        string // Site the feed belongs to
+	SelfURL     string // Address of the feed itself
+}
+
+// Updated returns when the newest of items changed, or the zero time for no
+// items.
+func Updated(items []Item) time.Time {
+	var updated time.Time
+	for _, item := range items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+	return updated
+}
+
+// summary is the plain-text body of an item: who is hiring, where and for
+// how much, then the description.
+func (item Item) summary() string {
+	var lines []string
+	for _, line := range []string{
+		"Company: " + item.Company,
+		"Location: " + item.Location,
+		"Salary: " + item.Salary.String(),
+	} {
+		if !strings.HasSuffix(line, ": ") {
+			lines = append(lines, line)
+		}
+	}
+	if item.Description != "" {
+		lines = append(lines, "", item.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (item Item) heading() string {
+	if item.Company == "" {
+		return item.Title
+	}
+	return item.Title + " at " + item.Company
+}
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomSpace string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders items as an RSS 2.0 feed.
+func RSS(channel Channel, items []Item) ([]byte, error) {
+	feed := rssFeed{
+		Version:   "2.0",
+		AtomSpace: "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       channel.Title,
+			Link:        channel.Link,
+			Description: channel.Description,
+			Self:        atomLink{Href: channel.SelfURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]rssItem, 0, len(items)),
+		},
+	}
+	if updated := Updated(items); !updated.IsZero() {
+		feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.heading(),
+			Link:        item.URL,
+			Description: item.summary(),
+			GUID:        rssGUID{IsPermaLink: true, Value: item.URL},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshalXML(feed)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Link      atomLink   `xml:"link"`
+	Author    atomPerson `xml:"author"`
+	Summary   atomText   `xml:"summary"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom renders items as an Atom feed.
+func Atom(channel Channel, items []Item) ([]byte, error) {
+	updated := Updated(items)
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	feed := atomFeed{
+		Title:   channel.Title,
+		ID:      channel.SelfURL,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: channel.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: channel.Link, Rel: "alternate"},
+		},
+		Author:  atomPerson{Name: channel.Title},
+		Entries: make([]atomEntry, 0, len(items)),
+	}
+	for _, item := range items {
+		author := item.Company
+		if author == "" {
+			author = channel.Title
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     item.heading(),
+			ID:        item.URL,
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: item.URL, Rel: "alternate"},
+			Author:    atomPerson{Name: author},
+			Summary:   atomText{Type: "text", Value: item.summary()},
+		})
+	}
+	return marshalXML(feed)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+type jobPosting struct {
+	Context            string          `json:"@context"`
+	Type               string          `json:"@type"`
+	Title              string          `json:"title"`
+	Description        string          `json:"description"`
+	Identifier         propertyValue   `json:"identifier"`
+	URL                string          `json:"url,omitempty"`
+	DatePosted         string          `json:"datePosted"`
+	ValidThrough       string          `json:"validThrough,omitempty"`
+	TotalJobOpenings   int             `json:"totalJobOpenings,omitempty"`
+	HiringOrganization organization    `json:"hiringOrganization"`
+	JobLocation        *place          `json:"jobLocation,omitempty"`
+	BaseSalary         *monetaryAmount `json:"baseSalary,omitempty"`
+}
+
+type propertyValue struct {
+	Type  string `json:"@type"`
+	Name  string `json:"name"`
+	Value uint   `json:"value"`
+}
+
+type organization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	Logo string `json:"logo,omitempty"`
+}
+
+type place struct {
+	Type    string        `json:"@type"`
+	Address postalAddress `json:"address"`
+}
+
+type postalAddress struct {
+	Type            string `json:"@type"`
+	AddressLocality string `json:"addressLocality"`
+}
+
+type monetaryAmount struct {
+	Type     string            `json:"@type"`
+	Currency string            `json:"currency"`
+	Value    quantitativeValue `json:"value"`
+}
+
+type quantitativeValue struct {
+	Type     string `json:"@type"`
+	Value    *int   `json:"value,omitempty"`
+	MinValue *int   `json:"minValue,omitempty"`
+	MaxValue *int   `json:"maxValue,omitempty"`
+	UnitText string `json:"unitText"`
+}
+
+// JobPosting renders an item as schema.org JobPosting JSON-LD.
+func JobPosting(item Item) ([]byte, error) {
+	posting := jobPosting{
+		Context:            "https://schema.org/",
+		Type:               "JobPosting",
+		Title:              item.Title,
+		Description:        strings.ReplaceAll(html.EscapeString(item.Description), "\n", "<br>"), // HTML, as the schema asks for
+		Identifier:         propertyValue{Type: "PropertyValue", Name: item.Company, Value: item.ID},
+		URL:                item.URL,
+		DatePosted:         item.Published.UTC().Format(time.RFC3339),
+		TotalJobOpenings:   item.Quantity,
+		HiringOrganization: organization{Type: "Organization", Name: item.Company, Logo: item.CompanyLogo},
+	}
+	if item.ValidThrough != nil {
+		posting.ValidThrough = item.ValidThrough.UTC().Format(time.RFC3339)
+	}
+	if item.Location != "" {
+		posting.JobLocation = &place{
+			Type:    "Place",
+			Address: postalAddress{Type: "PostalAddress", AddressLocality: item.Location},
+		}
+	}
+	if s := item.Salary; s.Min != nil || s.Max != nil {
+		value := quantitativeValue{Type: "QuantitativeValue", UnitText: strings.ToUpper(s.Period)}
+		if s.Min != nil && s.Max != nil && *s.Min == *s.Max {
+			value.Value = s.Min
+		} else {
+			value.MinValue, value.MaxValue = s.Min, s.Max
+		}
+		posting.BaseSalary = &monetaryAmount{Type: "MonetaryAmount", Currency: s.Currency, Value: value}
+	}
+	return json.MarshalIndent(posting, "", "  ")
+}
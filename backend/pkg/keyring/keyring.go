@@ -0,0 +1,266 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey         = errors.New("unknown signing key")
+	ErrUnexpectedAlg      = errors.New("unexpected signing method")
+	ErrNoSigningKey       = errors.New("keyring has no signing key")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
+
+// legacyKeyID is used for the shared-secret HS256 key.  Tokens signed before
+// key IDs existed have no kid header and are matched to it.
+const legacyKeyID = "hs256"
+
+// Key is one signing or verification key.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signKey is nil for verification-only keys (retired keys, or public keys
+	// of other services).
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key has private material.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring holds the active signing key and every key that tokens may still
+// be verified with.  It is loaded once at startup.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// New builds a keyring from the given keys.  activeID selects the signing key.
+func New(activeID string, keys ...*Key) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if _, exists := kr.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		kr.keys[key.ID] = key
+	}
+
+	active, ok := kr.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q: %w", activeID, ErrUnknownKey)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	kr.active = active
+	return kr, nil
+}
+
+// NewHMACKey returns an HS256 key for a shared secret.
+func NewHMACKey(secret []byte) *Key {
+	return &Key{ID: legacyKeyID, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// NewKey wraps a parsed RSA or Ed25519 private key (signing and verification)
+// or public key (verification only).
+func NewKey(id string, key interface{}) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	}
+	return nil, fmt.Errorf("key %q: %w (%T)", id, ErrUnsupportedKeyType, key)
+}
+
+// LoadFromEnv builds the keyring from the environment:
+//
+//   - JWT_KEYS_DIR: directory of PEM files named <kid>.pem.  Private keys
+//     (PKCS#8, or PKCS#1 for RSA) can sign; public keys (PKIX) only verify.
+//   - JWT_ACTIVE_KID: the kid to sign new tokens with.  Optional when the
+//     directory holds exactly one private key.
+//   - JWT_SECRET_KEY: HS256 secret.  Used for signing when JWT_KEYS_DIR is
+//     unset; otherwise kept for verification so tokens issued before the
+//     switch stay valid until they expire.
+func LoadFromEnv() (*Keyring, error) {
+	var keys []*Key
+	if secret := os.Getenv("JWT_SECRET_KEY"); secret != "" {
+		keys = append(keys, NewHMACKey([]byte(secret)))
+	}
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("either JWT_KEYS_DIR or JWT_SECRET_KEY environment variable must be set")
+		}
+		return New(legacyKeyID, keys...)
+	}
+
+	fileKeys, err := loadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, fileKeys...)
+
+	activeID := os.Getenv("JWT_ACTIVE_KID")
+	if activeID == "" {
+		var signers []string
+		for _, key := range fileKeys {
+			if key.CanSign() {
+				signers = append(signers, key.ID)
+			}
+		}
+		if len(signers) != 1 {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID must be set when JWT_KEYS_DIR has %d private keys", len(signers))
+		}
+		activeID = signers[0]
+	}
+	return New(activeID, keys...)
+}
+
+func loadDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	sort.Strings(paths)
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		parsed, err := parsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		key, err := NewKey(id, parsed)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parsePEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// Sign signs the claims with the active key and sets the kid header.
+func (kr *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if kr.active == nil {
+		return "", ErrNoSigningKey
+	}
+	token := jwt.NewWithClaims(kr.active.Method, claims)
+	token.Header["kid"] = kr.active.ID
+	signed, err := token.SignedString(kr.active.signKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// Parse verifies a token against the keyring and returns it with MapClaims.
+func (kr *Keyring) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, kr.Keyfunc)
+}
+
+// Keyfunc resolves the verification key from the kid header and checks that
+// the token's algorithm is the one the key is meant for.
+func (kr *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = legacyKeyID
+	}
+	key, ok := kr.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("%w: %v", ErrUnexpectedAlg, token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a JSON Web Key (RFC 7517) for a public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the body of /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services can verify tokens with.
+// Shared HMAC secrets are never published.
+func (kr *Keyring) JWKS() JWKSet {
+	ids := make([]string, 0, len(kr.keys))
+	for id := range kr.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range ids {
+		key := kr.keys[id]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+)
+
+// IMailer sends plain-text emails.
+type IMailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends emails through an SMTP server using PLAIN auth.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	From     string
+	Password string
+}
+
+// NewSMTPMailer creates a new SMTPMailer.
+func NewSMTPMailer(host, port, from, password string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, From: from, Password: password}
+}
+
+// NewSMTPMailerFromEnv reads EMAIL_FROM, EMAIL_PASS, SMTP_HOST and SMTP_PORT.
+// SMTP_HOST and SMTP_PORT default to Gmail.
+func NewSMTPMailerFromEnv() (*SMTPMailer, error) {
+	from := os.Getenv("EMAIL_FROM")
+	pass := os.Getenv("EMAIL_PASS")
+	if from == "" || pass == "" {
+		return nil, fmt.Errorf("EMAIL_FROM and EMAIL_PASS environment variables must be set")
+	}
+
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		host = "smtp.gmail.com"
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	return NewSMTPMailer(host, port, from, pass), nil
+}
+
+// Send sends a plain-text email.
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	msg := []byte(fmt.Sprintf("From: %s\r\n"+
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=\"UTF-8\"\r\n"+
+		"\r\n"+
+		"%s\r\n", m.From, to, subject, strings.ReplaceAll(body, "\n", "\r\n")))
+
+	auth := smtp.PlainAuth("", m.From, m.Password, m.Host)
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// Mail is an email captured by MemoryMailer.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MemoryMailer keeps sent emails in memory instead of delivering them.
+// It is meant for tests and local development.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Mail
+}
+
+// NewMemoryMailer creates a new MemoryMailer.
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the email.
+func (m *MemoryMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, Mail{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// Sent returns a copy of all emails sent so far.
+func (m *MemoryMailer) Sent() []Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Mail(nil), m.sent...)
+}
+
+// Last returns the most recent email sent to the given address.
+func (m *MemoryMailer) Last(to string) (Mail, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To == to {
+			return m.sent[i], true
+		}
+	}
+	return Mail{}, false
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import "testing"
+
+func TestMemoryMailerKeepsSentMail(t *testing.T) {
+	m := NewMemoryMailer()
+	for _, mail := range []Mail{
+		{To: "a@example.com", Subject: "first", Body: "1"},
+		{To: "b@example.com", Subject: "second", Body: "2"},
+		{To: "a@example.com", Subject: "third", Body: "3"},
+	} {
+		if err := m.Send(mail.To, mail.Subject, mail.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sent := m.Sent(); len(sent) != 3 {
+		t.Fatalf("Sent() has %d mails, want 3", len(sent))
+	}
+	last, ok := m.Last("a@example.com")
+	if !ok || last.Subject != "third" {
+		t.Errorf("Last(a@example.com) = %+v, %v; want the third mail", last, ok)
+	}
+	if _, ok := m.Last("c@example.com"); ok {
+		t.Error("Last(c@example.com) found a mail that was never sent")
+	}
+
+	// Sent returns a copy.
+	m.Sent()[0].Subject = "changed"
+	if m.Sent()[0].Subject != "first" {
+		t.Error("changing the result of Sent() changed the mailer")
+	}
+}
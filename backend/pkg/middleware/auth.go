@@ -2,17 +2,43 @@
 package middleware
 
 import (
+	"backend/pkg/keyring"
 	"errors"
-	"fmt"
-	"os"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5" // CORRECT IMPORT: /v5
 )
 
-// AuthMiddleware is a basic JWT authentication middleware.
+// SessionValidator reports whether the session an access token was issued for
+// is still active (i.e. has not been logged out or revoked).
+type SessionValidator interface {
+	IsSessionActive(userID uint, sessionID string) (bool, error)
+}
+
+var sessionValidator SessionValidator
+
+// UseSessionValidator installs the validator AuthMiddleware uses to reject
+// tokens of revoked sessions.  Call it once at startup.
+func UseSessionValidator(v SessionValidator) {
+	sessionValidator = v
+}
+
+var tokenKeyring *keyring.Keyring
+
+// UseKeyring installs the keyring AuthMiddleware verifies tokens with.
+// Call it once at startup.
+func UseKeyring(kr *keyring.Keyring) {
+	tokenKeyring = kr
+}
+
+// AuthMiddleware is a basic JWT authentication middleware.  Requests can
+// present an API key instead (see RequireScope).
 func AuthMiddleware(c *fiber.Ctx) error {
+	if key, ok := apiKeyFromRequest(c); ok {
+		return authenticateAPIKey(c, key)
+	}
+
 	authHeader := c.Get("Authorization") // Get the Authorization header
 	if authHeader == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authorization header is required"})
@@ -26,19 +52,13 @@ func AuthMiddleware(c *fiber.Ctx) error {
 
 	tokenString := parts[1] // Get the token part
 
-	// Parse the token.
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method. VERY IMPORTANT!
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// Get secret key from environment
-		secretKey := os.Getenv("JWT_SECRET_KEY")
-		if secretKey == "" {
-			return nil, fmt.Errorf("JWT_SECRET_KEY environment variable not set")
-		}
-		return []byte(secretKey), nil // Return the secret key.  MUST be []byte
-	})
+	if tokenKeyring == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Token verification is not configured"})
+	}
+
+	// Parse the token.  The keyring picks the key from the kid header and
+	// rejects tokens whose algorithm doesn't match that key.
+	token, err := tokenKeyring.Parse(tokenString)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenMalformed) {
@@ -53,6 +73,11 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	// Check if the token is valid.
 	//Check claims and store it to context
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		// Single-purpose tokens (e.g. email verification links) are signed with
+		// the same keys but must never be accepted as access tokens.
+		if _, ok := claims["purpose"]; ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+		}
 		userID, ok := claims["user_id"].(float64)
 		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user_id claim is missing or invalid"})
@@ -62,12 +87,53 @@ func AuthMiddleware(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user_type claim is missing or invalid"})
 
 		}
+		sessionID, ok := claims["sid"].(string)
+		if !ok || sessionID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "sid claim is missing or invalid"})
+		}
+		if sessionValidator != nil {
+			active, err := sessionValidator.IsSessionActive(uint(userID), sessionID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to validate session"})
+			}
+			if !active {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has been revoked"})
+			}
+		}
 
 		c.Locals("user", token)          // Store the entire token (for other claims)
 		c.Locals("userID", uint(userID)) // Store user ID as uint
 		c.Locals("userType", userType)   // Store user type
+		c.Locals("sessionID", sessionID) // Store session (refresh token family) ID
+		emailVerified, _ := claims["email_verified"].(bool)
+		c.Locals("emailVerified", emailVerified) // Missing in tokens issued before verification existed
 		return c.Next()
 	}
 
 	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
 }
+
+// UserIDFromToken returns the user ID from the token AuthMiddleware stored in
+// the request context.
+func UserIDFromToken(c *fiber.Ctx) (uint, error) {
+	user := c.Locals("user") // Set by AuthMiddleware
+	if user == nil {
+		return 0, errors.New("no user in context")
+	}
+
+	token, ok := user.(*jwt.Token)
+	if !ok {
+		return 0, errors.New("invalid token type")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, errors.New("invalid claims type")
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64) // JWT numbers are floats
+	if !ok {
+		return 0, errors.New("invalid user ID format in token")
+	}
+	return uint(userIDFloat), nil
+}
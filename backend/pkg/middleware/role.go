@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireRole only lets requests through when the authenticated user's type
+// (set by AuthMiddleware) is one of the given roles.  It must run after
+// AuthMiddleware.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userType, ok := c.Locals("userType").(string)
+		if !ok {
+			return unauthenticated(c)
+		}
+		for _, role := range roles {
+			if userType == role {
+				return c.Next()
+			}
+		}
+		return Forbidden(c)
+	}
+}
+
+// Forbidden writes the standard 403 response used by every authorization check.
+func Forbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+}
+
+// RequireSelf only lets requests through when the given path parameter is the
+// authenticated user's own ID.  Routes without the parameter pass through.
+// It must run after AuthMiddleware.
+func RequireSelf(param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		raw := c.Params(param)
+		if raw == "" {
+			return c.Next()
+		}
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return unauthenticated(c)
+		}
+		if raw != strconv.FormatUint(uint64(userID), 10) {
+			return Forbidden(c)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,79 @@
+package auditmodel
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be changed or deleted")
+
+// Target types.
+const (
+	TargetUser           = "user"
+	TargetJobPost        = "job_post"
+	TargetJobApplication = "job_application"
+	TargetMessage        = "message"
+	TargetOrganization   = "organization"
+)
+
+// Audited actions.
+const (
+	ActionRegister                 = "auth.register"
+	ActionLogin                    = "auth.login"
+	ActionLoginFailed              = "auth.login_failed"
+	ActionLoginLocked              = "auth.login_locked"
+	ActionLogoutAll                = "auth.logout_all"
+	ActionProfileUpdated           = "auth.profile_updated"
+	ActionPasswordResetRequested   = "auth.password_reset_requested"
+	ActionPasswordReset            = "auth.password_reset"
+	ActionEmailVerified            = "auth.email_verified"
+	ActionMFAFailed                = "auth.mfa_failed"
+	ActionMFAEnabled               = "auth.mfa_enabled"
+	ActionMFADisabled              = "auth.mfa_disabled"
+	ActionRecoveryCodesRegenerated = "auth.recovery_codes_regenerated"
+	ActionMFAPolicyChanged         = "auth.mfa_policy_changed"
+	ActionAPIKeyCreated            = "auth.api_key_created"
+	ActionAPIKeyRevoked            = "auth.api_key_revoked"
+	ActionJobPostCreated           = "job_post.created"
+	ActionJobPostUpdated           = "job_post.updated"
+	ActionJobPostDeleted           = "job_post.deleted"
+	ActionJobPostStateChanged      = "job_post.state_changed"
+	ActionJobPostsImported         = "job_post.imported"
+	ActionApplicationSubmitted     = "application.submitted"
+	ActionApplicationStatusChanged = "application.status_changed"
+	ActionApplicationWithdrawn     = "application.withdrawn"
+	ActionMessageSent              = "message.sent"
+)
+
+// Change is the before and after value of one field.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLog records who did what to which record.  Entries are append-only:
+// the hooks below refuse updates and deletes.
+type AuditLog struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	ActorID        *uint           `gorm:"index" json:"actor_id"`        // Nil for anonymous requests, e.g. a failed login
+	OrganizationID *uint           `gorm:"index" json:"organization_id"` // Organization whose owners can see the entry
+	Action         string          `gorm:"type:varchar(64);not null;index" json:"action"`
+	TargetType     string          `gorm:"type:varchar(32);not null;index:idx_audit_target" json:"target_type"`
+	TargetID       *uint           `gorm:"index:idx_audit_target" json:"target_id"`
+	Changes        json.RawMessage `gorm:"type:json" json:"changes,omitempty"` // Field name to Change
+	Details        json.RawMessage `gorm:"type:json" json:"details,omitempty"` // Extra context, e.g. the email of a failed login
+	IP             string          `gorm:"type:varchar(45)" json:"ip"`
+	UserAgent      string          `gorm:"type:varchar(255)" json:"user_agent"`
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
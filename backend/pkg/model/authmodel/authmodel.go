@@ -1,11 +1,18 @@
 package authmodel
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// User types, stored in User.UserType and carried in the user_type JWT claim.
+const (
+	UserTypeApplicant = "applicant"
+	UserTypeCompany   = "company"
+)
+
 type User struct {
 	ID           uint    `gorm:"primaryKey"`
 	Name         string  `gorm:"not null"`
@@ -15,6 +22,22 @@ type User struct {
 	UserType     string  `gorm:"type:enum('applicant', 'company');not null"`
 	CompanyName  *string `gorm:"type:varchar(255);default:NULL"`
 	ProfileImage *string `gorm:"type:varchar(255);default:NULL"`
+	// ProfileImageKey is where the uploaded avatar is stored; ProfileImage is
+	// the URL it is served from.
+	ProfileImageKey *string `gorm:"type:varchar(255);default:NULL" json:"-"`
+	FirebaseUID     *string `gorm:"type:varchar(128);uniqueIndex;default:NULL"`
+	// EmailVerified is set once the user follows the link sent to Email.
+	EmailVerified      bool `gorm:"not null;default:false"`
+	EmailVerifiedAt    *time.Time
+	VerificationSentAt *time.Time // Last verification email, for resend throttling
+	// TOTP two-factor authentication.  TOTPSecret is set on enrolment and
+	// TOTPEnabled once the user has confirmed a code from it.
+	TOTPSecret   *string `gorm:"type:varchar(64);default:NULL" json:"-"`
+	TOTPEnabled  bool    `gorm:"not null;default:false"`
+	TOTPLastStep int64   `gorm:"not null;default:0"` // Last accepted time step, so a code works only once
+	// AnonymizedAt is set when the account was deleted.  The row is kept, with
+	// its personal data wiped, so records of other users can still refer to it.
+	AnonymizedAt *time.Time
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
@@ -25,8 +48,18 @@ type LoginRequest struct {
 
 type ResetPasswordRequest struct {
 	Email       string `json:"email" binding:"required,email"`
+	OTP         string `json:"otp" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required"`
 }
+
+type RequestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type VerifyOTPRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	OTP   string `json:"otp" binding:"required"`
+}
 type UpdateProfileRequest struct {
 	Name  *string `json:"name"`  // Use pointers to allow partial updates
 	Phone *string `json:"phone"` // Use pointers to allow partial updates
@@ -40,14 +73,25 @@ type RegisterRequest struct {
 	CompanyName *string `json:"company_name"`
 }
 
+// CompanyProfile is the public identity of a company account.  Every company
+// user has exactly one, created on registration.
 type CompanyProfile struct {
-	ID          uint   `gorm:"primaryKey"`
-	UserID      uint   `gorm:"not null;uniqueIndex"`
-	CompanyName string `gorm:"not null"`
-	Description string
-	Logo        string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	CompanyName string    `gorm:"not null" json:"company_name"`
+	Description string    `json:"description"`
+	Logo        string    `json:"logo"`
+	LogoKey     *string   `gorm:"type:varchar(255);default:NULL" json:"-"` // Where an uploaded logo is stored
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CompanyProfileRequest creates or updates a company profile.  Nil fields are
+// left unchanged.
+type CompanyProfileRequest struct {
+	CompanyName *string `json:"company_name"`
+	Description *string `json:"description"`
+	Logo        *string `json:"logo"`
 }
 
 type Message struct {
@@ -72,6 +116,185 @@ type Notification struct { //Move form jobmodel to authmodel
 	User      User           `gorm:"foreignKey:UserID"`
 }
 
+// PasswordResetOTP is a single-use one-time password for resetting a password.
+// Only a hash of the code is stored.
+type PasswordResetOTP struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"not null;index"`
+	CodeHash   string    `gorm:"type:varchar(64);not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	Attempts   int       `gorm:"not null;default:0"`
+	VerifiedAt *time.Time
+	UsedAt     *time.Time
+	CreatedAt  time.Time
+	User       User `gorm:"foreignKey:UserID"`
+}
+
+// RefreshToken is a rotating refresh token.  Every token issued from the same
+// login shares a FamilyID; only a hash of the token itself is stored.
+type RefreshToken struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"not null;index"`
+	FamilyID     string    `gorm:"type:varchar(36);not null;index"`
+	TokenHash    string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+	CreatedAt    time.Time
+	User         User `gorm:"foreignKey:UserID"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// DeleteAccountRequest confirms an account deletion with the password, or a
+// fresh Firebase ID token for accounts that sign in with Firebase.
+type DeleteAccountRequest struct {
+	Password        string `json:"password"`
+	FirebaseIDToken string `json:"firebase_id_token"`
+}
+
+// FirebaseLoginRequest signs in with a Firebase ID token.  The optional fields
+// are only used when the account doesn't exist yet and has to be provisioned.
+type FirebaseLoginRequest struct {
+	IDToken     string  `json:"id_token" binding:"required"`
+	UserType    string  `json:"user_type"` // Defaults to applicant
+	Phone       string  `json:"phone"`
+	CompanyName *string `json:"company_name"`
+}
+
 type FirebaseResponse struct {
 	IDToken string `json:"idToken"`
 }
+
+// LoginThrottle counts recent failed logins for one key: "email:<address>"
+// for an account or "ip:<address>" for a client.  While LockedUntil is in the
+// future, logins for the key are refused.
+type LoginThrottle struct {
+	ID            uint   `gorm:"primaryKey"`
+	Key           string `gorm:"type:varchar(320);not null;uniqueIndex"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt *time.Time
+	LockedUntil   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+const (
+	LockoutEventLocked   = "locked"
+	LockoutEventUnlocked = "unlocked"
+)
+
+// LockoutEvent records every time a throttle key is locked or unlocked.
+type LockoutEvent struct {
+	ID          uint   `gorm:"primaryKey"`
+	Key         string `gorm:"type:varchar(320);not null;index"`
+	Event       string `gorm:"type:varchar(16);not null"`
+	UserID      *uint  `gorm:"index"` // Set for account keys of existing users
+	IP          string `gorm:"type:varchar(64)"`
+	Failures    int    `gorm:"not null;default:0"`
+	LockedUntil *time.Time
+	CreatedAt   time.Time
+}
+
+// MFARecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost.  Only a hash of the code is stored.
+type MFARecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	User      User `gorm:"foreignKey:UserID"`
+}
+
+// MFAVerifyRequest completes a login that requires a second factor.  Either
+// Code (from the authenticator) or RecoveryCode must be set.
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFACodeRequest proves possession of the second factor for account changes.
+type MFACodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type MFAPolicyRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+// MFAEnrollment is returned when enrolment starts.  The otpauth URI is what
+// authenticator apps scan as a QR code.
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// API key scopes.  A key can only be used on routes that require one of the
+// scopes it was granted.
+const (
+	ScopeJobsRead          = "jobs:read"
+	ScopeJobsWrite         = "jobs:write"
+	ScopeApplicationsRead  = "applications:read"
+	ScopeApplicationsWrite = "applications:write"
+	ScopeProfileRead       = "profile:read"
+)
+
+// APIScopes lists every scope a key can be granted.
+var APIScopes = []string{ScopeJobsRead, ScopeJobsWrite, ScopeApplicationsRead, ScopeApplicationsWrite, ScopeProfileRead}
+
+// APIKey lets scripts call the API as a user without signing in.  Only a hash
+// of the key is stored; Prefix is kept so users can tell their keys apart.
+type APIKey struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(16);not null"`
+	KeyHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     string `gorm:"type:varchar(255);not null"` // Space-separated
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"type:varchar(64)"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	User       User `gorm:"foreignKey:UserID"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range strings.Fields(k.Scopes) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays *int     `json:"expires_in_days"` // Nil for a key that doesn't expire
+}
+
+// APIKeyResponse describes a key without its secret.
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -2,6 +2,8 @@ package jobmodel
 
 import (
 	"backend/pkg/model/authmodel"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -27,12 +29,32 @@ type SavedJob struct {
 
 type JobPost struct {
 	ID             uint           `gorm:"primaryKey"`
-	UserID         uint           `gorm:"not null"`          // Foreign key referencing Users
-	User           authmodel.User `gorm:"foreignKey:UserID"` // Add this line for the relationship
+	UserID         uint           `gorm:"not null"`                               // Foreign key referencing Users
+	User           authmodel.User `gorm:"foreignKey:UserID"`                      // Add this line for the relationship
+	OrganizationID *uint          `gorm:"index;uniqueIndex:idx_job_external_ref"` // Team that owns the post; UserID is the member who posted it
 	Title          string         `gorm:"not null"`
 	Description    string         `gorm:"type:text"` // Use 'text' for longer descriptions
 	Location       string
-	SalaryRange    string
+	SalaryRange    string // Free text shown to applicants; derived from the fields below when empty
+
+	// Structured compensation.  Amounts are per SalaryPeriod in SalaryCurrency;
+	// either bound may be missing ("from 30,000", "up to 45,000").
+	SalaryMin        *int   `gorm:"index"`
+	SalaryMax        *int   `gorm:"index"`
+	SalaryCurrency   string `gorm:"type:varchar(3)"`  // ISO 4217 code, e.g. THB
+	SalaryPeriod     string `gorm:"type:varchar(10)"` // One of the SalaryPeriod values
+	SalaryNegotiable bool   `gorm:"default:false"`
+
+	// Lifecycle; see the JobPostState values.  Status mirrors State == open
+	// for older clients.
+	State     string     `gorm:"type:varchar(20);not null;default:open;index"`
+	PublishAt *time.Time `gorm:"index"` // When a scheduled post opens, or when an open post was published
+	ExpiresAt *time.Time `gorm:"index"` // When an open post closes by itself
+
+	// ID of the post in the system it was imported from; unique within the
+	// organization, so importing the same file again updates the posts.
+	ExternalRef *string `gorm:"type:varchar(100);uniqueIndex:idx_job_external_ref"`
+
 	Quantity       int
 	JobPosition    string
 	Status         bool `gorm:"default:true"` // Use boolean; true for open, false for closed
@@ -41,6 +63,8 @@ type JobPost struct {
 	DeletedAt      gorm.DeletedAt   `gorm:"index"`
 	ApplicantCount int              `gorm:"default:0"`        // Add this line
 	Applications   []JobApplication `gorm:"foreignKey:JobID"` // Add this line to define the relationship
+	// CompanyProfile of the posting company, filled in by the job service.
+	CompanyProfile *authmodel.CompanyProfile `gorm:"-" json:"company_profile,omitempty"`
 }
 
 type JobApplication struct {
@@ -57,6 +81,35 @@ type JobApplication struct {
 	GeminiSummary string         `gorm:"type:text"`
 	Questions     *string        `gorm:"type:text"`
 	Score         *float64       `gorm:"type:double"`
+
+	// Revision of the job post the resume was scored against; nil for
+	// applications made before job posts were versioned.
+	RevisionID *uint `gorm:"index"`
+}
+
+// JobPostRevision is the content of a job post as it was after one edit.
+// Revisions are numbered from 1 per post and never change.
+type JobPostRevision struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	JobID       uint      `gorm:"not null;uniqueIndex:idx_job_revision" json:"job_id"`
+	Number      int       `gorm:"not null;uniqueIndex:idx_job_revision" json:"number"`
+	EditorID    uint      `gorm:"not null" json:"editor_id"` // Company user who made the edit
+	Title       string    `gorm:"not null" json:"title"`
+	Description string    `gorm:"type:text" json:"description"`
+	Location    string    `json:"location"`
+	JobPosition string    `json:"job_position"`
+	Quantity    int       `json:"quantity"`
+	SalaryRange string    `json:"salary_range"`
+	CreatedAt   time.Time `json:"created_at"`
+	Salary      `gorm:"embedded;embeddedPrefix:salary_"`
+}
+
+// SameContent reports whether two revisions hold the same job post content.
+func (r *JobPostRevision) SameContent(other *JobPostRevision) bool {
+	return r.Title == other.Title && r.Description == other.Description &&
+		r.Location == other.Location && r.JobPosition == other.JobPosition &&
+		r.Quantity == other.Quantity && r.SalaryRange == other.SalaryRange &&
+		r.Salary.Equal(other.Salary)
 }
 
 type Message struct {
@@ -70,3 +123,239 @@ type Message struct {
 	Sender      authmodel.User `gorm:"foreignKey:SenderID"`   // For preloading
 	Receiver    authmodel.User `gorm:"foreignKey:ReceiverID"` // For preloading
 }
+
+// Lifecycle states of a job post.  Drafts and scheduled posts are only seen
+// by their organization; open posts take applications; paused and closed
+// posts are still listed but don't; archived posts are hidden again.
+const (
+	JobPostStateDraft     = "draft"
+	JobPostStateScheduled = "scheduled"
+	JobPostStateOpen      = "open"
+	JobPostStatePaused    = "paused"
+	JobPostStateClosed    = "closed"
+	JobPostStateArchived  = "archived"
+)
+
+// PublicJobPostStates are the states in which anyone may see a post.
+var PublicJobPostStates = []string{JobPostStateOpen, JobPostStatePaused, JobPostStateClosed}
+
+// IsOpen reports whether the post takes applications at now.  A post past
+// its expiry is closed even before the scheduler gets to it.
+func (p *JobPost) IsOpen(now time.Time) bool {
+	return p.State == JobPostStateOpen && (p.ExpiresAt == nil || p.ExpiresAt.After(now))
+}
+
+// IsPublic reports whether anyone may see the post.
+func (p *JobPost) IsPublic() bool {
+	for _, state := range PublicJobPostStates {
+		if p.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+// JobPostStateRequest moves a job post to another state.  Timestamps left
+// nil keep their current value.
+type JobPostStateRequest struct {
+	State     string     `json:"state"` // Empty keeps the state and only changes the timestamps
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Periods a salary is paid for.
+const (
+	SalaryPeriodHour  = "hour"
+	SalaryPeriodDay   = "day"
+	SalaryPeriodMonth = "month"
+	SalaryPeriodYear  = "year"
+)
+
+// DefaultSalaryCurrency is assumed when a salary names no currency.
+const DefaultSalaryCurrency = "THB"
+
+// Salary is the structured compensation of a job post, as it appears in
+// responses.
+type Salary struct {
+	Min        *int   `json:"salary_min"`
+	Max        *int   `json:"salary_max"`
+	Currency   string `json:"salary_currency"`
+	Period     string `json:"salary_period"`
+	Negotiable bool   `json:"salary_negotiable"`
+}
+
+// Salary returns the structured compensation of the post.
+func (p *JobPost) Salary() Salary {
+	return Salary{
+		Min:        p.SalaryMin,
+		Max:        p.SalaryMax,
+		Currency:   p.SalaryCurrency,
+		Period:     p.SalaryPeriod,
+		Negotiable: p.SalaryNegotiable,
+	}
+}
+
+// SetSalary replaces the structured compensation of the post.
+func (p *JobPost) SetSalary(s Salary) {
+	p.SalaryMin = s.Min
+	p.SalaryMax = s.Max
+	p.SalaryCurrency = s.Currency
+	p.SalaryPeriod = s.Period
+	p.SalaryNegotiable = s.Negotiable
+}
+
+// IsZero reports whether no salary field is set.
+func (s Salary) IsZero() bool {
+	return s.Min == nil && s.Max == nil && s.Currency == "" && s.Period == "" && !s.Negotiable
+}
+
+// Equal reports whether two salaries are the same.
+func (s Salary) Equal(other Salary) bool {
+	return equalAmount(s.Min, other.Min) && equalAmount(s.Max, other.Max) &&
+		s.Currency == other.Currency && s.Period == other.Period && s.Negotiable == other.Negotiable
+}
+
+func equalAmount(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// String formats the salary for display, e.g. "30,000 - 45,000 THB/month".
+func (s Salary) String() string {
+	var amount string
+	switch {
+	case s.Min != nil && s.Max != nil && *s.Min == *s.Max:
+		amount = formatAmount(*s.Min)
+	case s.Min != nil && s.Max != nil:
+		amount = formatAmount(*s.Min) + " - " + formatAmount(*s.Max)
+	case s.Min != nil:
+		amount = "From " + formatAmount(*s.Min)
+	case s.Max != nil:
+		amount = "Up to " + formatAmount(*s.Max)
+	default:
+		if s.Negotiable {
+			return "Negotiable"
+		}
+		return ""
+	}
+	amount += " " + s.Currency + "/" + s.Period
+	if s.Negotiable {
+		amount += " (negotiable)"
+	}
+	return amount
+}
+
+// formatAmount writes n with thousands separators.
+func formatAmount(n int) string {
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+// Sort orders for job post listings.
+const (
+	JobPostSortNewest    = "newest"
+	JobPostSortOldest    = "oldest"
+	JobPostSortTitle     = "title"
+	JobPostSortTitleDesc = "title_desc"
+)
+
+// JobPostQuery filters and pages a job post listing.  Zero values match
+// anything.
+type JobPostQuery struct {
+	Keyword     string // Every word must appear in the title or description
+	Location    string
+	JobPosition string
+	Status      *bool
+	CompanyID   *uint // Company profile ID
+	// Salary bounds, per SalaryPeriod (month by default) in SalaryCurrency
+	// (THB by default).  A post matches when its range overlaps them.
+	SalaryMin      *int
+	SalaryMax      *int
+	SalaryCurrency string
+	SalaryPeriod   string
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	Sort           string // One of the JobPostSort values; newest by default
+	Cursor         string // NextCursor of the previous page
+	Limit          int
+}
+
+// JobPostPage is one page of a job post listing.
+type JobPostPage struct {
+	JobPosts   []JobPost
+	Total      int64  // Posts matching the filters, on every page
+	NextCursor string // Empty on the last page
+}
+
+// JobPostSearchResult is a job post found by a keyword search.
+type JobPostSearchResult struct {
+	JobPost JobPost
+	Score   float64
+	// Highlights are HTML snippets of the matching fields, keyed by field
+	// name, with the matched words wrapped in <mark>.
+	Highlights map[string]string
+}
+
+// JobPostSearchPage is one page of search results, best match first.
+type JobPostSearchPage struct {
+	Results    []JobPostSearchResult
+	Total      int64 // Posts matching the query, on every page
+	NextOffset *int  // Nil on the last page
+}
+
+// JobPostRecord is a job post as it appears in import and export files.  CSV
+// columns and JSON keys use the JSON names below.
+type JobPostRecord struct {
+	ID          uint   `json:"id,omitempty"` // Set in exports; in an import, the post the row updates
+	ExternalRef string `json:"external_ref"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	JobPosition string `json:"job_position"`
+	Quantity    int    `json:"quantity"`
+	SalaryRange string `json:"salary_range"`
+	Salary
+	// Empty lifecycle fields keep the state of an existing post; others move
+	// it as PUT /api/jobs/:id/state would.
+	State     string     `json:"state"`
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Outcomes of an imported row.
+const (
+	JobPostImportCreated   = "created"
+	JobPostImportUpdated   = "updated"
+	JobPostImportUnchanged = "unchanged"
+	JobPostImportInvalid   = "invalid"
+)
+
+// JobPostImportResult is the outcome of one row of an import.
+type JobPostImportResult struct {
+	Row         int      `json:"row"` // Position in the file, from 1, not counting the CSV header
+	ExternalRef string   `json:"external_ref,omitempty"`
+	Action      string   `json:"action"`           // One of the JobPostImport values
+	JobID       *uint    `json:"job_id,omitempty"` // Nil for invalid rows and for new posts in a dry run
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// JobPostImportReport describes an import.  Nothing is saved when any row is
+// invalid, nor for a dry run.
+type JobPostImportReport struct {
+	DryRun    bool                  `json:"dry_run"`
+	Imported  bool                  `json:"imported"`
+	Created   int                   `json:"created"`
+	Updated   int                   `json:"updated"`
+	Unchanged int                   `json:"unchanged"`
+	Invalid   int                   `json:"invalid"`
+	Rows      []JobPostImportResult `json:"rows"`
+}
@@ -0,0 +1,120 @@
+package orgmodel
+
+import (
+	"backend/pkg/model/authmodel"
+	"strings"
+	"time"
+)
+
+// Member roles, from most to least privileged.
+const (
+	RoleOwner     = "owner"     // Manages members and settings, and everything a recruiter can do
+	RoleRecruiter = "recruiter" // Manages job posts and applications, and messages applicants
+	RoleViewer    = "viewer"    // Read-only access to job posts, applications and messages
+)
+
+// ValidRole reports whether role is one of the member roles.
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleRecruiter || role == RoleViewer
+}
+
+// CanManageJobs reports whether the role may create, edit and delete job posts
+// and decide on applications.
+func CanManageJobs(role string) bool {
+	return role == RoleOwner || role == RoleRecruiter
+}
+
+// Organization is a hiring team.  Job posts belong to an organization, and
+// every member can access them according to their role.
+type Organization struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"not null" json:"name"`
+	MFARequired bool      `gorm:"not null;default:false" json:"mfa_required"` // Members must use two-factor authentication
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Hiring settings.  A job post closes once as many applications are
+	// accepted as its Quantity, and the applicants still pending can be told
+	// with a notification rendered from FilledJobTemplate.
+	AutoCloseFilledJobs   bool   `gorm:"not null;default:true" json:"auto_close_filled_jobs"`
+	NotifyPendingOnFilled bool   `gorm:"not null;default:false" json:"notify_pending_on_filled"`
+	FilledJobTemplate     string `gorm:"type:text" json:"filled_job_template"` // Empty uses DefaultFilledJobTemplate
+}
+
+// DefaultFilledJobTemplate is the notification pending applicants get when a
+// job post is filled, unless the organization has its own.
+const DefaultFilledJobTemplate = "Thank you for applying to {job_title} at {company_name}. All positions have now been filled, so the job post is closed."
+
+// FilledJobPlaceholders can be used in FilledJobTemplate.
+var FilledJobPlaceholders = []string{"{applicant_name}", "{job_title}", "{company_name}"}
+
+// RenderFilledJobNotice fills in the placeholders of template, or of the
+// default template when it is empty.
+func RenderFilledJobNotice(template, applicantName, jobTitle, companyName string) string {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultFilledJobTemplate
+	}
+	return strings.NewReplacer(
+		"{applicant_name}", applicantName,
+		"{job_title}", jobTitle,
+		"{company_name}", companyName,
+	).Replace(template)
+}
+
+// OrganizationMember links a company user to the one organization they work
+// for.
+type OrganizationMember struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;index" json:"organization_id"`
+	UserID         uint           `gorm:"not null;uniqueIndex" json:"user_id"`
+	Role           string         `gorm:"type:varchar(16);not null" json:"role"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Organization   Organization   `gorm:"foreignKey:OrganizationID" json:"-"`
+	User           authmodel.User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// OrganizationInvitation invites an email address to join an organization.
+// Only a hash of the invitation token is stored.
+type OrganizationInvitation struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizationID uint         `gorm:"not null;index" json:"organization_id"`
+	Email          string       `gorm:"not null;index" json:"email"`
+	Role           string       `gorm:"type:varchar(16);not null" json:"role"`
+	TokenHash      string       `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	InvitedByID    uint         `gorm:"not null" json:"invited_by_id"`
+	ExpiresAt      time.Time    `gorm:"not null" json:"expires_at"`
+	AcceptedAt     *time.Time   `json:"accepted_at,omitempty"`
+	RevokedAt      *time.Time   `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID" json:"-"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name                  *string `json:"name"`
+	AutoCloseFilledJobs   *bool   `json:"auto_close_filled_jobs"`
+	NotifyPendingOnFilled *bool   `json:"notify_pending_on_filled"`
+	FilledJobTemplate     *string `json:"filled_job_template"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// MemberResponse is a member as shown to the rest of the team.
+type MemberResponse struct {
+	UserID   uint      `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
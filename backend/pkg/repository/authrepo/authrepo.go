@@ -9,6 +9,13 @@ import (
 	"google.golang.org/api/option"
 )
 
+// IFirebaseRepository verifies Firebase ID tokens and looks up Firebase users.
+// Tests can swap in a fake that doesn't talk to Google.
+type IFirebaseRepository interface {
+	VerifyIDToken(idToken string) (*auth.Token, error)
+	GetUser(uid string) (*auth.UserRecord, error)
+}
+
 type FirebaseRepository struct {
 	authClient *auth.Client
 }
@@ -0,0 +1,152 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// fieldWeights boosts matches in short, descriptive fields over matches in the
+// description.
+var fieldWeights = [...]float64{
+	3, // Title
+	2, // Position
+	2, // Company
+	1, // Description
+}
+
+// MemoryIndex is an in-process index ranked with BM25.  It is rebuilt from the
+// database on start, so it suits development, tests and single-instance
+// deployments.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[uint]map[string]float64 // Weighted term frequencies per document
+	lengths  map[uint]float64            // Weighted length per document
+	postings map[string]map[uint]bool    // Documents per term
+	total    float64                     // Sum of lengths, for the average
+}
+
+// NewMemoryIndex creates an empty MemoryIndex.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[uint]map[string]float64),
+		lengths:  make(map[uint]float64),
+		postings: make(map[string]map[uint]bool),
+	}
+}
+
+func (m *MemoryIndex) Put(doc Document) error {
+	frequencies := make(map[string]float64)
+	var length float64
+	for i, text := range [...]string{doc.Title, doc.Position, doc.Company, doc.Description} {
+		for _, term := range Terms(text) {
+			frequencies[term] += fieldWeights[i]
+			length += fieldWeights[i]
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(doc.ID)
+	m.docs[doc.ID] = frequencies
+	m.lengths[doc.ID] = length
+	m.total += length
+	for term := range frequencies {
+		if m.postings[term] == nil {
+			m.postings[term] = make(map[uint]bool)
+		}
+		m.postings[term][doc.ID] = true
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+// remove drops a document; the caller holds the write lock.
+func (m *MemoryIndex) remove(id uint) {
+	frequencies, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for term := range frequencies {
+		delete(m.postings[term], id)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	m.total -= m.lengths[id]
+	delete(m.docs, id)
+	delete(m.lengths, id)
+}
+
+// Search matches documents containing every term of the query.
+func (m *MemoryIndex) Search(query string, offset, limit int) ([]Hit, int64, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := float64(len(m.docs))
+	if n == 0 {
+		return nil, 0, nil
+	}
+	average := m.total / n
+	scores := make(map[uint]float64)
+	matched := make(map[uint]int) // Distinct query terms found per document
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := m.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id := range postings {
+			tf := m.docs[id][term]
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*m.lengths[id]/average))
+			matched[id]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		if matched[id] == len(seen) {
+			hits = append(hits, Hit{ID: id, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID // Newer posts first on ties
+	})
+	total := int64(len(hits))
+	if offset >= len(hits) {
+		return []Hit{}, total, nil
+	}
+	hits = hits[offset:]
+	if limit < len(hits) {
+		hits = hits[:limit]
+	}
+	return hits, total, nil
+}
+
+func (m *MemoryIndex) Len() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return int64(len(m.docs)), nil
+}
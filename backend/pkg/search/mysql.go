@@ -0,0 +1,125 @@
+package search
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MySQLDocument is the row MySQLIndex keeps per job post.  It has to be
+// migrated along with the other models.  It holds the terms of the post as
+// Terms produces them, encoded by encodeTerms, so MySQL matches exactly the
+// words MemoryIndex does: whole words, and bigrams of Thai.
+type MySQLDocument struct {
+	JobID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	TitleTerms string `gorm:"type:text;index:idx_search_title,class:FULLTEXT"`
+	Terms      string `gorm:"type:mediumtext;index:idx_search_terms,class:FULLTEXT"` // Title, position, company and description
+}
+
+func (MySQLDocument) TableName() string {
+	return "job_search_terms"
+}
+
+const (
+	matchAllSQL   = "MATCH(terms) AGAINST (? IN BOOLEAN MODE)"
+	matchTitleSQL = "MATCH(title_terms) AGAINST (? IN BOOLEAN MODE)"
+)
+
+// maxTermBytes keeps encoded terms within innodb_ft_max_token_size, 84
+// characters by default.  Longer terms are cut in the index and in queries
+// alike.
+const maxTermBytes = 41
+
+// MySQLIndex searches with InnoDB FULLTEXT indexes.  A document matches when
+// it has every term of the query, and a match in the title counts three times
+// as much as a match elsewhere.
+type MySQLIndex struct {
+	DB *gorm.DB
+}
+
+// NewMySQLIndex creates a MySQLIndex on db.
+func NewMySQLIndex(db *gorm.DB) *MySQLIndex {
+	return &MySQLIndex{DB: db}
+}
+
+func (m *MySQLIndex) Put(doc Document) error {
+	var terms []string
+	for _, text := range [...]string{doc.Title, doc.Position, doc.Company, doc.Description} {
+		terms = append(terms, Terms(text)...)
+	}
+	row := MySQLDocument{
+		JobID:      doc.ID,
+		TitleTerms: encodeTerms(Terms(doc.Title), ""),
+		Terms:      encodeTerms(terms, ""),
+	}
+	if err := m.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error; err != nil {
+		return fmt.Errorf("failed to index job post %d: %w", doc.ID, err)
+	}
+	return nil
+}
+
+func (m *MySQLIndex) Delete(id uint) error {
+	if err := m.DB.Delete(&MySQLDocument{}, id).Error; err != nil {
+		return fmt.Errorf("failed to remove job post %d from the index: %w", id, err)
+	}
+	return nil
+}
+
+func (m *MySQLIndex) Search(query string, offset, limit int) ([]Hit, int64, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+	// Every term is required; the title only adds to the score of a match.
+	required, ranked := encodeTerms(terms, "+"), encodeTerms(terms, "")
+
+	var total int64
+	if err := m.DB.Model(&MySQLDocument{}).Where(matchAllSQL, required).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+	var rows []struct {
+		JobID uint
+		Score float64
+	}
+	err := m.DB.Model(&MySQLDocument{}).
+		Select("job_id, 2 * "+matchTitleSQL+" + "+matchAllSQL+" AS score", ranked, ranked).
+		Where(matchAllSQL, required).
+		Order("score DESC, job_id DESC").
+		Offset(offset).Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search job posts: %w", err)
+	}
+	hits := make([]Hit, len(rows))
+	for i, row := range rows {
+		hits[i] = Hit{ID: row.JobID, Score: row.Score}
+	}
+	return hits, total, nil
+}
+
+func (m *MySQLIndex) Len() (int64, error) {
+	var count int64
+	if err := m.DB.Model(&MySQLDocument{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count indexed job posts: %w", err)
+	}
+	return count, nil
+}
+
+// encodeTerms joins terms into words MySQL's built-in FULLTEXT parser keeps
+// as they are, each preceded by op.  A term becomes "t" and its bytes in hex:
+// the parser would otherwise drop words shorter than innodb_ft_min_token_size
+// and stopwords, and split Thai text it has no dictionary for.  Hex words
+// after a "t" are never stopwords, and the operators of a query can't get in.
+func encodeTerms(terms []string, op string) string {
+	encoded := make([]string, len(terms))
+	for i, term := range terms {
+		if len(term) > maxTermBytes {
+			term = term[:maxTermBytes]
+		}
+		encoded[i] = op + "t" + hex.EncodeToString([]byte(term))
+	}
+	return strings.Join(encoded, " ")
+}
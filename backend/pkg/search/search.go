@@ -0,0 +1,180 @@
+// Package search indexes job posts for keyword search with relevance
+// ranking.  Two backends implement IIndex: MySQLIndex, which uses FULLTEXT
+// indexes, and MemoryIndex, an in-process BM25 index for development and
+// tests.  Both split text with Terms and return the documents that have every
+// term of a query, so they find the same posts and only rank them
+// differently.
+package search
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrEmptyQuery = errors.New("search query has no searchable words")
+
+// Document is what gets indexed for one job post.
+type Document struct {
+	ID          uint
+	Title       string
+	Description string
+	Position    string
+	Company     string
+}
+
+// Hit is one search result.  Scores are only comparable within one search.
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+// IIndex is a full-text index of documents.
+type IIndex interface {
+	// Put adds a document, replacing any earlier version with the same ID.
+	Put(doc Document) error
+	Delete(id uint) error
+	// Search returns the hits for query, best first, and the total number of
+	// matching documents.
+	Search(query string, offset, limit int) ([]Hit, int64, error)
+	// Len returns the number of indexed documents.
+	Len() (int64, error)
+}
+
+// token is a normalized term and where it was found in the original text.
+type token struct {
+	term       string
+	start, end int // Byte offsets
+}
+
+// Terms returns the normalized search terms of text.
+func Terms(text string) []string {
+	tokens := tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.term
+	}
+	return terms
+}
+
+// tokenize splits text into lower-cased words.  Thai is written without
+// spaces between words, so runs of Thai script become overlapping character
+// bigrams instead.
+func tokenize(text string) []token {
+	var tokens []token
+	type char struct {
+		r    rune
+		pos  int
+		thai bool
+	}
+	var run []char
+	flush := func() {
+		for start := 0; start < len(run); {
+			end := start
+			for end < len(run) && run[end].thai == run[start].thai {
+				end++
+			}
+			segment := run[start:end]
+			last := func(i int) int { return segment[i].pos + utf8.RuneLen(segment[i].r) }
+			if segment[0].thai {
+				if len(segment) == 1 {
+					tokens = append(tokens, token{string(segment[0].r), segment[0].pos, last(0)})
+				}
+				for i := 0; i+1 < len(segment); i++ {
+					tokens = append(tokens, token{string([]rune{segment[i].r, segment[i+1].r}), segment[i].pos, last(i + 1)})
+				}
+			} else {
+				var b strings.Builder
+				for _, c := range segment {
+					b.WriteRune(unicode.ToLower(c.r))
+				}
+				tokens = append(tokens, token{b.String(), segment[0].pos, last(len(segment) - 1)})
+			}
+			start = end
+		}
+		run = run[:0]
+	}
+	for pos, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+			run = append(run, char{r, pos, unicode.Is(unicode.Thai, r)})
+			continue
+		}
+		flush()
+	}
+	flush()
+	return tokens
+}
+
+// Highlight returns an HTML snippet of about width characters from text,
+// centred on the first word matching query.  Matches are wrapped in <mark>
+// and everything else is escaped.  Without a match the start of the text is
+// returned.
+func Highlight(text, query string, width int) string {
+	wanted := make(map[string]bool)
+	for _, term := range Terms(query) {
+		wanted[term] = true
+	}
+	type span struct{ start, end int }
+	var spans []span
+	for _, t := range tokenize(text) {
+		if !wanted[t.term] {
+			continue
+		}
+		if n := len(spans); n > 0 && t.start <= spans[n-1].end {
+			if t.end > spans[n-1].end {
+				spans[n-1].end = t.end // Overlapping Thai bigrams
+			}
+			continue
+		}
+		spans = append(spans, span{t.start, t.end})
+	}
+
+	// Pick the window, in bytes, on rune boundaries.
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > width {
+		if len(spans) > 0 {
+			from = backRunes(text, spans[0].start, width/3)
+		}
+		to = forwardRunes(text, from, width)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		start, end := max(s.start, from), min(s.end, to)
+		b.WriteString(html.EscapeString(text[pos:start]))
+		b.WriteString("<mark>" + html.EscapeString(text[start:end]) + "</mark>")
+		pos = end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// backRunes moves n runes back from byte offset i.
+func backRunes(s string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes moves n runes forward from byte offset i.
+func forwardRunes(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
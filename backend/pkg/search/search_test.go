@@ -0,0 +1,140 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Senior Go Developer", []string{"senior", "go", "developer"}},
+		{"C++/Go, remote-first", []string{"c", "go", "remote", "first"}},
+		{"นักพัฒนา", []string{"นั", "ัก", "กพ", "พั", "ัฒ", "ฒน", "นา"}},
+		{"Go ก", []string{"go", "ก"}},
+		{"  ,.  ", []string{}},
+	}
+	for _, tt := range tests {
+		if got := Terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeTerms(t *testing.T) {
+	if got, want := encodeTerms([]string{"go", "c"}, "+"), "+t676f +t63"; got != want {
+		t.Errorf("encodeTerms() = %q, want %q", got, want)
+	}
+	// Long terms are cut, so the query side encodes them the same way.
+	long := strings.Repeat("a", maxTermBytes+10)
+	if got := encodeTerms([]string{long}, ""); len(got) != 1+2*maxTermBytes {
+		t.Errorf("encodeTerms() of a long term has %d bytes, want %d", len(got), 1+2*maxTermBytes)
+	}
+}
+
+func newTestIndex(t *testing.T, docs ...Document) *MemoryIndex {
+	t.Helper()
+	m := NewMemoryIndex()
+	for _, doc := range docs {
+		if err := m.Put(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func hitIDs(hits []Hit) []uint {
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestMemoryIndexRanksTitleMatchesFirst(t *testing.T) {
+	m := newTestIndex(t,
+		Document{ID: 1, Title: "Accountant", Description: "Our team works in Go and Python."},
+		Document{ID: 2, Title: "Go Developer", Description: "Build services."},
+		Document{ID: 3, Title: "Designer", Description: "Figma and Sketch."},
+	)
+	hits, total, err := m.Search("go", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hitIDs(hits), []uint{2, 1}; !reflect.DeepEqual(got, want) || total != 2 {
+		t.Errorf("Search(go) = %v (total %d), want %v (total 2)", got, total, want)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Errorf("scores %v aren't in descending order", hits)
+	}
+}
+
+func TestMemoryIndexNeedsEveryTerm(t *testing.T) {
+	m := newTestIndex(t,
+		Document{ID: 1, Title: "Go Developer"},
+		Document{ID: 2, Title: "Python Developer"},
+		Document{ID: 3, Title: "Senior Go Developer", Company: "Acme"},
+	)
+	hits, total, err := m.Search("go go developer acme", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hitIDs(hits), []uint{3}; !reflect.DeepEqual(got, want) || total != 1 {
+		t.Errorf("Search() = %v (total %d), want %v (total 1)", got, total, want)
+	}
+	if _, _, err := m.Search("?!", 0, 10); err != ErrEmptyQuery {
+		t.Errorf("Search(?!) error = %v, want %v", err, ErrEmptyQuery)
+	}
+}
+
+func TestMemoryIndexPages(t *testing.T) {
+	m := newTestIndex(t)
+	for id := uint(1); id <= 5; id++ {
+		if err := m.Put(Document{ID: id, Title: "Go Developer"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Equal scores fall back to newest first.
+	var pages [][]uint
+	for offset := 0; offset < 6; offset += 2 {
+		hits, total, err := m.Search("developer", offset, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 5 {
+			t.Errorf("Search(offset %d) total = %d, want 5", offset, total)
+		}
+		pages = append(pages, hitIDs(hits))
+	}
+	if want := [][]uint{{5, 4}, {3, 2}, {1}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+	if hits, _, _ := m.Search("developer", 10, 2); len(hits) != 0 {
+		t.Errorf("Search() past the end = %v, want no hits", hitIDs(hits))
+	}
+}
+
+func TestMemoryIndexReplacesAndDeletes(t *testing.T) {
+	m := newTestIndex(t,
+		Document{ID: 1, Title: "Go Developer"},
+		Document{ID: 2, Title: "Go Tester"},
+	)
+	if err := m.Put(Document{ID: 1, Title: "Rust Developer"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if hits, total, _ := m.Search("go", 0, 10); len(hits) != 0 || total != 0 {
+		t.Errorf("Search(go) = %v (total %d), want nothing", hitIDs(hits), total)
+	}
+	if hits, _, _ := m.Search("rust", 0, 10); !reflect.DeepEqual(hitIDs(hits), []uint{1}) {
+		t.Errorf("Search(rust) = %v, want [1]", hitIDs(hits))
+	}
+	if n, _ := m.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+}
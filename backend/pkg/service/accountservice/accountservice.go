@@ -0,0 +1,477 @@
+package accountservice
+
+import (
+	"archive/zip"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/service/authservice"
+	"backend/pkg/service/imageservice"
+	"backend/pkg/service/jobservice"
+	"backend/pkg/service/orgservice"
+	"backend/pkg/storage"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrSoleOwner    = errors.New("make another member an owner of your organization before deleting your account")
+)
+
+// IAccountService interface
+type IAccountService interface {
+	ExportData(userID uint) ([]byte, error)
+	DeleteAccount(userID uint, req *authmodel.DeleteAccountRequest, ip string) error
+}
+
+type AccountService struct {
+	DB           *gorm.DB
+	Storage      storage.IStorage
+	AuthService  authservice.IAuthService
+	ImageService imageservice.IImageService
+	JobService   jobservice.IJobService
+}
+
+// NewAccountService creates a new AccountService.
+func NewAccountService(db *gorm.DB, store storage.IStorage, authService authservice.IAuthService, imageService imageservice.IImageService, jobService jobservice.IJobService) *AccountService {
+	return &AccountService{DB: db, Storage: store, AuthService: authService, ImageService: imageService, JobService: jobService}
+}
+
+// The export is plain JSON with stable, documented field names rather than the
+// internal models, so it stays readable and leaks no secrets (password hash,
+// TOTP secret).
+type exportedProfile struct {
+	ID            uint                      `json:"id"`
+	Name          string                    `json:"name"`
+	Email         string                    `json:"email"`
+	Phone         string                    `json:"phone"`
+	UserType      string                    `json:"user_type"`
+	CompanyName   *string                   `json:"company_name,omitempty"`
+	EmailVerified bool                      `json:"email_verified"`
+	MFAEnabled    bool                      `json:"mfa_enabled"`
+	CreatedAt     time.Time                 `json:"created_at"`
+	Company       *authmodel.CompanyProfile `json:"company_profile,omitempty"`
+	Organization  *exportedMembership       `json:"organization,omitempty"`
+}
+
+type exportedMembership struct {
+	ID       uint      `json:"id"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type exportedApplication struct {
+	ID            uint      `json:"id"`
+	JobID         uint      `json:"job_id"`
+	JobTitle      string    `json:"job_title"`
+	Status        string    `json:"status"`
+	AppliedAt     time.Time `json:"applied_at"`
+	Resume        string    `json:"resume,omitempty"` // Path of the PDF inside the archive
+	GeminiSummary string    `json:"gemini_summary"`
+	Questions     *string   `json:"questions,omitempty"`
+	Score         *float64  `json:"score,omitempty"`
+}
+
+type exportedMessage struct {
+	ID         uint      `json:"id"`
+	SenderID   uint      `json:"sender_id"`
+	ReceiverID uint      `json:"receiver_id"`
+	Sent       bool      `json:"sent"` // True when the user wrote it
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type exportedSavedJob struct {
+	JobID    uint      `json:"job_id"`
+	JobTitle string    `json:"job_title"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+type exportedNotification struct {
+	Message   string    `json:"message"`
+	IsRead    bool      `json:"is_read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportData returns a ZIP archive with everything stored about the user:
+// profile, applications, messages, saved jobs and notifications as JSON, plus
+// the original resume PDFs and the profile image.
+func (s *AccountService) ExportData(userID uint) ([]byte, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := exportedProfile{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		UserType:      user.UserType,
+		CompanyName:   user.CompanyName,
+		EmailVerified: user.EmailVerified,
+		MFAEnabled:    user.TOTPEnabled,
+		CreatedAt:     user.CreatedAt,
+	}
+	var company authmodel.CompanyProfile
+	if err := s.DB.Where("user_id = ?", userID).First(&company).Error; err == nil {
+		profile.Company = &company
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to retrieve company profile: %w", err)
+	}
+	member, err := orgservice.FindMembership(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		profile.Organization = &exportedMembership{
+			ID:       member.OrganizationID,
+			Name:     member.Organization.Name,
+			Role:     member.Role,
+			JoinedAt: member.CreatedAt,
+		}
+	}
+
+	var applications []jobmodel.JobApplication
+	if err := s.DB.Unscoped().Preload("JobPost", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ?", userID).Order("created_at ASC").Find(&applications).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve applications: %w", err)
+	}
+	var messages []authmodel.Message
+	if err := s.DB.Where("sender_id = ? OR receiver_id = ?", userID, userID).Order("created_at ASC").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve messages: %w", err)
+	}
+	var savedJobs []jobmodel.SavedJob
+	if err := s.DB.Preload("JobPost", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ?", userID).Order("created_at ASC").Find(&savedJobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve saved jobs: %w", err)
+	}
+	var notifications []authmodel.Notification
+	if err := s.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve notifications: %w", err)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	exportedApplications := make([]exportedApplication, 0, len(applications))
+	for _, application := range applications {
+		exported := exportedApplication{
+			ID:            application.ID,
+			JobID:         application.JobID,
+			JobTitle:      application.JobPost.Title,
+			Status:        string(application.Status),
+			AppliedAt:     application.CreatedAt,
+			GeminiSummary: application.GeminiSummary,
+			Questions:     application.Questions,
+			Score:         application.Score,
+		}
+		if application.ResumeFile != "" {
+			name := fmt.Sprintf("resumes/application-%d.pdf", application.ID)
+			if err := s.copyFile(archive, name, resumeKey(application.ResumeFile)); err != nil {
+				log.Printf("export of user %d: skipping resume of application %d: %v", userID, application.ID, err)
+			} else {
+				exported.Resume = name
+			}
+		}
+		exportedApplications = append(exportedApplications, exported)
+	}
+
+	exportedMessages := make([]exportedMessage, 0, len(messages))
+	for _, message := range messages {
+		exportedMessages = append(exportedMessages, exportedMessage{
+			ID:         message.ID,
+			SenderID:   message.SenderID,
+			ReceiverID: message.ReceiverID,
+			Sent:       message.SenderID == userID,
+			Text:       message.MessageText,
+			CreatedAt:  message.CreatedAt,
+		})
+	}
+	exportedSavedJobs := make([]exportedSavedJob, 0, len(savedJobs))
+	for _, savedJob := range savedJobs {
+		exportedSavedJobs = append(exportedSavedJobs, exportedSavedJob{
+			JobID:    savedJob.JobID,
+			JobTitle: savedJob.JobPost.Title,
+			SavedAt:  savedJob.CreatedAt,
+		})
+	}
+	exportedNotifications := make([]exportedNotification, 0, len(notifications))
+	for _, notification := range notifications {
+		exportedNotifications = append(exportedNotifications, exportedNotification{
+			Message:   notification.Message,
+			IsRead:    notification.IsRead,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"applications.json", exportedApplications},
+		{"messages.json", exportedMessages},
+		{"saved_jobs.json", exportedSavedJobs},
+		{"notifications.json", exportedNotifications},
+	}
+	for _, file := range files {
+		if err := writeJSON(archive, file.name, file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.ProfileImageKey != nil {
+		image, _, err := s.ImageService.OpenAvatar(userID, "large")
+		if err == nil {
+			err = copyReader(archive, "profile_image.jpg", image)
+		}
+		if err != nil {
+			log.Printf("export of user %d: skipping profile image: %v", userID, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DeleteAccount deletes the user's account after confirming their identity.
+//
+// Personal data is purged: applications with their resumes and Gemini
+// results, saved jobs, notifications, sessions and 2FA codes.  An applicant's
+// messages are deleted too; a company user's messages stay with the
+// organization.  A company user leaves their organization, and an
+// organization nobody else is in is deleted with its job posts.  The user
+// row itself is anonymized rather than deleted, so job posts and messages of
+// other users keep a valid sender.
+func (s *AccountService) DeleteAccount(userID uint, req *authmodel.DeleteAccountRequest, ip string) error {
+	if err := s.AuthService.ConfirmIdentity(userID, req.Password, req.FirebaseIDToken, ip); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+	member, err := orgservice.FindMembership(s.DB, userID)
+	if err != nil {
+		return err
+	}
+	if member != nil && member.Role == orgmodel.RoleOwner {
+		if err := s.checkNotSoleOwner(member); err != nil {
+			return err
+		}
+	}
+
+	// Images first: their service clears the columns that point at them.
+	if err := s.ImageService.DeleteAvatar(userID); err != nil && !errors.Is(err, imageservice.ErrImageNotFound) {
+		return err
+	}
+	if user.UserType == authmodel.UserTypeCompany {
+		if err := s.ImageService.DeleteCompanyLogo(userID); err != nil &&
+			!errors.Is(err, imageservice.ErrImageNotFound) && !errors.Is(err, imageservice.ErrCompanyNotFound) {
+			return err
+		}
+	}
+
+	var applications []jobmodel.JobApplication
+	if err := s.DB.Unscoped().Where("user_id = ?", userID).Find(&applications).Error; err != nil {
+		return fmt.Errorf("failed to retrieve applications: %w", err)
+	}
+
+	var deletedJobIDs []uint
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if member != nil {
+			ids, err := leaveOrganization(tx, member)
+			if err != nil {
+				return err
+			}
+			deletedJobIDs = ids
+		}
+
+		purges := []purge{
+			{"applications", &jobmodel.JobApplication{}, "user_id = @id"},
+			{"saved jobs", &jobmodel.SavedJob{}, "user_id = @id"},
+			{"notifications", &authmodel.Notification{}, "user_id = @id"},
+			{"company profile", &authmodel.CompanyProfile{}, "user_id = @id"},
+			{"sessions", &authmodel.RefreshToken{}, "user_id = @id"},
+			{"password reset codes", &authmodel.PasswordResetOTP{}, "user_id = @id"},
+			{"recovery codes", &authmodel.MFARecoveryCode{}, "user_id = @id"},
+			{"API keys", &authmodel.APIKey{}, "user_id = @id"},
+		}
+		if user.UserType == authmodel.UserTypeApplicant {
+			purges = append(purges, purge{"messages", &authmodel.Message{}, "sender_id = @id OR receiver_id = @id"})
+		}
+		for _, p := range purges {
+			if err := tx.Unscoped().Where(p.query, sql.Named("id", userID)).Delete(p.model).Error; err != nil {
+				return fmt.Errorf("failed to delete %s: %w", p.what, err)
+			}
+		}
+		if len(applications) > 0 {
+			jobIDs := make([]uint, 0, len(applications))
+			for _, application := range applications {
+				jobIDs = append(jobIDs, application.JobID)
+			}
+			if _, err := jobservice.RecountApplicants(tx, jobIDs...); err != nil {
+				return err
+			}
+		}
+		if err := authservice.PurgeLoginHistory(tx, user.Email, userID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(user).Updates(map[string]interface{}{
+			"name":                 "Deleted user",
+			"email":                fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"password":             "", // Matches no password
+			"phone":                "",
+			"company_name":         nil,
+			"profile_image":        nil,
+			"profile_image_key":    nil,
+			"firebase_uid":         nil,
+			"email_verified":       false,
+			"email_verified_at":    nil,
+			"verification_sent_at": nil,
+			"totp_secret":          nil,
+			"totp_enabled":         false,
+			"totp_last_step":       0,
+			"anonymized_at":        now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.JobService.ReindexJobPosts(deletedJobIDs...)
+
+	// Files go last, once nothing refers to them any more.
+	for _, application := range applications {
+		if application.ResumeFile == "" {
+			continue
+		}
+		if err := s.Storage.Delete(resumeKey(application.ResumeFile)); err != nil {
+			log.Printf("failed to delete resume of application %d: %v", application.ID, err)
+		}
+	}
+	return nil
+}
+
+// purge deletes the rows of model matching query, where @id is the user.
+type purge struct {
+	what  string
+	model interface{}
+	query string
+}
+
+func (s *AccountService) user(userID uint) (*authmodel.User, error) {
+	var user authmodel.User
+	err := s.DB.Where("anonymized_at IS NULL").First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return &user, nil
+}
+
+// checkNotSoleOwner refuses to let the only owner of a team leave it without
+// an owner.
+func (s *AccountService) checkNotSoleOwner(member *orgmodel.OrganizationMember) error {
+	var others, owners int64
+	base := s.DB.Model(&orgmodel.OrganizationMember{}).Where("organization_id = ? AND id <> ?", member.OrganizationID, member.ID)
+	if err := base.Session(&gorm.Session{}).Count(&others).Error; err != nil {
+		return fmt.Errorf("failed to count members: %w", err)
+	}
+	if err := base.Session(&gorm.Session{}).Where("role = ?", orgmodel.RoleOwner).Count(&owners).Error; err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if others > 0 && owners == 0 {
+		return ErrSoleOwner
+	}
+	return nil
+}
+
+// leaveOrganization removes the membership.  An organization left empty is
+// deleted together with its job posts and invitations; the IDs of the deleted
+// job posts are returned, to take them out of the search index.
+func leaveOrganization(tx *gorm.DB, member *orgmodel.OrganizationMember) ([]uint, error) {
+	if err := tx.Delete(member).Error; err != nil {
+		return nil, fmt.Errorf("failed to leave organization: %w", err)
+	}
+	var remaining int64
+	if err := tx.Model(&orgmodel.OrganizationMember{}).Where("organization_id = ?", member.OrganizationID).Count(&remaining).Error; err != nil {
+		return nil, fmt.Errorf("failed to count members: %w", err)
+	}
+	if remaining > 0 {
+		return nil, nil
+	}
+
+	// Job posts are soft-deleted like DeleteJobPost does, so applicants keep
+	// seeing what they applied to.
+	var jobIDs []uint
+	if err := tx.Model(&jobmodel.JobPost{}).Where("organization_id = ?", member.OrganizationID).Pluck("id", &jobIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	if err := tx.Where("organization_id = ?", member.OrganizationID).Delete(&jobmodel.JobPost{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete job posts: %w", err)
+	}
+	if err := tx.Where("organization_id = ?", member.OrganizationID).Delete(&orgmodel.OrganizationInvitation{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete invitations: %w", err)
+	}
+	if err := tx.Delete(&orgmodel.Organization{}, member.OrganizationID).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete organization: %w", err)
+	}
+	return jobIDs, nil
+}
+
+// resumeKey returns the storage key of a resume from the path stored on the
+// application ("uploads/resumes/<id>.pdf").
+func resumeKey(resumeFile string) string {
+	return "resumes/" + filepath.Base(resumeFile)
+}
+
+func (s *AccountService) copyFile(archive *zip.Writer, name, key string) error {
+	file, err := s.Storage.Open(key)
+	if err != nil {
+		return err
+	}
+	return copyReader(archive, name, file)
+}
+
+func copyReader(archive *zip.Writer, name string, file io.ReadCloser) error {
+	defer file.Close()
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+func writeJSON(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
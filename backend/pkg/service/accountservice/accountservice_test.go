@@ -0,0 +1,46 @@
+package accountservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/search"
+	"backend/pkg/service/authservice"
+	"backend/pkg/service/imageservice"
+	"backend/pkg/service/jobservice"
+	"backend/pkg/service/orgservice"
+	"backend/pkg/storage"
+	"backend/pkg/testdb"
+	"testing"
+)
+
+func TestDeleteAccountUnindexesJobPostsOfDeletedOrganization(t *testing.T) {
+	db := testdb.New(t)
+	store := storage.NewLocalStorage(t.TempDir())
+	index := search.NewMemoryIndex()
+	jobService := jobservice.NewJobService(db, nil, nil, store, index)
+	s := NewAccountService(db, store, &authservice.AuthService{DB: db}, imageservice.NewImageService(db, store), jobService)
+
+	user := &authmodel.User{Name: "Acme", Email: "owner@example.com", Password: "secret", UserType: authmodel.UserTypeCompany, EmailVerified: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	member, err := orgservice.CreatePersonalOrganization(db, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobPost := &jobmodel.JobPost{UserID: user.ID, OrganizationID: &member.OrganizationID, Title: "Go Developer", State: jobmodel.JobPostStateOpen, Status: true}
+	if err := db.Create(jobPost).Error; err != nil {
+		t.Fatal(err)
+	}
+	jobService.ReindexJobPosts(jobPost.ID)
+	if _, total, _ := index.Search("go", 0, 10); total != 1 {
+		t.Fatalf("the job post isn't indexed")
+	}
+
+	if err := s.DeleteAccount(user.ID, &authmodel.DeleteAccountRequest{Password: "secret"}, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if hits, total, _ := index.Search("go", 0, 10); total != 0 {
+		t.Errorf("Search(go) after the organization was deleted = %v (total %d), want nothing", hits, total)
+	}
+}
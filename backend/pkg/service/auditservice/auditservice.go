@@ -0,0 +1,243 @@
+package auditservice
+
+import (
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/service/orgservice"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 500
+)
+
+// Entry describes one audited action.
+type Entry struct {
+	ActorID    *uint
+	Action     string
+	TargetType string
+	TargetID   *uint
+	Changes    map[string]auditmodel.Change
+	Details    map[string]interface{}
+	IP         string
+	UserAgent  string
+}
+
+// Filter narrows down a query of the audit log.  Zero values match anything.
+type Filter struct {
+	ActorID    *uint
+	Action     string
+	TargetType string
+	TargetID   *uint
+	From       *time.Time
+	To         *time.Time
+	BeforeID   uint // Only entries older than this one, for paging
+	Limit      int
+}
+
+// IAuditService interface
+type IAuditService interface {
+	Record(entry Entry)
+	List(userID uint, filter Filter) ([]auditmodel.AuditLog, error)
+}
+
+type AuditService struct {
+	DB *gorm.DB
+}
+
+// NewAuditService creates a new AuditService.
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{DB: db}
+}
+
+// Record appends an entry to the audit log.  The entry belongs to the
+// organization of its target, or else to the actor's organization.  Failures
+// are logged rather than returned: the action has already happened.
+func (s *AuditService) Record(entry Entry) {
+	record := auditmodel.AuditLog{
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		IP:         entry.IP,
+		UserAgent:  truncate(entry.UserAgent, 255),
+	}
+	var err error
+	if len(entry.Changes) > 0 {
+		if record.Changes, err = json.Marshal(entry.Changes); err != nil {
+			log.Printf("audit: failed to encode changes of %s: %v", entry.Action, err)
+		}
+	}
+	if len(entry.Details) > 0 {
+		if record.Details, err = json.Marshal(entry.Details); err != nil {
+			log.Printf("audit: failed to encode details of %s: %v", entry.Action, err)
+		}
+	}
+	if record.OrganizationID, err = s.organizationOf(&entry); err != nil {
+		log.Printf("audit: failed to resolve organization of %s: %v", entry.Action, err)
+	}
+
+	if err := s.DB.Create(&record).Error; err != nil {
+		log.Printf("audit: failed to record %s: %v", entry.Action, err)
+	}
+}
+
+// List returns the audit log of the caller's organization, newest first.
+// Only organization owners can read it.
+func (s *AuditService) List(userID uint, filter Filter) ([]auditmodel.AuditLog, error) {
+	member, err := orgservice.FindMembership(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil || member.Role != orgmodel.RoleOwner {
+		return nil, orgservice.ErrNotOwner
+	}
+
+	query := s.DB.Where("organization_id = ?", member.OrganizationID)
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	} else if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	var entries []auditmodel.AuditLog
+	if err := query.Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// organizationOf finds the organization an entry belongs to.
+func (s *AuditService) organizationOf(entry *Entry) (*uint, error) {
+	var orgIDs []uint
+	if entry.TargetID != nil {
+		var err error
+		switch entry.TargetType {
+		case auditmodel.TargetOrganization:
+			return entry.TargetID, nil
+		case auditmodel.TargetUser:
+			err = orgservice.MemberOrganizationIDs(s.DB, *entry.TargetID).Pluck("organization_id", &orgIDs).Error
+		case auditmodel.TargetJobPost:
+			err = s.DB.Unscoped().Model(&jobmodel.JobPost{}).
+				Where("id = ? AND organization_id IS NOT NULL", *entry.TargetID).
+				Pluck("organization_id", &orgIDs).Error
+		case auditmodel.TargetJobApplication:
+			err = s.DB.Unscoped().Model(&jobmodel.JobPost{}).
+				Where("organization_id IS NOT NULL AND id IN (?)",
+					s.DB.Unscoped().Model(&jobmodel.JobApplication{}).Select("job_id").Where("id = ?", *entry.TargetID)).
+				Pluck("organization_id", &orgIDs).Error
+		case auditmodel.TargetMessage:
+			// A conversation belongs to whichever side is a company.
+			var message authmodel.Message
+			if err = s.DB.Unscoped().Select("sender_id", "receiver_id").First(&message, *entry.TargetID).Error; err == nil {
+				err = s.DB.Model(&orgmodel.OrganizationMember{}).
+					Where("user_id IN ?", []uint{message.SenderID, message.ReceiverID}).
+					Pluck("organization_id", &orgIDs).Error
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(orgIDs) > 0 {
+			return &orgIDs[0], nil
+		}
+	}
+	if entry.ActorID == nil {
+		return nil, nil
+	}
+	if err := orgservice.MemberOrganizationIDs(s.DB, *entry.ActorID).Pluck("organization_id", &orgIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(orgIDs) > 0 {
+		return &orgIDs[0], nil
+	}
+	return nil, nil
+}
+
+// Diff compares two values field by field, as they are encoded to JSON, and
+// returns the fields that differ.  With fields given only those are compared;
+// otherwise every top-level field except nested objects and timestamps is.
+func Diff(before, after interface{}, fields ...string) map[string]auditmodel.Change {
+	beforeFields, afterFields := toFields(before), toFields(after)
+	if len(fields) == 0 {
+		seen := make(map[string]bool)
+		for _, values := range []map[string]interface{}{beforeFields, afterFields} {
+			for name, value := range values {
+				if _, nested := value.(map[string]interface{}); nested || ignoredFields[name] || seen[name] {
+					continue
+				}
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		}
+	}
+
+	changes := make(map[string]auditmodel.Change)
+	for _, name := range fields {
+		b, a := beforeFields[name], afterFields[name]
+		if !reflect.DeepEqual(b, a) {
+			changes[name] = auditmodel.Change{Before: b, After: a}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+var ignoredFields = map[string]bool{
+	"CreatedAt": true, "created_at": true,
+	"UpdatedAt": true, "updated_at": true,
+	"DeletedAt": true, "deleted_at": true,
+}
+
+func toFields(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
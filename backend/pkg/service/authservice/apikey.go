@@ -0,0 +1,203 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrAPIKeyNameRequired = errors.New("name is required and must be at most 100 characters")
+	ErrInvalidScope       = errors.New("unknown scope")
+	ErrScopesRequired     = errors.New("at least one scope is required")
+	ErrInvalidKeyExpiry   = errors.New("expires_in_days must be between 1 and 365")
+	ErrTooManyAPIKeys     = errors.New("too many active API keys")
+)
+
+const (
+	// apiKeyPrefix starts every key, so leaked keys are easy to recognise.
+	apiKeyPrefix = "frk_"
+	// maxAPIKeysPerUser bounds the active keys of one user.
+	maxAPIKeysPerUser = 20
+	// apiKeyUsageInterval throttles last-used updates to one write per key
+	// per interval.
+	apiKeyUsageInterval = time.Minute
+)
+
+// CreateAPIKey issues a new API key for the user.  The key itself is only
+// returned here; afterwards only its prefix is shown.
+func (s *AuthService) CreateAPIKey(userID uint, req *authmodel.CreateAPIKeyRequest) (string, *authmodel.APIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return "", nil, ErrAPIKeyNameRequired
+	}
+	if len(req.Scopes) == 0 {
+		return "", nil, ErrScopesRequired
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > 365 {
+			return "", nil, ErrInvalidKeyExpiry
+		}
+		t := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if user == nil {
+		return "", nil, ErrUserNotFound
+	}
+	var active int64
+	if err := s.activeAPIKeys(userID).Model(&authmodel.APIKey{}).Count(&active).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if active >= maxAPIKeysPerUser {
+		return "", nil, ErrTooManyAPIKeys
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + secret
+	record := authmodel.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   hashToken(key),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	response := apiKeyResponse(&record)
+	return key, &response, nil
+}
+
+// ListAPIKeys returns the user's keys, including revoked and expired ones,
+// newest first.
+func (s *AuthService) ListAPIKeys(userID uint) ([]authmodel.APIKeyResponse, error) {
+	var keys []authmodel.APIKey
+	if err := s.DB.Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	response := make([]authmodel.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		response = append(response, apiKeyResponse(&keys[i]))
+	}
+	return response, nil
+}
+
+// RevokeAPIKey stops one of the user's keys from working.  Revoking a key
+// twice is harmless.
+func (s *AuthService) RevokeAPIKey(userID, keyID uint) error {
+	var key authmodel.APIKey
+	err := s.DB.Where("id = ? AND user_id = ?", keyID, userID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAPIKeyNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	if err := s.DB.Model(&key).Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey looks up an active API key and its user.  It is used by
+// the middleware for requests that present a key instead of a JWT.
+func (s *AuthService) AuthenticateAPIKey(key, ip string) (*authmodel.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	var record authmodel.APIKey
+	err := s.DB.Preload("User").
+		Where("key_hash = ? AND revoked_at IS NULL", hashToken(key)).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	now := time.Now()
+	if record.ExpiresAt != nil && now.After(*record.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+	// The user row is soft-deleted or anonymized along with the account.
+	if record.User.ID == 0 || record.User.AnonymizedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= apiKeyUsageInterval {
+		err := s.DB.Model(&authmodel.APIKey{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+		record.LastUsedAt = &now
+		record.LastUsedIP = ip
+	}
+	return &record, nil
+}
+
+func (s *AuthService) activeAPIKeys(userID uint) *gorm.DB {
+	return s.DB.Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now())
+}
+
+// normalizeScopes validates scopes and drops duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	var result []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		known := false
+		for _, s := range authmodel.APIScopes {
+			if s == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func apiKeyResponse(k *authmodel.APIKey) authmodel.APIKeyResponse {
+	return authmodel.APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     strings.Fields(k.Scopes),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
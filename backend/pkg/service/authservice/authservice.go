@@ -1,13 +1,16 @@
 package authservice
 
 import (
+	"backend/pkg/keyring"
+	"backend/pkg/mailer"
 	"backend/pkg/model/authmodel"
+	"backend/pkg/repository/authrepo"
+	"backend/pkg/service/orgservice"
 	"errors"
 	"fmt"
-	"os"
+	"log"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
@@ -17,96 +20,186 @@ var (
 	ErrOTPMismatch        = errors.New("OTP does not match")
 	ErrOTPExpired         = errors.New("OTP has expired")
 	ErrOTPAlreadyUsed     = errors.New("OTP has already been used")
+	ErrOTPNotFound        = errors.New("no OTP has been requested")
+	ErrOTPNotVerified     = errors.New("OTP has not been verified")
+	ErrOTPTooManyAttempts = errors.New("too many OTP attempts")
+	ErrOTPRequestTooSoon  = errors.New("an OTP was requested too recently")
+
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
 )
 
 const (
-	otpChars  = "1234567890"
-	otpLength = 6
+	otpChars          = "1234567890"
+	otpLength         = 6
+	otpTTL            = 10 * time.Minute // How long an OTP stays valid
+	otpMaxAttempts    = 5                // Wrong guesses allowed per OTP
+	otpMaxUserGuesses = 10               // Wrong guesses allowed per user across OTPs...
+	otpGuessWindow    = 24 * time.Hour   // ...within this long
+	otpResendInterval = time.Minute      // Minimum time between two OTP requests
 )
 
 type IAuthService interface {
 	Register(registerRequest *authmodel.RegisterRequest) error
-	Login(email, password string) (string, *authmodel.User, error)
-	RequestPasswordReset(email string) error
+	Login(email, password, ip string) (*authmodel.TokenPair, *authmodel.User, error)
+	RefreshTokens(refreshToken string) (*authmodel.TokenPair, *authmodel.User, error)
+	Logout(refreshToken string) error
+	LogoutAll(userID uint) error
+	LoginWithFirebase(req *authmodel.FirebaseLoginRequest) (*authmodel.TokenPair, *authmodel.User, error)
+	RequestPasswordReset(email string) (*authmodel.User, error)
 	VerifyOTP(email, otp string) (*authmodel.User, error)
-	ResetPassword(req *authmodel.ResetPasswordRequest) error
+	ResetPassword(req *authmodel.ResetPasswordRequest) (*authmodel.User, error)
+	VerifyMFA(challenge, code, recoveryCode, ip string) (*authmodel.TokenPair, *authmodel.User, error)
+	BeginMFAEnrollment(userID uint) (*authmodel.MFAEnrollment, error)
+	ConfirmMFAEnrollment(userID uint, code string) ([]string, error)
+	DisableMFA(userID uint, code, recoveryCode string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	SetMFAPolicy(userID uint, required bool) error
+	SendVerificationEmail(userID uint) error
+	VerifyEmail(token string) (*authmodel.User, error)
 	UpdateProfile(userID uint, name, phone *string) error
+	ConfirmIdentity(userID uint, password, firebaseIDToken, ip string) error
+	CreateAPIKey(userID uint, req *authmodel.CreateAPIKeyRequest) (string, *authmodel.APIKeyResponse, error)
+	ListAPIKeys(userID uint) ([]authmodel.APIKeyResponse, error)
+	RevokeAPIKey(userID, keyID uint) error
+	AuthenticateAPIKey(key, ip string) (*authmodel.APIKey, error)
 }
 type AuthService struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Mailer   mailer.IMailer
+	Firebase authrepo.IFirebaseRepository // nil disables Firebase sign-in
+	Keyring  *keyring.Keyring             // Signs access tokens
 }
 
-func NewAuthService(db *gorm.DB) *AuthService {
-	return &AuthService{DB: db}
+func NewAuthService(db *gorm.DB, mailer mailer.IMailer, firebase authrepo.IFirebaseRepository, keyring *keyring.Keyring) *AuthService {
+	return &AuthService{DB: db, Mailer: mailer, Firebase: firebase, Keyring: keyring}
 }
 
 func (s *AuthService) Register(registerRequest *authmodel.RegisterRequest) error {
 
-	var User authmodel.RegisterRequest
+	var User authmodel.User
 	if err := s.DB.Where("email = ?", registerRequest.Email).First(&User).Error; err == nil {
 		return errors.New("user already exists")
 	}
 
+	hashedPassword, err := hashPassword(registerRequest.Password)
+	if err != nil {
+		return err
+	}
+
 	newUser := authmodel.User{
 		Email:       registerRequest.Email,
 		Name:        registerRequest.Name,
-		Password:    registerRequest.Password,
+		Password:    hashedPassword,
 		Phone:       registerRequest.Phone,
 		UserType:    registerRequest.UserType,
 		CompanyName: registerRequest.CompanyName,
 	}
 
-	if err := s.DB.Create(&newUser).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		return createCompanyProfile(tx, &newUser)
+	})
+	if err != nil {
 		return err
 	}
 
+	// The account exists either way; the user can ask for another email.
+	if err := s.SendVerificationEmail(newUser.ID); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", newUser.ID, err)
+	}
+
 	return nil
 }
 
-func (s *AuthService) Login(email, password string) (string, *authmodel.User, error) {
+// Login checks the credentials and starts a new session.  Failed attempts are
+// counted per account and per client IP; while either is locked out, Login
+// returns a *LockedError without checking the password.
+func (s *AuthService) Login(email, password, ip string) (*authmodel.TokenPair, *authmodel.User, error) {
+	if err := s.checkLoginThrottle(accountThrottleKey(email), ipThrottleKey(ip)); err != nil {
+		return nil, nil, err
+	}
+
 	var user authmodel.User
 	result := s.DB.Where("email = ?", email).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return "", nil, ErrUserNotFound
+			// Unknown emails are counted and timed like wrong passwords, so
+			// neither lockouts nor response times reveal whether the email
+			// exists.
+			verifyPassword(dummyPasswordHash, password)
+			if err := s.recordLoginFailure(email, ip, nil); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, ErrInvalidCredentials // Don't reveal whether the email exists
 		}
-		return "", nil, fmt.Errorf("failed to query user: %w", result.Error)
+		return nil, nil, fmt.Errorf("failed to query user: %w", result.Error)
 	}
 
-	// Generate JWT token
-	token, err := generateJWTToken(&user) // Pass the entire user object
-	if err != nil {
-		return "", nil, err
+	ok, needsRehash := verifyPassword(user.Password, password)
+	if !ok {
+		if err := s.recordLoginFailure(email, ip, &user.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	return token, &user, nil // Return the token, user and nil error on success
-}
-
-func generateJWTToken(user *authmodel.User) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id":      user.ID, // Include user ID
-		"name":         user.Name,
-		"email":        user.Email,
-		"phone":        user.Phone,
-		"user_type":    user.UserType,
-		"company_name": user.CompanyName,                      // Handle potential nil pointer
-		"exp":          time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
+	if err := clearLoginThrottle(s.DB, email, &user.ID, ip); err != nil {
+		log.Printf("failed to reset login throttle for user %d: %v", user.ID, err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Upgrade legacy plaintext (or weaker) hashes now that we know the password.
+	if needsRehash {
+		if err := s.rehashPassword(&user, password); err != nil {
+			// Log and continue.  The user is authenticated either way.
+			log.Printf("failed to upgrade password hash for user %d: %v", user.ID, err)
+		}
+	}
 
-	// Load secret key from environment variable.
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		return "", fmt.Errorf("JWT_SECRET_KEY environment variable not set")
+	// Accounts with 2FA get a challenge instead of tokens.
+	if err := s.mfaChallenge(&user); err != nil {
+		return nil, nil, err
 	}
 
-	signedToken, err := token.SignedString([]byte(secretKey)) // Sign the token
+	// Start a new session: access token plus a fresh refresh token family.
+	tokens, err := s.issueTokens(s.DB, &user, "")
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err) // More specific error
+		return nil, nil, err
+	}
+
+	return tokens, &user, nil // Return the tokens, user and nil error on success
+}
+
+// createCompanyProfile gives a new company user its profile and personal
+// organization.  Other user types have neither.
+func createCompanyProfile(tx *gorm.DB, user *authmodel.User) error {
+	if user.UserType != authmodel.UserTypeCompany {
+		return nil
+	}
+	profile := authmodel.CompanyProfile{UserID: user.ID, CompanyName: user.Name}
+	if user.CompanyName != nil && *user.CompanyName != "" {
+		profile.CompanyName = *user.CompanyName
+	}
+	if err := tx.Create(&profile).Error; err != nil {
+		return fmt.Errorf("failed to create company profile: %w", err)
 	}
+	_, err := orgservice.CreatePersonalOrganization(tx, user)
+	return err
+}
 
-	return signedToken, nil
+// rehashPassword replaces the stored password with a fresh hash of the plaintext.
+func (s *AuthService) rehashPassword(user *authmodel.User, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.DB.Model(user).Update("password", hashedPassword).Error; err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	user.Password = hashedPassword
+	return nil
 }
 
 func (s *AuthService) GetUserByID(userID uint) (*authmodel.User, error) {
@@ -123,19 +216,6 @@ func (s *AuthService) GetUserByID(userID uint) (*authmodel.User, error) {
 	return &user, nil
 }
 
-// ResetPassword updates the user's password (after request reset verification).
-func (s *AuthService) ResetPassword(req *authmodel.ResetPasswordRequest) error {
-	var user authmodel.User
-	if err := s.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		return errors.New("email not found")
-	}
-	user.Password = req.NewPassword
-	if err := s.DB.Save(&user).Error; err != nil {
-		return errors.New("failed to update password")
-	}
-	return nil
-}
-
 // UpdateProfile updates the user's profile (name and phone).
 func (s *AuthService) UpdateProfile(userID uint, name, phone *string) error {
 	// 1. Find the user by ID.
@@ -177,74 +257,3 @@ func (s *AuthService) UpdateProfile(userID uint, name, phone *string) error {
 
 	return nil
 }
-
-// VerifyOTP checks the provided OTP against the stored OTP for the user.
-// func (s *AuthService) VerifyOTP(email, otp string) (*authmodel.User, error) {
-// 	var user authmodel.User
-// 	result := s.DB.Where("email = ?", email).First(&user)
-// 	if result.Error != nil {
-// 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-// 			return nil, ErrUserNotFound // Consistent error
-// 		}
-// 		return nil, fmt.Errorf("failed to retrieve user: %w", result.Error)
-// 	}
-
-// 	// Check if OTP is set, not expired, and not used.
-// 	if user.OTP == nil || *user.OTP != otp || time.Now().After(*user.OTPExpiry) || user.OTPUsed {
-// 		if user.OTP == nil {
-// 			return nil, ErrUserNotFound // No OTP, treat as user not found (for security)
-// 		} else if *user.OTP != otp {
-// 			return nil, ErrOTPMismatch
-// 		} else if time.Now().After(*user.OTPExpiry) {
-// 			return nil, ErrOTPExpired
-// 		} else {
-// 			return nil, ErrOTPAlreadyUsed
-// 		}
-
-// 	}
-
-// 	return &user, nil
-// }
-
-// generateOTP generates a random 6-digit OTP.
-// func generateOTP(length int) (string, error) {
-// 	otp := make([]byte, length)
-// 	for i := 0; i < length; i++ {
-// 		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(otpChars))))
-// 		if err != nil {
-// 			return "", err
-// 		}
-// 		otp[i] = otpChars[num.Int64()]
-// 	}
-// 	return string(otp), nil
-// }
-
-// sendOTPEmail sends the OTP to the user's email address.
-// func sendOTPEmail(email, otp string) error {
-// 	from := os.Getenv("EMAIL_FROM")
-// 	pass := os.Getenv("EMAIL_PASS")
-// 	if from == "" || pass == "" {
-// 		return fmt.Errorf("EMAIL_FROM and EMAIL_PASS environment variables must be set")
-// 	}
-
-// 	// Construct the email message.
-// 	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-// 		"Subject: Password Reset OTP\r\n"+
-// 		"\r\n"+
-// 		"Your OTP for password reset is: %s\r\n", email, otp))
-
-// 	// SMTP server settings.  Replace with your actual settings.
-// 	smtpHost := "smtp.gmail.com" // Example: Gmail
-// 	smtpPort := "587"
-
-// 	// Authentication information.
-// 	auth := smtp.PlainAuth("", from, pass, smtpHost)
-
-// 	// Send the email.
-// 	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{email}, msg)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to send email: %w", err)
-// 	}
-
-// 	return nil
-// }
@@ -0,0 +1,166 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFirebaseDisabled         = errors.New("firebase sign-in is not configured")
+	ErrInvalidFirebaseToken     = errors.New("invalid firebase ID token")
+	ErrFirebaseEmailNotVerified = errors.New("firebase account email is not verified")
+	ErrInvalidUserType          = errors.New("user_type must be applicant or company")
+	ErrCompanyNameRequired      = errors.New("company_name is required for company accounts")
+)
+
+// LoginWithFirebase verifies a Firebase ID token and signs the matching local
+// user in.  The user is found by Firebase UID, then by (verified) email, and
+// is provisioned when neither exists.
+func (s *AuthService) LoginWithFirebase(req *authmodel.FirebaseLoginRequest) (*authmodel.TokenPair, *authmodel.User, error) {
+	if s.Firebase == nil {
+		return nil, nil, ErrFirebaseDisabled
+	}
+
+	token, err := s.Firebase.VerifyIDToken(req.IDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFirebaseToken, err)
+	}
+
+	email, _ := token.Claims["email"].(string)
+	emailVerified, _ := token.Claims["email_verified"].(bool)
+	name, _ := token.Claims["name"].(string)
+	email = strings.TrimSpace(email)
+
+	user, err := s.findFirebaseUser(token.UID, email, emailVerified)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user == nil {
+		if email == "" {
+			return nil, nil, fmt.Errorf("%w: token has no email", ErrInvalidFirebaseToken)
+		}
+		phone := req.Phone
+		if record, err := s.Firebase.GetUser(token.UID); err == nil {
+			if name == "" {
+				name = record.DisplayName
+			}
+			if phone == "" {
+				phone = record.PhoneNumber
+			}
+		}
+		user, err = s.provisionFirebaseUser(token.UID, email, name, phone, emailVerified, req)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Firebase is the first factor only; 2FA still applies.
+	if err := s.mfaChallenge(user); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokens(s.DB, user, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// findFirebaseUser returns the local user linked to the Firebase UID.  An
+// existing account with the same email is linked on first sign-in, but only
+// when Firebase has verified that email; otherwise anyone could claim it.
+func (s *AuthService) findFirebaseUser(uid, email string, emailVerified bool) (*authmodel.User, error) {
+	var user authmodel.User
+	err := s.DB.Where("firebase_uid = ?", uid).First(&user).Error
+	if err == nil {
+		return &user, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	if email == "" {
+		return nil, nil
+	}
+	err = s.DB.Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	if !emailVerified {
+		return nil, ErrFirebaseEmailNotVerified
+	}
+	updates := map[string]interface{}{"firebase_uid": uid}
+	if !user.EmailVerified {
+		// Firebase has verified the address, which is as good as our own link.
+		now := time.Now()
+		updates["email_verified"] = true
+		updates["email_verified_at"] = now
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.DB.Model(&user).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to link firebase account: %w", err)
+	}
+	user.FirebaseUID = &uid
+	user.EmailVerified = true
+	return &user, nil
+}
+
+func (s *AuthService) provisionFirebaseUser(uid, email, name, phone string, emailVerified bool, req *authmodel.FirebaseLoginRequest) (*authmodel.User, error) {
+	userType := req.UserType
+	if userType == "" {
+		userType = authmodel.UserTypeApplicant
+	}
+	if userType != authmodel.UserTypeApplicant && userType != authmodel.UserTypeCompany {
+		return nil, ErrInvalidUserType
+	}
+	if userType == authmodel.UserTypeCompany && (req.CompanyName == nil || *req.CompanyName == "") {
+		return nil, ErrCompanyNameRequired
+	}
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	// Firebase users sign in without a local password; store a random one so
+	// the password login can never match.
+	password, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := authmodel.User{
+		Email:       email,
+		Name:        name,
+		Password:    hashedPassword,
+		Phone:       phone,
+		UserType:    userType,
+		CompanyName: req.CompanyName,
+		FirebaseUID: &uid,
+	}
+	if emailVerified {
+		now := time.Now()
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return createCompanyProfile(tx, &user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,160 @@
+package authservice
+
+import (
+	"backend/pkg/keyring"
+	"backend/pkg/model/authmodel"
+	"errors"
+	"testing"
+
+	"firebase.google.com/go/auth"
+)
+
+// fakeFirebase accepts the ID tokens in tokens and rejects the rest, without
+// talking to Google.
+type fakeFirebase struct {
+	tokens map[string]*auth.Token
+	users  map[string]*auth.UserRecord
+}
+
+func (f *fakeFirebase) VerifyIDToken(idToken string) (*auth.Token, error) {
+	if token, ok := f.tokens[idToken]; ok {
+		return token, nil
+	}
+	return nil, errors.New("token signature is invalid")
+}
+
+func (f *fakeFirebase) GetUser(uid string) (*auth.UserRecord, error) {
+	if user, ok := f.users[uid]; ok {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+// firebaseToken is a verified ID token of uid with the given email claims.
+func firebaseToken(uid, email string, emailVerified bool) *auth.Token {
+	return &auth.Token{UID: uid, Claims: map[string]interface{}{"email": email, "email_verified": emailVerified}}
+}
+
+func newFirebaseService(t *testing.T, firebase *fakeFirebase) *AuthService {
+	t.Helper()
+	s, _ := newTestService(t)
+	key := keyring.NewHMACKey([]byte("firebase-test-secret"))
+	kr, err := keyring.New(key.ID, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Keyring = kr
+	s.Firebase = firebase
+	return s
+}
+
+func TestLoginWithFirebaseLinkedByUID(t *testing.T) {
+	s := newFirebaseService(t, &fakeFirebase{tokens: map[string]*auth.Token{
+		// Firebase says the email changed; the UID still names the user.
+		"token": firebaseToken("uid-1", "new@example.com", true),
+	}})
+	uid := "uid-1"
+	user := createUser(t, s, "old@example.com", "password")
+	if err := s.DB.Model(user).Update("firebase_uid", uid).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, got, err := s.LoginWithFirebase(&authmodel.FirebaseLoginRequest{IDToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || tokens.AccessToken == "" {
+		t.Errorf("signed in as user %d, want %d with tokens", got.ID, user.ID)
+	}
+}
+
+func TestLoginWithFirebaseLinksVerifiedEmail(t *testing.T) {
+	s := newFirebaseService(t, &fakeFirebase{tokens: map[string]*auth.Token{
+		"token": firebaseToken("uid-1", "user@example.com", true),
+	}})
+	user := createUser(t, s, "user@example.com", "password")
+	if err := s.DB.Model(user).Update("email_verified", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, got, err := s.LoginWithFirebase(&authmodel.FirebaseLoginRequest{IDToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("signed in as user %d, want %d", got.ID, user.ID)
+	}
+	var stored authmodel.User
+	if err := s.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.FirebaseUID == nil || *stored.FirebaseUID != "uid-1" || !stored.EmailVerified {
+		t.Errorf("stored user has firebase_uid %v and email_verified %v, want uid-1 and true", stored.FirebaseUID, stored.EmailVerified)
+	}
+}
+
+func TestLoginWithFirebaseRefusesUnverifiedEmailLink(t *testing.T) {
+	s := newFirebaseService(t, &fakeFirebase{tokens: map[string]*auth.Token{
+		"token": firebaseToken("uid-1", "user@example.com", false),
+	}})
+	user := createUser(t, s, "user@example.com", "password")
+
+	if _, _, err := s.LoginWithFirebase(&authmodel.FirebaseLoginRequest{IDToken: "token"}); !errors.Is(err, ErrFirebaseEmailNotVerified) {
+		t.Fatalf("LoginWithFirebase() error = %v, want %v", err, ErrFirebaseEmailNotVerified)
+	}
+	var stored authmodel.User
+	if err := s.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.FirebaseUID != nil {
+		t.Errorf("the account was linked to %s", *stored.FirebaseUID)
+	}
+}
+
+func TestLoginWithFirebaseProvisionsUser(t *testing.T) {
+	s := newFirebaseService(t, &fakeFirebase{
+		tokens: map[string]*auth.Token{"token": firebaseToken("uid-1", "new@example.com", true)},
+		users:  map[string]*auth.UserRecord{"uid-1": {UserInfo: &auth.UserInfo{DisplayName: "New User", PhoneNumber: "+66800000000"}}},
+	})
+
+	tokens, user, err := s.LoginWithFirebase(&authmodel.FirebaseLoginRequest{IDToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken == "" {
+		t.Error("no access token")
+	}
+	var stored authmodel.User
+	if err := s.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != "new@example.com" || stored.Name != "New User" || stored.Phone != "+66800000000" ||
+		stored.UserType != authmodel.UserTypeApplicant || !stored.EmailVerified ||
+		stored.FirebaseUID == nil || *stored.FirebaseUID != "uid-1" {
+		t.Errorf("provisioned user = %+v", stored)
+	}
+	// The random password must not be usable.
+	if ok, _ := verifyPassword(stored.Password, ""); ok {
+		t.Error("the provisioned user can sign in with an empty password")
+	}
+}
+
+func TestLoginWithFirebaseProvisioningErrors(t *testing.T) {
+	s := newFirebaseService(t, &fakeFirebase{tokens: map[string]*auth.Token{
+		"token": firebaseToken("uid-1", "new@example.com", true),
+	}})
+	tests := []struct {
+		name string
+		req  authmodel.FirebaseLoginRequest
+		want error
+	}{
+		{"forged token", authmodel.FirebaseLoginRequest{IDToken: "forged"}, ErrInvalidFirebaseToken},
+		{"unknown user type", authmodel.FirebaseLoginRequest{IDToken: "token", UserType: "admin"}, ErrInvalidUserType},
+		{"company without a name", authmodel.FirebaseLoginRequest{IDToken: "token", UserType: authmodel.UserTypeCompany}, ErrCompanyNameRequired},
+	}
+	for _, tt := range tests {
+		if _, _, err := s.LoginWithFirebase(&tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: LoginWithFirebase() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
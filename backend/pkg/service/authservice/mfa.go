@@ -0,0 +1,425 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/service/orgservice"
+	"backend/pkg/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMFANotAllowed       = errors.New("two-factor authentication is only available for company accounts")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollNotStarted = errors.New("two-factor enrolment has not been started")
+	ErrInvalidMFACode      = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken     = errors.New("invalid or expired MFA token")
+	ErrMFARequiredByPolicy = errors.New("two-factor authentication is required for this account")
+	ErrMFAPolicyOwnerOnly  = errors.New("only organization owners can change the two-factor policy")
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaIssuer         = "Filter Resume" // Shown in authenticator apps
+	mfaSkew           = 1               // Steps of clock drift accepted either way
+	recoveryCodeCount = 10
+
+	// PurposeMFA marks the challenge token issued between the password and the
+	// second factor.
+	PurposeMFA = "mfa"
+)
+
+// MFARequiredError is returned by Login when the password was right but a
+// second factor is needed.  Token is the challenge to present to VerifyMFA,
+// or to CompleteMFAEnrollment when Enrolled is false (the account has to set
+// up TOTP because its policy requires it).
+type MFARequiredError struct {
+	Token    string
+	Enrolled bool
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+// mfaChallenge returns an MFARequiredError if the user has to pass a second
+// factor before getting tokens.
+func (s *AuthService) mfaChallenge(user *authmodel.User) error {
+	required := user.TOTPEnabled
+	if !required {
+		var err error
+		required, err = s.mfaRequiredByPolicy(user)
+		if err != nil {
+			return err
+		}
+	}
+	if !required {
+		return nil
+	}
+
+	now := time.Now()
+	token, err := s.Keyring.Sign(jwt.MapClaims{
+		"purpose": PurposeMFA,
+		"user_id": user.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(mfaChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return &MFARequiredError{Token: token, Enrolled: user.TOTPEnabled}
+}
+
+// mfaRequiredByPolicy reports whether the user's organization requires 2FA.
+func (s *AuthService) mfaRequiredByPolicy(user *authmodel.User) (bool, error) {
+	if user.UserType != authmodel.UserTypeCompany {
+		return false, nil
+	}
+	var count int64
+	err := s.DB.Model(&orgmodel.Organization{}).
+		Where("id IN (?) AND mfa_required = ?", orgservice.MemberOrganizationIDs(s.DB, user.ID), true).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check MFA policy: %w", err)
+	}
+	return count > 0, nil
+}
+
+// parseMFAChallenge returns the user a challenge token was issued to.
+func (s *AuthService) parseMFAChallenge(tokenString string) (*authmodel.User, error) {
+	token, err := s.Keyring.Parse(tokenString)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidMFAToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidMFAToken
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != PurposeMFA {
+		return nil, ErrInvalidMFAToken
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.GetUserByID(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidMFAToken
+	}
+	return user, nil
+}
+
+// VerifyMFA completes a login with a TOTP or recovery code.  Wrong codes
+// count towards the same lockout as wrong passwords.
+func (s *AuthService) VerifyMFA(challenge, code, recoveryCode, ip string) (*authmodel.TokenPair, *authmodel.User, error) {
+	user, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkLoginThrottle(accountThrottleKey(user.Email), ipThrottleKey(ip)); err != nil {
+		return nil, nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, nil, ErrMFANotEnrolled
+	}
+
+	if err := s.checkSecondFactor(user, code, recoveryCode); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.recordLoginFailure(user.Email, ip, &user.ID); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, err
+	}
+
+	tokens, err := s.issueTokens(s.DB, user, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// BeginMFAEnrollment generates a new TOTP secret for the user.  It only takes
+// effect once a code from it is confirmed.
+func (s *AuthService) BeginMFAEnrollment(userID uint) (*authmodel.MFAEnrollment, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.UserType != authmodel.UserTypeCompany {
+		return nil, ErrMFANotAllowed
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	if err := s.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+
+	return &authmodel.MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// BeginMFAEnrollmentWithChallenge starts enrolment for a user who was asked
+// to set up 2FA during login.
+func (s *AuthService) BeginMFAEnrollmentWithChallenge(challenge string) (*authmodel.MFAEnrollment, error) {
+	user, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginMFAEnrollment(user.ID)
+}
+
+// ConfirmMFAEnrollment enables 2FA once the user proves the authenticator
+// works.  It returns the recovery codes, which are only shown this once.
+func (s *AuthService) ConfirmMFAEnrollment(userID uint, code string) ([]string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return nil, ErrMFAEnrollNotStarted
+	}
+
+	step, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), mfaSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	return codes, nil
+}
+
+// CompleteMFAEnrollment confirms enrolment for a user who was asked to set up
+// 2FA during login, and finishes that login.
+func (s *AuthService) CompleteMFAEnrollment(challenge, code string) (*authmodel.TokenPair, *authmodel.User, []string, error) {
+	user, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	codes, err := s.ConfirmMFAEnrollment(user.ID, code)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	user.TOTPEnabled = true
+
+	tokens, err := s.issueTokens(s.DB, user, "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tokens, user, codes, nil
+}
+
+// DisableMFA turns 2FA off.  It needs a valid code and is refused while the
+// account's policy requires 2FA.
+func (s *AuthService) DisableMFA(userID uint, code, recoveryCode string) error {
+	user, err := s.enrolledUser(userID)
+	if err != nil {
+		return err
+	}
+	required, err := s.mfaRequiredByPolicy(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByPolicy
+	}
+	if err := s.checkSecondFactor(user, code, recoveryCode); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":    nil,
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&authmodel.MFARecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user.
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.enrolledUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(user, code, ""); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// SetMFAPolicy sets whether 2FA is mandatory for every member of the caller's
+// organization.  Only owners can change it, and they have to be enrolled
+// before requiring it.
+func (s *AuthService) SetMFAPolicy(userID uint, required bool) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.UserType != authmodel.UserTypeCompany {
+		return ErrMFANotAllowed
+	}
+	if required && !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	member, err := orgservice.EnsureMembership(s.DB, user.ID)
+	if err != nil {
+		return err
+	}
+	if member.Role != orgmodel.RoleOwner {
+		return ErrMFAPolicyOwnerOnly
+	}
+	if err := s.DB.Model(&member.Organization).Update("mfa_required", required).Error; err != nil {
+		return fmt.Errorf("failed to update MFA policy: %w", err)
+	}
+	return nil
+}
+
+func (s *AuthService) enrolledUser(userID uint) (*authmodel.User, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	return user, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// Each TOTP step and each recovery code can only be used once.
+func (s *AuthService) checkSecondFactor(user *authmodel.User, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		return s.useRecoveryCode(user.ID, recoveryCode)
+	}
+	if user.TOTPSecret == nil {
+		return ErrMFANotEnrolled
+	}
+
+	step, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), mfaSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	// Only move forward: a replayed code (same or older step) finds no row.
+	result := s.DB.Model(&authmodel.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record TOTP use: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *AuthService) useRecoveryCode(userID uint, code string) error {
+	result := s.DB.Model(&authmodel.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores new ones.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&authmodel.MFARecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]authmodel.MFARecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes[i] = code
+		records[i] = authmodel.MFARecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code))}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k3m9q-7xw2p" (50 random bits).
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes the user may type.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
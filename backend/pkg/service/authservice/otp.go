@@ -0,0 +1,233 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// RequestPasswordReset generates a new OTP for the user and emails it.
+// Any OTP that was issued earlier and not yet used is invalidated.  The user
+// is returned whenever the email is registered, even with an error.
+//
+// Every request hashes an OTP, registered email or not, and the email goes
+// out in the background, so the response time doesn't tell who has an
+// account.  Failures to send are only logged.
+func (s *AuthService) RequestPasswordReset(email string) (*authmodel.User, error) {
+	otp, err := generateOTP(otpLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OTP: %w", err)
+	}
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash OTP: %w", err)
+	}
+
+	user, err := s.getUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest authmodel.PasswordResetOTP
+	err = s.DB.Where("user_id = ?", user.ID).Order("created_at DESC").First(&latest).Error
+	if err == nil && time.Since(latest.CreatedAt) < otpResendInterval {
+		return user, ErrOTPRequestTooSoon
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, fmt.Errorf("failed to check previous OTP: %w", err)
+	}
+
+	now := time.Now()
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Supersede outstanding OTPs so only the newest one works.
+		if err := tx.Model(&authmodel.PasswordResetOTP{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&authmodel.PasswordResetOTP{
+			UserID:    user.ID,
+			CodeHash:  string(codeHash),
+			ExpiresAt: now.Add(otpTTL),
+		}).Error
+	})
+	if err != nil {
+		return user, fmt.Errorf("failed to store OTP: %w", err)
+	}
+
+	go func() {
+		if err := s.sendOTPEmail(user.Email, otp); err != nil {
+			log.Printf("failed to send password reset OTP to user %d: %v", user.ID, err)
+		}
+	}()
+	return user, nil
+}
+
+// VerifyOTP checks the provided OTP against the latest OTP issued to the user
+// and marks it as verified.  The OTP still has to be presented to ResetPassword,
+// which consumes it.
+func (s *AuthService) VerifyOTP(email, otp string) (*authmodel.User, error) {
+	user, err := s.getUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.checkOTP(user.ID, otp)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.VerifiedAt == nil {
+		now := time.Now()
+		if err := s.DB.Model(record).Update("verified_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to mark OTP as verified: %w", err)
+		}
+	}
+
+	return user, nil
+}
+
+// ResetPassword updates the user's password using a previously verified OTP
+// and returns the user.
+func (s *AuthService) ResetPassword(req *authmodel.ResetPasswordRequest) (*authmodel.User, error) {
+	user, err := s.getUserByEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.checkOTP(user.ID, req.OTP)
+	if err != nil {
+		return nil, err
+	}
+	if record.VerifiedAt == nil {
+		return nil, ErrOTPNotVerified
+	}
+
+	hashedPassword, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Consume the OTP first; the used_at guard makes concurrent resets lose.
+		result := tx.Model(&authmodel.PasswordResetOTP{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to consume OTP: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrOTPAlreadyUsed
+		}
+
+		if err := tx.Model(user).Update("password", hashedPassword).Error; err != nil {
+			return errors.New("failed to update password")
+		}
+
+		// A new password ends every existing session.
+		if err := tx.Model(&authmodel.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		// Proving ownership of the email also lifts a login lockout.
+		return clearLoginThrottle(tx, user.Email, &user.ID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkOTP loads the latest OTP for the user and validates the code against it.
+// Every check takes one of the OTP's attempts up front, in a conditional
+// update, so concurrent guesses can't get past the limit; a matching code
+// gives its attempt back.  Wrong guesses also count towards a limit per user,
+// so requesting a new OTP doesn't reset the budget.
+func (s *AuthService) checkOTP(userID uint, otp string) (*authmodel.PasswordResetOTP, error) {
+	var record authmodel.PasswordResetOTP
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC").First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOTPNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve OTP: %w", err)
+	}
+
+	if record.UsedAt != nil {
+		return nil, ErrOTPAlreadyUsed
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrOTPExpired
+	}
+
+	var guesses int64
+	if err := s.DB.Model(&authmodel.PasswordResetOTP{}).
+		Where("user_id = ? AND created_at > ?", userID, time.Now().Add(-otpGuessWindow)).
+		Select("COALESCE(SUM(attempts), 0)").Scan(&guesses).Error; err != nil {
+		return nil, fmt.Errorf("failed to count OTP attempts: %w", err)
+	}
+	if guesses >= otpMaxUserGuesses {
+		return nil, ErrOTPTooManyAttempts
+	}
+
+	result := s.DB.Model(&authmodel.PasswordResetOTP{}).
+		Where("id = ? AND attempts < ?", record.ID, otpMaxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to record OTP attempt: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOTPTooManyAttempts
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(record.CodeHash), []byte(otp)) != nil {
+		return nil, ErrOTPMismatch
+	}
+	if err := s.DB.Model(&record).Update("attempts", gorm.Expr("attempts - 1")).Error; err != nil {
+		return nil, fmt.Errorf("failed to record OTP attempt: %w", err)
+	}
+	return &record, nil
+}
+
+func (s *AuthService) getUserByEmail(email string) (*authmodel.User, error) {
+	var user authmodel.User
+	if err := s.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return &user, nil
+}
+
+// generateOTP generates a random numeric OTP.
+func generateOTP(length int) (string, error) {
+	otp := make([]byte, length)
+	for i := 0; i < length; i++ {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(otpChars))))
+		if err != nil {
+			return "", err
+		}
+		otp[i] = otpChars[num.Int64()]
+	}
+	return string(otp), nil
+}
+
+// sendOTPEmail sends the OTP to the user's email address.
+func (s *AuthService) sendOTPEmail(email, otp string) error {
+	if s.Mailer == nil {
+		return fmt.Errorf("no mailer configured")
+	}
+	body := fmt.Sprintf("Your OTP for password reset is: %s\n\n"+
+		"It expires in %d minutes. If you did not request a password reset, you can ignore this email.",
+		otp, int(otpTTL.Minutes()))
+	return s.Mailer.Send(email, "Password Reset OTP", body)
+}
@@ -0,0 +1,161 @@
+package authservice
+
+import (
+	"backend/pkg/mailer"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/testdb"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestService(t *testing.T) (*AuthService, *mailer.MemoryMailer) {
+	t.Helper()
+	m := mailer.NewMemoryMailer()
+	return &AuthService{DB: testdb.New(t), Mailer: m}, m
+}
+
+// createUser stores an applicant with the given email and stored password.
+func createUser(t *testing.T, s *AuthService, email, password string) *authmodel.User {
+	t.Helper()
+	user := &authmodel.User{Name: "Test User", Email: email, Password: password, UserType: authmodel.UserTypeApplicant, EmailVerified: true}
+	if err := s.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// issueOTP stores an OTP for the user directly, bypassing the resend interval.
+func issueOTP(t *testing.T, s *AuthService, userID uint, code string, attempts int) *authmodel.PasswordResetOTP {
+	t.Helper()
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Model(&authmodel.PasswordResetOTP{}).Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	record := &authmodel.PasswordResetOTP{UserID: userID, CodeHash: string(codeHash), ExpiresAt: time.Now().Add(otpTTL), Attempts: attempts}
+	if err := s.DB.Create(record).Error; err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+// mailedOTP returns the OTP in the last email sent to email, waiting for it
+// to go out in the background.
+func mailedOTP(t *testing.T, m *mailer.MemoryMailer, email string) string {
+	t.Helper()
+	mail, ok := m.Last(email)
+	for deadline := time.Now().Add(5 * time.Second); !ok && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		mail, ok = m.Last(email)
+	}
+	if !ok {
+		t.Fatalf("no email was sent to %s", email)
+	}
+	otp := regexp.MustCompile(`\b[0-9]{6}\b`).FindString(mail.Body)
+	if otp == "" {
+		t.Fatalf("email %q has no OTP", mail.Body)
+	}
+	return otp
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	s, m := newTestService(t)
+	user := createUser(t, s, "user@example.com", "old-password")
+
+	if _, err := s.RequestPasswordReset(user.Email); err != nil {
+		t.Fatal(err)
+	}
+	otp := mailedOTP(t, m, user.Email)
+	req := &authmodel.ResetPasswordRequest{Email: user.Email, OTP: otp, NewPassword: "new-password"}
+
+	if _, err := s.ResetPassword(req); !errors.Is(err, ErrOTPNotVerified) {
+		t.Fatalf("ResetPassword() before VerifyOTP error = %v, want %v", err, ErrOTPNotVerified)
+	}
+	if _, err := s.VerifyOTP(user.Email, "abcdef"); !errors.Is(err, ErrOTPMismatch) {
+		t.Fatalf("VerifyOTP() with a wrong code error = %v, want %v", err, ErrOTPMismatch)
+	}
+	if _, err := s.VerifyOTP(user.Email, otp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ResetPassword(req); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored authmodel.User
+	if err := s.DB.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := verifyPassword(stored.Password, "new-password"); !ok {
+		t.Error("the new password doesn't work")
+	}
+	if _, err := s.ResetPassword(req); !errors.Is(err, ErrOTPAlreadyUsed) {
+		t.Errorf("second ResetPassword() error = %v, want %v", err, ErrOTPAlreadyUsed)
+	}
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	s, m := newTestService(t)
+	if _, err := s.RequestPasswordReset("nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("RequestPasswordReset() error = %v, want %v", err, ErrUserNotFound)
+	}
+	if sent := m.Sent(); len(sent) != 0 {
+		t.Errorf("%d emails were sent for an unknown address", len(sent))
+	}
+}
+
+func TestCheckOTPLimitsGuessesPerOTP(t *testing.T) {
+	s, _ := newTestService(t)
+	user := createUser(t, s, "user@example.com", "password")
+	issueOTP(t, s, user.ID, "123456", 0)
+
+	for i := 0; i < otpMaxAttempts; i++ {
+		if _, err := s.VerifyOTP(user.Email, "654321"); !errors.Is(err, ErrOTPMismatch) {
+			t.Fatalf("guess %d error = %v, want %v", i+1, err, ErrOTPMismatch)
+		}
+	}
+	if _, err := s.VerifyOTP(user.Email, "123456"); !errors.Is(err, ErrOTPTooManyAttempts) {
+		t.Errorf("right code after %d wrong guesses error = %v, want %v", otpMaxAttempts, err, ErrOTPTooManyAttempts)
+	}
+}
+
+func TestCheckOTPRightCodeKeepsAttempts(t *testing.T) {
+	s, _ := newTestService(t)
+	user := createUser(t, s, "user@example.com", "password")
+	record := issueOTP(t, s, user.ID, "123456", 0)
+
+	for i := 0; i < otpMaxAttempts+1; i++ {
+		if _, err := s.VerifyOTP(user.Email, "123456"); err != nil {
+			t.Fatalf("check %d: %v", i+1, err)
+		}
+	}
+	if err := s.DB.First(record, record.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.Attempts != 0 {
+		t.Errorf("attempts = %d after right codes, want 0", record.Attempts)
+	}
+}
+
+func TestCheckOTPLimitsGuessesAcrossOTPs(t *testing.T) {
+	s, _ := newTestService(t)
+	user := createUser(t, s, "user@example.com", "password")
+	// Earlier OTPs used up most of the user's budget; a new one doesn't
+	// bring it back.
+	issueOTP(t, s, user.ID, "111111", otpMaxAttempts)
+	issueOTP(t, s, user.ID, "222222", otpMaxUserGuesses-otpMaxAttempts-1)
+	issueOTP(t, s, user.ID, "123456", 0)
+
+	if _, err := s.VerifyOTP(user.Email, "654321"); !errors.Is(err, ErrOTPMismatch) {
+		t.Fatalf("last guess error = %v, want %v", err, ErrOTPMismatch)
+	}
+	if _, err := s.VerifyOTP(user.Email, "123456"); !errors.Is(err, ErrOTPTooManyAttempts) {
+		t.Errorf("right code over the user's budget error = %v, want %v", err, ErrOTPTooManyAttempts)
+	}
+}
@@ -0,0 +1,68 @@
+package authservice
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrPasswordRequired = errors.New("password is required")
+var ErrPasswordTooLong = errors.New("password must be at most 72 bytes")
+
+// passwordHashCost is the bcrypt work factor used for new hashes.
+// Stored hashes with a lower cost are upgraded on the next successful login.
+const passwordHashCost = bcrypt.DefaultCost
+
+// dummyPasswordHash is a bcrypt hash of no one's password, at
+// passwordHashCost.  Logins with an unknown email are checked against it, so
+// they take as long as logins with a wrong password.
+const dummyPasswordHash = "$2a$10$5oA7p2nwOTYfLbF2fNCkE.RWcqgeGTDo8NC7mFZbvlSoLyEQsMAee"
+
+// hashPassword returns the bcrypt hash of a plaintext password.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", ErrPasswordRequired
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return "", ErrPasswordTooLong
+		}
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// isPasswordHash reports whether a stored password is a bcrypt hash
+// (as opposed to a legacy plaintext value written before hashing existed).
+func isPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// verifyPassword checks a plaintext password against the stored value.
+// needsRehash is true when the password matched but the stored value is a
+// legacy plaintext password or a hash with an outdated cost.
+func verifyPassword(stored, password string) (ok bool, needsRehash bool) {
+	if stored == "" || password == "" {
+		return false, false
+	}
+
+	if !isPasswordHash(stored) {
+		// Legacy plaintext row: compare in constant time, then upgrade.
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)); err != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(stored))
+	return true, err != nil || cost < passwordHashCost
+}
@@ -0,0 +1,116 @@
+package authservice
+
+import (
+	"backend/pkg/keyring"
+	"backend/pkg/model/authmodel"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	current, err := bcrypt.GenerateFromPassword([]byte("secret"), passwordHashCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name            string
+		stored          string
+		password        string
+		wantOK, wantNew bool
+	}{
+		{"bcrypt match", string(current), "secret", true, false},
+		{"bcrypt mismatch", string(current), "wrong", false, false},
+		{"weaker bcrypt match", string(weak), "secret", true, true},
+		{"weaker bcrypt mismatch", string(weak), "wrong", false, false},
+		{"legacy plaintext match", "secret", "secret", true, true},
+		{"legacy plaintext mismatch", "secret", "wrong", false, false},
+		{"legacy plaintext prefix", "secret", "secre", false, false},
+		{"hash typed as password", string(current), string(current), false, false},
+		{"empty stored password", "", "", false, false},
+		{"empty password", "secret", "", false, false},
+	}
+	for _, tt := range tests {
+		ok, needsRehash := verifyPassword(tt.stored, tt.password)
+		if ok != tt.wantOK || needsRehash != tt.wantNew {
+			t.Errorf("%s: verifyPassword() = %v, %v; want %v, %v", tt.name, ok, needsRehash, tt.wantOK, tt.wantNew)
+		}
+	}
+}
+
+// Logins with an unknown email only take as long as wrong passwords if the
+// dummy hash costs as much as real ones.
+func TestDummyPasswordHashCost(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != passwordHashCost {
+		t.Errorf("dummy hash cost = %d, want %d", cost, passwordHashCost)
+	}
+}
+
+func newLoginService(t *testing.T) *AuthService {
+	t.Helper()
+	s, _ := newTestService(t)
+	key := keyring.NewHMACKey([]byte("login-test-secret"))
+	kr, err := keyring.New(key.ID, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Keyring = kr
+	return s
+}
+
+func storedPassword(t *testing.T, s *AuthService, userID uint) string {
+	t.Helper()
+	var user authmodel.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user.Password
+}
+
+func TestLoginUpgradesLegacyPassword(t *testing.T) {
+	s := newLoginService(t)
+	user := createUser(t, s, "user@example.com", "secret")
+
+	if _, _, err := s.Login(user.Email, "wrong", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() with a wrong password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if got := storedPassword(t, s, user.ID); got != "secret" {
+		t.Fatalf("a failed login changed the stored password to %q", got)
+	}
+
+	tokens, _, err := s.Login(user.Email, "secret", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken == "" {
+		t.Error("no access token")
+	}
+	stored := storedPassword(t, s, user.ID)
+	if !isPasswordHash(stored) {
+		t.Fatalf("stored password = %q, want a bcrypt hash", stored)
+	}
+	if ok, needsRehash := verifyPassword(stored, "secret"); !ok || needsRehash {
+		t.Errorf("verifyPassword() of the upgraded hash = %v, %v; want true, false", ok, needsRehash)
+	}
+
+	// The upgraded account keeps working.
+	if _, _, err := s.Login(user.Email, "secret", "127.0.0.1"); err != nil {
+		t.Errorf("Login() after the upgrade: %v", err)
+	}
+}
+
+func TestLoginUnknownEmail(t *testing.T) {
+	s := newLoginService(t)
+	if _, _, err := s.Login("nobody@example.com", "secret", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
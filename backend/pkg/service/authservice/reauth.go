@@ -0,0 +1,42 @@
+package authservice
+
+import (
+	"errors"
+)
+
+var ErrIdentityNotConfirmed = errors.New("password or Firebase ID token is incorrect")
+
+// ConfirmIdentity re-authenticates a signed-in user before a sensitive action
+// such as deleting the account.  Users who sign in with Firebase, and so have
+// no password of their own, can present a fresh Firebase ID token instead.
+// Wrong passwords count towards the login throttle like failed logins.
+func (s *AuthService) ConfirmIdentity(userID uint, password, firebaseIDToken, ip string) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := s.checkLoginThrottle(accountThrottleKey(user.Email), ipThrottleKey(ip)); err != nil {
+		return err
+	}
+
+	if password != "" {
+		if ok, _ := verifyPassword(user.Password, password); ok {
+			return nil
+		}
+		if err := s.recordLoginFailure(user.Email, ip, &user.ID); err != nil {
+			return err
+		}
+		return ErrIdentityNotConfirmed
+	}
+
+	if firebaseIDToken != "" && s.Firebase != nil && user.FirebaseUID != nil {
+		token, err := s.Firebase.VerifyIDToken(firebaseIDToken)
+		if err == nil && token.UID == *user.FirebaseUID {
+			return nil
+		}
+	}
+	return ErrIdentityNotConfirmed
+}
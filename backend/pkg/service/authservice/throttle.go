@@ -0,0 +1,196 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Failed logins are counted per account and per client IP.  Once a key goes
+// over its allowance it is locked, and every further failure doubles the
+// lockout up to the maximum.  Counters reset after a quiet period.
+const (
+	accountFailureAllowance = 5
+	ipFailureAllowance      = 20
+	lockoutBase             = time.Minute
+	lockoutMax              = time.Hour
+	failureWindow           = 15 * time.Minute // Failures older than this are forgotten
+)
+
+// LockedError is returned by Login while the account or client is locked out.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+func accountThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginThrottle returns a LockedError when any of the keys is locked.
+func (s *AuthService) checkLoginThrottle(keys ...string) error {
+	var throttles []authmodel.LoginThrottle
+	now := time.Now()
+	if err := s.DB.Where("`key` IN ? AND locked_until > ?", keys, now).Find(&throttles).Error; err != nil {
+		return fmt.Errorf("failed to check login throttle: %w", err)
+	}
+
+	var retryAfter time.Duration
+	for _, t := range throttles {
+		if wait := t.LockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against the account and the IP.
+// It returns a LockedError if this failure locked either of them.
+func (s *AuthService) recordLoginFailure(email, ip string, userID *uint) error {
+	var locked *LockedError
+	keys := []struct {
+		key       string
+		allowance int
+		userID    *uint
+	}{
+		{accountThrottleKey(email), accountFailureAllowance, userID},
+		{ipThrottleKey(ip), ipFailureAllowance, nil},
+	}
+	for _, k := range keys {
+		lockedUntil, err := s.incrementThrottle(k.key, k.allowance, ip, k.userID)
+		if err != nil {
+			return err
+		}
+		if lockedUntil != nil {
+			wait := time.Until(*lockedUntil)
+			if locked == nil || wait > locked.RetryAfter {
+				locked = &LockedError{RetryAfter: wait}
+			}
+		}
+	}
+	if locked != nil {
+		return locked
+	}
+	return nil
+}
+
+// incrementThrottle adds a failure to the key and locks it once the allowance
+// is used up.  It returns the new lock expiry if the key got locked.
+func (s *AuthService) incrementThrottle(key string, allowance int, ip string, userID *uint) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists, then lock it so concurrent failures for the
+		// same key are counted one after the other.
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&authmodel.LoginThrottle{Key: key}).Error; err != nil {
+			return err
+		}
+		var throttle authmodel.LoginThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("`key` = ?", key).First(&throttle).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if throttle.LastFailureAt != nil && now.Sub(*throttle.LastFailureAt) > failureWindow &&
+			(throttle.LockedUntil == nil || now.After(*throttle.LockedUntil)) {
+			throttle.Failures = 0
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = &now
+
+		if over := throttle.Failures - allowance; over >= 0 {
+			until := now.Add(lockoutDuration(over))
+			throttle.LockedUntil = &until
+			lockedUntil = &until
+		}
+		if err := tx.Save(&throttle).Error; err != nil {
+			return err
+		}
+
+		if lockedUntil == nil {
+			return nil
+		}
+		return tx.Create(&authmodel.LockoutEvent{
+			Key:         key,
+			Event:       authmodel.LockoutEventLocked,
+			UserID:      userID,
+			IP:          ip,
+			Failures:    throttle.Failures,
+			LockedUntil: lockedUntil,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return lockedUntil, nil
+}
+
+// lockoutDuration doubles the lockout for every failure over the allowance.
+func lockoutDuration(over int) time.Duration {
+	d := lockoutBase
+	for i := 0; i < over && d < lockoutMax; i++ {
+		d *= 2
+	}
+	if d > lockoutMax {
+		d = lockoutMax
+	}
+	return d
+}
+
+// clearLoginThrottle forgets the failures of an account, e.g. after a
+// successful login.  A lock that was still active is recorded as unlocked.
+func clearLoginThrottle(db *gorm.DB, email string, userID *uint, ip string) error {
+	key := accountThrottleKey(email)
+	var throttle authmodel.LoginThrottle
+	err := db.Where("`key` = ?", key).First(&throttle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to load login throttle: %w", err)
+	}
+
+	if throttle.LockedUntil != nil && time.Now().Before(*throttle.LockedUntil) {
+		if err := db.Create(&authmodel.LockoutEvent{
+			Key:      key,
+			Event:    authmodel.LockoutEventUnlocked,
+			UserID:   userID,
+			IP:       ip,
+			Failures: throttle.Failures,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record unlock: %w", err)
+		}
+	}
+	if err := db.Delete(&throttle).Error; err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+	return nil
+}
+
+// PurgeLoginHistory deletes the throttle and lockout history of an account,
+// which records its email address.  It is used when the account is deleted.
+func PurgeLoginHistory(tx *gorm.DB, email string, userID uint) error {
+	key := accountThrottleKey(email)
+	if err := tx.Where("`key` = ?", key).Delete(&authmodel.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("failed to delete login throttle: %w", err)
+	}
+	if err := tx.Where("`key` = ? OR user_id = ?", key, userID).Delete(&authmodel.LockoutEvent{}).Error; err != nil {
+		return fmt.Errorf("failed to delete lockout events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// RefreshTokens rotates a refresh token: the presented token is revoked and a
+// new access/refresh pair in the same family is returned.  Presenting a token
+// that was already rotated revokes the whole family, since it means the token
+// has leaked.
+func (s *AuthService) RefreshTokens(refreshToken string) (*authmodel.TokenPair, *authmodel.User, error) {
+	var current authmodel.RefreshToken
+	err := s.DB.Where("token_hash = ?", hashToken(refreshToken)).First(&current).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, fmt.Errorf("failed to retrieve refresh token: %w", err)
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedByID != nil {
+			return nil, nil, s.handleRefreshTokenReuse(&current)
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.GetUserByID(current.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var tokens *authmodel.TokenPair
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// The revoked_at guard makes sure two concurrent refreshes with the same
+		// token can't both succeed; the loser is treated as reuse.
+		result := tx.Model(&authmodel.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var next *authmodel.RefreshToken
+		var err error
+		tokens, next, err = s.issueTokensWithRecord(tx, user, current.FamilyID)
+		if err != nil {
+			return err
+		}
+		// Remember the successor: a revoked token with a successor that shows
+		// up again has been replayed.
+		return tx.Model(&current).Update("replaced_by_id", next.ID).Error
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, nil, s.handleRefreshTokenReuse(&current)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, user, nil
+}
+
+// Logout revokes the session (refresh token family) the token belongs to.
+// Unknown tokens are ignored so logout is idempotent.
+func (s *AuthService) Logout(refreshToken string) error {
+	var current authmodel.RefreshToken
+	err := s.DB.Where("token_hash = ?", hashToken(refreshToken)).First(&current).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to retrieve refresh token: %w", err)
+	}
+	return s.revokeFamily(current.FamilyID)
+}
+
+// LogoutAll revokes every session of the user.
+func (s *AuthService) LogoutAll(userID uint) error {
+	err := s.DB.Model(&authmodel.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// IsSessionActive reports whether the session an access token was issued for
+// still has a live refresh token.  It is used by the auth middleware so that
+// logging out also invalidates outstanding access tokens.
+func (s *AuthService) IsSessionActive(userID uint, sessionID string) (bool, error) {
+	var count int64
+	err := s.DB.Model(&authmodel.RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, sessionID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *AuthService) handleRefreshTokenReuse(token *authmodel.RefreshToken) error {
+	log.Printf("refresh token reuse detected for user %d, revoking session %s", token.UserID, token.FamilyID)
+	if err := s.revokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *AuthService) revokeFamily(familyID string) error {
+	err := s.DB.Model(&authmodel.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// issueTokens creates a refresh token in the given family (a new family when
+// familyID is empty) and a matching access token.
+func (s *AuthService) issueTokens(db *gorm.DB, user *authmodel.User, familyID string) (*authmodel.TokenPair, error) {
+	tokens, _, err := s.issueTokensWithRecord(db, user, familyID)
+	return tokens, err
+}
+
+func (s *AuthService) issueTokensWithRecord(db *gorm.DB, user *authmodel.User, familyID string) (*authmodel.TokenPair, *authmodel.RefreshToken, error) {
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	record := authmodel.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	accessToken, err := s.generateJWTToken(user, familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &authmodel.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}, &record, nil
+}
+
+func (s *AuthService) generateJWTToken(user *authmodel.User, sessionID string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id":        user.ID, // Include user ID
+		"name":           user.Name,
+		"email":          user.Email,
+		"phone":          user.Phone,
+		"user_type":      user.UserType,
+		"company_name":   user.CompanyName, // Handle potential nil pointer
+		"email_verified": user.EmailVerified,
+		"sid":            sessionID, // Refresh token family, checked by the middleware
+		"iat":            now.Unix(),
+		"exp":            now.Add(accessTokenTTL).Unix(),
+	}
+
+	// Signed with the keyring's active key; the kid header tells verifiers which one.
+	return s.Keyring.Sign(claims)
+}
+
+// randomToken returns a random, URL-safe opaque token.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of an opaque token.  Refresh tokens are
+// high-entropy, so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,169 @@
+package authservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidVerificationToken   = errors.New("invalid or expired verification link")
+	ErrEmailAlreadyVerified       = errors.New("email address is already verified")
+	ErrVerificationRequestTooSoon = errors.New("a verification email was sent too recently")
+)
+
+const (
+	verificationTokenTTL       = 24 * time.Hour
+	verificationResendInterval = time.Minute // Minimum time between two verification emails
+
+	// PurposeEmailVerification marks tokens that may only be used to verify an
+	// email address.  Access tokens have no purpose claim.
+	PurposeEmailVerification = "email_verification"
+)
+
+// SendVerificationEmail emails the user a link that verifies their address.
+// Resends are throttled to one per verificationResendInterval.
+func (s *AuthService) SendVerificationEmail(userID uint) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	now := time.Now()
+	// The conditional update claims the send slot, so concurrent resends
+	// can't both get through.
+	result := s.DB.Model(&authmodel.User{}).
+		Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)", user.ID, now.Add(-verificationResendInterval)).
+		Update("verification_sent_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record verification email: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrVerificationRequestTooSoon
+	}
+
+	token, err := s.generateVerificationToken(user)
+	if err != nil {
+		return err
+	}
+	return s.sendVerificationEmail(user.Email, token)
+}
+
+// VerifyEmail marks the user's email address as verified.  The token is bound
+// to the address it was issued for, so changing the email invalidates it.
+func (s *AuthService) VerifyEmail(tokenString string) (*authmodel.User, error) {
+	token, err := s.Keyring.Parse(tokenString)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidVerificationToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != PurposeEmailVerification {
+		return nil, ErrInvalidVerificationToken
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+	email, _ := claims["email"].(string)
+
+	user, err := s.GetUserByID(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !strings.EqualFold(user.Email, email) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if user.EmailVerified {
+		return user, nil // Following the link twice is harmless
+	}
+
+	now := time.Now()
+	err = s.DB.Model(user).Updates(map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": now,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// VerifyExistingEmails marks every user as verified.  It runs once, when
+// email verification is introduced: accounts from before then were never sent
+// a link and would otherwise be locked out by the verified-email policy.
+func (s *AuthService) VerifyExistingEmails() (int64, error) {
+	result := s.DB.Model(&authmodel.User{}).
+		Where("email_verified = ?", false).
+		Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to verify existing emails: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// IsEmailVerified reports whether the user has verified their email address.
+// It is used by the middleware for tokens issued before the verification.
+func (s *AuthService) IsEmailVerified(userID uint) (bool, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.EmailVerified, nil
+}
+
+func (s *AuthService) generateVerificationToken(user *authmodel.User) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"purpose": PurposeEmailVerification,
+		"user_id": user.ID,
+		"email":   user.Email,
+		"iat":     now.Unix(),
+		"exp":     now.Add(verificationTokenTTL).Unix(),
+	}
+	return s.Keyring.Sign(claims)
+}
+
+// verificationLink builds the link to GET /auth/verify-email.  APP_BASE_URL
+// is the public address of the API, e.g. https://api.example.com.
+func verificationLink(token string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		base = "http://localhost:" + port
+	}
+	return strings.TrimRight(base, "/") + "/auth/verify-email?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail sends the verification link to the user's email address.
+func (s *AuthService) sendVerificationEmail(email, token string) error {
+	if s.Mailer == nil {
+		return fmt.Errorf("no mailer configured")
+	}
+	body := fmt.Sprintf("Please verify your email address by opening the link below:\n\n%s\n\n"+
+		"The link expires in %d hours. If you did not create an account, you can ignore this email.",
+		verificationLink(token), int(verificationTokenTTL.Hours()))
+	return s.Mailer.Send(email, "Verify your email address", body)
+}
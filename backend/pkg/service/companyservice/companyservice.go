@@ -0,0 +1,219 @@
+package companyservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/service/jobservice"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProfileNotFound     = errors.New("company profile not found")
+	ErrProfileExists       = errors.New("company profile already exists")
+	ErrNotCompany          = errors.New("only company accounts have a company profile")
+	ErrCompanyNameRequired = errors.New("company_name is required")
+)
+
+// ICompanyService interface
+type ICompanyService interface {
+	GetProfileByUserID(userID uint) (*authmodel.CompanyProfile, error)
+	CreateProfile(userID uint, req *authmodel.CompanyProfileRequest) (*authmodel.CompanyProfile, error)
+	UpdateProfile(userID uint, req *authmodel.CompanyProfileRequest) (*authmodel.CompanyProfile, error)
+	DeleteProfile(userID uint) error
+	GetPublicProfile(id uint) (*authmodel.CompanyProfile, []jobmodel.JobPost, error)
+	BackfillProfiles() (int, error)
+}
+
+type CompanyService struct {
+	DB         *gorm.DB
+	JobService jobservice.IJobService
+}
+
+// NewCompanyService creates a new CompanyService.
+func NewCompanyService(db *gorm.DB, jobService jobservice.IJobService) *CompanyService {
+	return &CompanyService{DB: db, JobService: jobService}
+}
+
+// GetProfileByUserID returns the profile of a company user.
+func (s *CompanyService) GetProfileByUserID(userID uint) (*authmodel.CompanyProfile, error) {
+	var profile authmodel.CompanyProfile
+	err := s.DB.Where("user_id = ?", userID).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve company profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// CreateProfile creates the profile of a company user that doesn't have one.
+// The company name defaults to the one given at registration.
+func (s *CompanyService) CreateProfile(userID uint, req *authmodel.CompanyProfileRequest) (*authmodel.CompanyProfile, error) {
+	user, err := s.companyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetProfileByUserID(userID); err == nil {
+		return nil, ErrProfileExists
+	} else if !errors.Is(err, ErrProfileNotFound) {
+		return nil, err
+	}
+
+	profile := authmodel.CompanyProfile{UserID: user.ID}
+	if user.CompanyName != nil {
+		profile.CompanyName = *user.CompanyName
+	}
+	applyProfileRequest(&profile, req)
+	if profile.CompanyName == "" {
+		return nil, ErrCompanyNameRequired
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&profile).Error; err != nil {
+			return fmt.Errorf("failed to create company profile: %w", err)
+		}
+		return syncCompanyName(tx, user, profile.CompanyName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.reindexJobPosts(userID)
+	return &profile, nil
+}
+
+// UpdateProfile updates the profile of a company user.  A new company name is
+// also written to the user, which the JWT and older clients still read.
+func (s *CompanyService) UpdateProfile(userID uint, req *authmodel.CompanyProfileRequest) (*authmodel.CompanyProfile, error) {
+	user, err := s.companyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.GetProfileByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyProfileRequest(profile, req)
+	if profile.CompanyName == "" {
+		return nil, ErrCompanyNameRequired
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(profile).Error; err != nil {
+			return fmt.Errorf("failed to update company profile: %w", err)
+		}
+		return syncCompanyName(tx, user, profile.CompanyName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.reindexJobPosts(userID)
+	return profile, nil
+}
+
+// DeleteProfile deletes the profile of a company user.  Job listings fall back
+// to the company name on the user.
+func (s *CompanyService) DeleteProfile(userID uint) error {
+	profile, err := s.GetProfileByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.DB.Delete(profile).Error; err != nil {
+		return fmt.Errorf("failed to delete company profile: %w", err)
+	}
+	s.reindexJobPosts(userID)
+	return nil
+}
+
+// reindexJobPosts updates the company name in the search index.  The profile
+// change has already been saved, so a failure is only logged.
+func (s *CompanyService) reindexJobPosts(userID uint) {
+	if err := s.JobService.ReindexCompanyJobPosts(userID); err != nil {
+		log.Printf("failed to reindex job posts of user %d: %v", userID, err)
+	}
+}
+
+// GetPublicProfile returns a company profile by its ID together with the
+// company's open job posts.
+func (s *CompanyService) GetPublicProfile(id uint) (*authmodel.CompanyProfile, []jobmodel.JobPost, error) {
+	var profile authmodel.CompanyProfile
+	err := s.DB.First(&profile, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrProfileNotFound
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve company profile: %w", err)
+	}
+
+	jobPosts, err := s.JobService.ListOpenJobPostsByUserID(profile.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	return &profile, jobPosts, nil
+}
+
+// BackfillProfiles creates the missing profile of every company user that
+// registered before profiles were created automatically.  It returns how many
+// profiles were created.
+func (s *CompanyService) BackfillProfiles() (int, error) {
+	var users []authmodel.User
+	err := s.DB.Where("user_type = ? AND id NOT IN (?)", authmodel.UserTypeCompany,
+		s.DB.Model(&authmodel.CompanyProfile{}).Select("user_id")).
+		Find(&users).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find companies without a profile: %w", err)
+	}
+
+	created := 0
+	for _, user := range users {
+		profile := authmodel.CompanyProfile{UserID: user.ID, CompanyName: user.Name}
+		if user.CompanyName != nil && *user.CompanyName != "" {
+			profile.CompanyName = *user.CompanyName
+		}
+		if err := s.DB.Create(&profile).Error; err != nil {
+			return created, fmt.Errorf("failed to create company profile for user %d: %w", user.ID, err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+func (s *CompanyService) companyUser(userID uint) (*authmodel.User, error) {
+	var user authmodel.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user.UserType != authmodel.UserTypeCompany {
+		return nil, ErrNotCompany
+	}
+	return &user, nil
+}
+
+func applyProfileRequest(profile *authmodel.CompanyProfile, req *authmodel.CompanyProfileRequest) {
+	if req.CompanyName != nil {
+		profile.CompanyName = strings.TrimSpace(*req.CompanyName)
+	}
+	if req.Description != nil {
+		profile.Description = *req.Description
+	}
+	if req.Logo != nil {
+		profile.Logo = *req.Logo
+	}
+}
+
+func syncCompanyName(tx *gorm.DB, user *authmodel.User, name string) error {
+	if user.CompanyName != nil && *user.CompanyName == name {
+		return nil
+	}
+	if err := tx.Model(user).Update("company_name", name).Error; err != nil {
+		return fmt.Errorf("failed to update company name: %w", err)
+	}
+	return nil
+}
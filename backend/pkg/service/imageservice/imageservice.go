@@ -0,0 +1,286 @@
+package imageservice
+
+import (
+	"backend/pkg/imaging"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/storage"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImageTooLarge     = errors.New("image must be at most 3 MB")
+	ErrUnsupportedImage  = imaging.ErrUnsupportedFormat
+	ErrImageDimensions   = imaging.ErrTooManyPixels
+	ErrImageNotFound     = errors.New("image not found")
+	ErrInvalidImageSize  = errors.New("size must be small, medium or large")
+	ErrNotCompany        = errors.New("only company accounts have a logo")
+	ErrCompanyNotFound   = errors.New("company profile not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidImageInput = errors.New("image could not be read")
+)
+
+// MaxImageSize is the largest upload accepted, in bytes.
+const MaxImageSize = 3 << 20
+
+// DefaultSize is served when no size is requested.
+const DefaultSize = "medium"
+
+// Sizes are the standard sizes (in pixels, longest side) every upload is
+// scaled to.
+var Sizes = map[string]int{
+	"small":  64,
+	"medium": 256,
+	"large":  512,
+}
+
+// kind describes how an image is processed and stored.
+type kind struct {
+	dir         string
+	ext         string
+	contentType string
+	resize      func(image.Image, int) *image.RGBA
+	encode      func(image.Image) ([]byte, error)
+}
+
+var (
+	// Avatars are cropped to squares and stored as JPEG.
+	avatarKind = kind{dir: "avatars", ext: ".jpg", contentType: "image/jpeg", resize: imaging.Square, encode: imaging.EncodeJPEG}
+	// Logos keep their aspect ratio and transparency.
+	logoKind = kind{dir: "logos", ext: ".png", contentType: "image/png", resize: imaging.Fit, encode: imaging.EncodePNG}
+)
+
+// IImageService interface
+type IImageService interface {
+	SetAvatar(userID uint, data []byte) (string, error)
+	DeleteAvatar(userID uint) error
+	OpenAvatar(userID uint, size string) (io.ReadCloser, string, error)
+	SetCompanyLogo(userID uint, data []byte) (*authmodel.CompanyProfile, error)
+	DeleteCompanyLogo(userID uint) error
+	OpenCompanyLogo(profileID uint, size string) (io.ReadCloser, string, error)
+}
+
+type ImageService struct {
+	DB      *gorm.DB
+	Storage storage.IStorage
+}
+
+// NewImageService creates a new ImageService.
+func NewImageService(db *gorm.DB, store storage.IStorage) *ImageService {
+	return &ImageService{DB: db, Storage: store}
+}
+
+// SetAvatar stores a new avatar for the user, replacing the previous one, and
+// returns the URL it is served from.
+func (s *ImageService) SetAvatar(userID uint, data []byte) (string, error) {
+	var user authmodel.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to retrieve user: %w", err)
+	}
+
+	key, version, err := s.store(avatarKind, userID, data)
+	if err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("/api/users/%d/avatar?v=%s", userID, version)
+	err = s.DB.Model(&user).Updates(map[string]interface{}{
+		"profile_image":     url,
+		"profile_image_key": key,
+	}).Error
+	if err != nil {
+		s.remove(avatarKind, key)
+		return "", fmt.Errorf("failed to update profile image: %w", err)
+	}
+	if user.ProfileImageKey != nil {
+		s.remove(avatarKind, *user.ProfileImageKey)
+	}
+	return url, nil
+}
+
+// DeleteAvatar removes the user's avatar.
+func (s *ImageService) DeleteAvatar(userID uint) error {
+	var user authmodel.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user.ProfileImageKey == nil {
+		return ErrImageNotFound
+	}
+
+	err := s.DB.Model(&user).Updates(map[string]interface{}{
+		"profile_image":     nil,
+		"profile_image_key": nil,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update profile image: %w", err)
+	}
+	s.remove(avatarKind, *user.ProfileImageKey)
+	return nil
+}
+
+// OpenAvatar opens the user's avatar in the given size.  It returns the
+// content type along with the file.
+func (s *ImageService) OpenAvatar(userID uint, size string) (io.ReadCloser, string, error) {
+	var user authmodel.User
+	err := s.DB.Select("id", "profile_image_key").First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrImageNotFound
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user.ProfileImageKey == nil {
+		return nil, "", ErrImageNotFound
+	}
+	return s.open(avatarKind, *user.ProfileImageKey, size)
+}
+
+// SetCompanyLogo stores a new logo for the company user's profile, replacing
+// the previous one.
+func (s *ImageService) SetCompanyLogo(userID uint, data []byte) (*authmodel.CompanyProfile, error) {
+	profile, err := s.companyProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, version, err := s.store(logoKind, profile.ID, data)
+	if err != nil {
+		return nil, err
+	}
+	oldKey := profile.LogoKey
+	profile.Logo = fmt.Sprintf("/api/companies/%d/logo?v=%s", profile.ID, version)
+	profile.LogoKey = &key
+	if err := s.DB.Model(profile).Updates(map[string]interface{}{"logo": profile.Logo, "logo_key": key}).Error; err != nil {
+		s.remove(logoKind, key)
+		return nil, fmt.Errorf("failed to update logo: %w", err)
+	}
+	if oldKey != nil {
+		s.remove(logoKind, *oldKey)
+	}
+	return profile, nil
+}
+
+// DeleteCompanyLogo removes the uploaded logo of the company user's profile.
+func (s *ImageService) DeleteCompanyLogo(userID uint) error {
+	profile, err := s.companyProfile(userID)
+	if err != nil {
+		return err
+	}
+	if profile.LogoKey == nil {
+		return ErrImageNotFound
+	}
+
+	if err := s.DB.Model(profile).Updates(map[string]interface{}{"logo": "", "logo_key": nil}).Error; err != nil {
+		return fmt.Errorf("failed to update logo: %w", err)
+	}
+	s.remove(logoKind, *profile.LogoKey)
+	return nil
+}
+
+// OpenCompanyLogo opens the uploaded logo of a company profile in the given
+// size.  It returns the content type along with the file.
+func (s *ImageService) OpenCompanyLogo(profileID uint, size string) (io.ReadCloser, string, error) {
+	var profile authmodel.CompanyProfile
+	err := s.DB.First(&profile, profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrImageNotFound
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to retrieve company profile: %w", err)
+	}
+	if profile.LogoKey == nil {
+		return nil, "", ErrImageNotFound
+	}
+	return s.open(logoKind, *profile.LogoKey, size)
+}
+
+func (s *ImageService) companyProfile(userID uint) (*authmodel.CompanyProfile, error) {
+	var user authmodel.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user.UserType != authmodel.UserTypeCompany {
+		return nil, ErrNotCompany
+	}
+
+	var profile authmodel.CompanyProfile
+	err := s.DB.Where("user_id = ?", userID).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCompanyNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve company profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// store validates data, scales it to every standard size and saves the
+// results.  It returns the base key of the stored files and a short version
+// string for cache busting.
+func (s *ImageService) store(k kind, ownerID uint, data []byte) (string, string, error) {
+	if len(data) > MaxImageSize {
+		return "", "", ErrImageTooLarge
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooManyPixels) {
+			return "", "", err
+		}
+		return "", "", ErrInvalidImageInput
+	}
+
+	version := strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	key := fmt.Sprintf("%s/%d/%s", k.dir, ownerID, version)
+	for name, size := range Sizes {
+		encoded, err := k.encode(k.resize(img, size))
+		if err != nil {
+			s.remove(k, key)
+			return "", "", err
+		}
+		if err := s.Storage.Save(key+"-"+name+k.ext, encoded); err != nil {
+			s.remove(k, key)
+			return "", "", err
+		}
+	}
+	return key, version, nil
+}
+
+func (s *ImageService) open(k kind, key, size string) (io.ReadCloser, string, error) {
+	if size == "" {
+		size = DefaultSize
+	}
+	if _, ok := Sizes[size]; !ok {
+		return nil, "", ErrInvalidImageSize
+	}
+	file, err := s.Storage.Open(key + "-" + size + k.ext)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrImageNotFound
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to open image: %w", err)
+	}
+	return file, k.contentType, nil
+}
+
+// remove deletes every size of a stored image.  Failures are only logged: an
+// orphaned file is harmless.
+func (s *ImageService) remove(k kind, key string) {
+	for name := range Sizes {
+		if err := s.Storage.Delete(key + "-" + name + k.ext); err != nil {
+			log.Printf("failed to delete image %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package jobservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/search"
+	"backend/pkg/service/orgservice"
+	"backend/pkg/testdb"
+	"errors"
+	"testing"
+)
+
+// accessFixture is a job post of organization A with one application, and
+// users inside and outside of A.
+type accessFixture struct {
+	s           *JobService
+	jobPost     *jobmodel.JobPost
+	application *jobmodel.JobApplication
+
+	owner     uint // Owner of A
+	viewer    uint // Viewer in A
+	outsider  uint // Owner of organization B
+	applicant uint // Applied to the post
+	bystander uint // Another applicant
+}
+
+func newAccessFixture(t *testing.T) *accessFixture {
+	t.Helper()
+	db := testdb.New(t)
+	f := &accessFixture{s: NewJobService(db, nil, nil, nil, search.NewMemoryIndex())}
+
+	createUser := func(email, userType string) *authmodel.User {
+		user := &authmodel.User{Name: email, Email: email, Password: "x", UserType: userType, EmailVerified: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	owner := createUser("owner@a.example.com", authmodel.UserTypeCompany)
+	orgA, err := orgservice.CreatePersonalOrganization(db, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewer := createUser("viewer@a.example.com", authmodel.UserTypeCompany)
+	if err := db.Create(&orgmodel.OrganizationMember{OrganizationID: orgA.OrganizationID, UserID: viewer.ID, Role: orgmodel.RoleViewer}).Error; err != nil {
+		t.Fatal(err)
+	}
+	outsider := createUser("owner@b.example.com", authmodel.UserTypeCompany)
+	if _, err := orgservice.CreatePersonalOrganization(db, outsider); err != nil {
+		t.Fatal(err)
+	}
+	applicant := createUser("applicant@example.com", authmodel.UserTypeApplicant)
+	bystander := createUser("bystander@example.com", authmodel.UserTypeApplicant)
+
+	f.jobPost = &jobmodel.JobPost{UserID: owner.ID, OrganizationID: &orgA.OrganizationID, Title: "Go Developer", Quantity: 2, State: jobmodel.JobPostStateOpen, Status: true}
+	if err := db.Create(f.jobPost).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.application = &jobmodel.JobApplication{JobID: f.jobPost.ID, UserID: applicant.ID, Status: jobmodel.JobApplicationStatusPending}
+	if err := db.Create(f.application).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.owner, f.viewer, f.outsider, f.applicant, f.bystander = owner.ID, viewer.ID, outsider.ID, applicant.ID, bystander.ID
+	return f
+}
+
+func TestAuthorizeJobPostAccess(t *testing.T) {
+	f := newAccessFixture(t)
+	tests := []struct {
+		name   string
+		userID uint
+		write  bool
+		want   error
+	}{
+		{"owner reads", f.owner, false, nil},
+		{"owner writes", f.owner, true, nil},
+		{"viewer reads", f.viewer, false, nil},
+		{"viewer writes", f.viewer, true, ErrUnauthorized},
+		{"other organization reads", f.outsider, false, ErrUnauthorized},
+		{"other organization writes", f.outsider, true, ErrUnauthorized},
+		{"applicant writes", f.applicant, true, ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		if _, err := f.s.AuthorizeJobPostAccess(f.jobPost.ID, tt.userID, tt.write); !errors.Is(err, tt.want) {
+			t.Errorf("%s: AuthorizeJobPostAccess() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeApplicationAccess(t *testing.T) {
+	f := newAccessFixture(t)
+	tests := []struct {
+		name   string
+		userID uint
+		want   error
+	}{
+		{"applicant", f.applicant, nil},
+		{"owner", f.owner, nil},
+		{"viewer", f.viewer, nil},
+		{"other organization", f.outsider, ErrUnauthorized},
+		{"other applicant", f.bystander, ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		if _, err := f.s.AuthorizeApplicationAccess(f.application.ID, tt.userID); !errors.Is(err, tt.want) {
+			t.Errorf("%s: AuthorizeApplicationAccess() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestOtherOrganizationCantChangeJobPost(t *testing.T) {
+	f := newAccessFixture(t)
+	for _, userID := range []uint{f.outsider, f.viewer} {
+		edit := &jobmodel.JobPost{ID: f.jobPost.ID, Title: "Taken over"}
+		if err := f.s.UpdateJobPost(edit, userID); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("UpdateJobPost() by user %d error = %v, want %v", userID, err, ErrUnauthorized)
+		}
+		if err := f.s.DeleteJobPost(f.jobPost.ID, userID); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("DeleteJobPost() by user %d error = %v, want %v", userID, err, ErrUnauthorized)
+		}
+		status := &jobmodel.JobApplication{ID: f.application.ID, Status: jobmodel.JobApplicationStatusAccepted}
+		if err := f.s.UpdateJobApplication(status, userID); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("UpdateJobApplication() by user %d error = %v, want %v", userID, err, ErrUnauthorized)
+		}
+	}
+
+	jobPost, err := f.s.GetJobPostByID(f.jobPost.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobPost == nil || jobPost.Title != "Go Developer" {
+		t.Errorf("job post = %+v, want it unchanged", jobPost)
+	}
+	application, err := f.s.GetJobApplicationByID(f.application.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if application.Status != jobmodel.JobApplicationStatusPending {
+		t.Errorf("application status = %s, want %s", application.Status, jobmodel.JobApplicationStatusPending)
+	}
+}
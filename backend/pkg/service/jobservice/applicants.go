@@ -0,0 +1,73 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobPost.ApplicantCount is the number of applications of a post that have
+// not been deleted or withdrawn.  It is changed in the same transaction as
+// the applications, so listings can read it instead of counting.
+
+// applicantCountSQL counts the live applications of the job post in the
+// outer query.
+const applicantCountSQL = "(SELECT COUNT(*) FROM job_applications WHERE job_applications.job_id = job_posts.id AND job_applications.deleted_at IS NULL)"
+
+// adjustApplicantCount adds delta to a job post's applicant count.
+func adjustApplicantCount(tx *gorm.DB, jobID uint, delta int) error {
+	err := tx.Unscoped().Model(&jobmodel.JobPost{}).Where("id = ?", jobID).
+		UpdateColumn("applicant_count", gorm.Expr("GREATEST(applicant_count + ?, 0)", delta)).Error
+	if err != nil {
+		return fmt.Errorf("failed to update applicant count: %w", err)
+	}
+	return nil
+}
+
+// RecountApplicants recomputes the applicant count of the given job posts, or
+// of every post when none are given.  It returns how many counts were wrong.
+// Callers that delete applications in bulk use it inside their transaction.
+func RecountApplicants(db *gorm.DB, jobIDs ...uint) (int64, error) {
+	query := db.Unscoped().Model(&jobmodel.JobPost{}).Where("applicant_count <> " + applicantCountSQL)
+	if len(jobIDs) > 0 {
+		query = query.Where("id IN ?", jobIDs)
+	}
+	result := query.UpdateColumn("applicant_count", gorm.Expr(applicantCountSQL))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to recount applicants: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ReconcileApplicantCounts repairs applicant counts that have drifted, e.g.
+// through manual database edits.  It returns how many posts were fixed.
+func (s *JobService) ReconcileApplicantCounts() (int64, error) {
+	return RecountApplicants(s.DB)
+}
+
+// WithdrawJobApplication lets an applicant take back a pending application.
+func (s *JobService) WithdrawJobApplication(applicationID, userID uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var application jobmodel.JobApplication
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&application, applicationID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrRecordNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to retrieve job application: %w", err)
+		}
+		if application.UserID != userID {
+			return ErrUnauthorized
+		}
+		if application.Status != jobmodel.JobApplicationStatusPending {
+			return ErrCannotWithdraw
+		}
+
+		if err := tx.Delete(&application).Error; err != nil {
+			return fmt.Errorf("failed to withdraw job application: %w", err)
+		}
+		return adjustApplicantCount(tx, application.JobID, -1)
+	})
+}
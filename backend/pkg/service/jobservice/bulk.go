@@ -0,0 +1,573 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/service/orgservice"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImportFormat   = errors.New("import must be a CSV file with a header row or a JSON array of job posts")
+	ErrImportTooLarge = fmt.Errorf("an import holds at most %d job posts", maxImportRows)
+)
+
+// Import file formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+const (
+	maxImportRows        = 500
+	maxTitleLength       = 255
+	maxExternalRefLength = 100
+	importDateLayout     = "2006-01-02"
+)
+
+// jobPostColumns are the columns of CSV imports and exports, in order.  Only
+// title is required in an import.
+var jobPostColumns = []string{
+	"id", "external_ref", "title", "description", "location", "job_position", "quantity", "salary_range",
+	"salary_min", "salary_max", "salary_currency", "salary_period", "salary_negotiable",
+	"state", "publish_at", "expires_at",
+}
+
+// contentColumns are written when an import updates a post: the file
+// replaces the content as a whole.  movedColumns add the state, for rows
+// that change it.
+var (
+	contentColumns = append([]string{"title", "description", "location", "job_position", "quantity"}, salaryColumns...)
+	movedColumns   = append(append([]string{}, contentColumns...), lifecycleColumns...)
+)
+
+// importRow is a record read from an import file, with the problems found
+// in it so far.
+type importRow struct {
+	record jobmodel.JobPostRecord
+	errors []string
+	moved  bool // The row changes the state or times of an existing post
+}
+
+// ImportJobPosts creates and updates the job posts of userID's organization
+// from a CSV or JSON file.  A row with the id of a post, as exported, or
+// whose external_ref matches a post imported before updates that post, so
+// the same file can be imported again; other rows create posts.  Every row
+// is checked first; the posts are only saved, all in one transaction, when
+// no row is invalid and dryRun is false.
+func (s *JobService) ImportJobPosts(userID uint, data []byte, format string, dryRun bool) (*jobmodel.JobPostImportReport, error) {
+	member, err := orgservice.EnsureMembership(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !orgmodel.CanManageJobs(member.Role) {
+		return nil, ErrUnauthorized
+	}
+
+	var rows []importRow
+	switch format {
+	case FormatCSV:
+		rows, err = parseCSVImport(data)
+	case FormatJSON:
+		rows, err = parseJSONImport(data)
+	default:
+		err = ErrImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file holds no job posts", ErrImportFormat)
+	}
+	if len(rows) > maxImportRows {
+		return nil, ErrImportTooLarge
+	}
+
+	byID, byRef, err := s.importedPosts(member.OrganizationID, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &jobmodel.JobPostImportReport{DryRun: dryRun, Rows: make([]jobmodel.JobPostImportResult, len(rows))}
+	jobPosts := make([]*jobmodel.JobPost, len(rows))
+	seenIDs := make(map[uint]int, len(rows))
+	seenRefs := make(map[string]int, len(rows))
+	now := time.Now()
+	for i := range rows {
+		row := &rows[i]
+		result := &report.Rows[i]
+		result.Row = i + 1
+		result.ExternalRef = row.record.ExternalRef
+
+		if id := row.record.ID; id != 0 {
+			if first, ok := seenIDs[id]; ok {
+				row.errors = append(row.errors, fmt.Sprintf("id repeats row %d", first))
+			} else {
+				seenIDs[id] = result.Row
+			}
+		}
+		if ref := row.record.ExternalRef; ref != "" {
+			if first, ok := seenRefs[ref]; ok {
+				row.errors = append(row.errors, fmt.Sprintf("external_ref repeats row %d", first))
+			} else {
+				seenRefs[ref] = result.Row
+			}
+		}
+		jobPost, action := prepareImport(row, matchImport(row, byID, byRef), userID, member.OrganizationID, now)
+		if len(row.errors) > 0 {
+			result.Action = jobmodel.JobPostImportInvalid
+			result.Errors = row.errors
+			report.Invalid++
+			continue
+		}
+		result.Action = action
+		if jobPost.ID != 0 {
+			id := jobPost.ID
+			result.JobID = &id
+		}
+		switch action {
+		case jobmodel.JobPostImportCreated:
+			report.Created++
+		case jobmodel.JobPostImportUpdated:
+			report.Updated++
+		case jobmodel.JobPostImportUnchanged:
+			report.Unchanged++
+		}
+		jobPosts[i] = jobPost
+	}
+	if dryRun || report.Invalid > 0 {
+		return report, nil
+	}
+
+	var changed []uint
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		for i, jobPost := range jobPosts {
+			switch report.Rows[i].Action {
+			case jobmodel.JobPostImportCreated:
+				if err := insertJobPost(tx, jobPost); err != nil {
+					return fmt.Errorf("failed to import row %d: %w", i+1, err)
+				}
+				id := jobPost.ID
+				report.Rows[i].JobID = &id
+			case jobmodel.JobPostImportUpdated:
+				columns := contentColumns
+				if rows[i].moved {
+					columns = movedColumns
+				}
+				if err := tx.Model(jobPost).Select(columns).Updates(jobPost).Error; err != nil {
+					return fmt.Errorf("failed to import row %d: %w", i+1, err)
+				}
+				if _, err := recordRevision(tx, jobPost.ID, userID); err != nil {
+					return err
+				}
+				// The file may lower the quantity to the accepted count.
+				if _, err := s.closeIfFilled(tx, jobPost.ID); err != nil {
+					return err
+				}
+			default:
+				continue
+			}
+			changed = append(changed, jobPost.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Imported = true
+	for _, id := range changed {
+		s.reindexJobPost(id)
+	}
+	return report, nil
+}
+
+// importedPosts loads the posts of an organization that rows refer to by id
+// or external_ref, deleted ones included, keyed by each.
+func (s *JobService) importedPosts(organizationID uint, rows []importRow) (map[uint]*jobmodel.JobPost, map[string]*jobmodel.JobPost, error) {
+	var ids []uint
+	var refs []string
+	for _, row := range rows {
+		if row.record.ID != 0 {
+			ids = append(ids, row.record.ID)
+		}
+		if row.record.ExternalRef != "" {
+			refs = append(refs, row.record.ExternalRef)
+		}
+	}
+	byID := make(map[uint]*jobmodel.JobPost, len(ids))
+	byRef := make(map[string]*jobmodel.JobPost, len(refs))
+	if len(ids) == 0 && len(refs) == 0 {
+		return byID, byRef, nil
+	}
+	query := s.DB.Unscoped().Where("organization_id = ?", organizationID)
+	switch {
+	case len(ids) == 0:
+		query = query.Where("external_ref IN ?", refs)
+	case len(refs) == 0:
+		query = query.Where("id IN ?", ids)
+	default:
+		query = query.Where("id IN ? OR external_ref IN ?", ids, refs)
+	}
+	var jobPosts []jobmodel.JobPost
+	if err := query.Find(&jobPosts).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve imported job posts: %w", err)
+	}
+	for i := range jobPosts {
+		byID[jobPosts[i].ID] = &jobPosts[i]
+		if jobPosts[i].ExternalRef != nil {
+			byRef[*jobPosts[i].ExternalRef] = &jobPosts[i]
+		}
+	}
+	return byID, byRef, nil
+}
+
+// matchImport finds the post a row updates: the one with its id, or else
+// the one with its external_ref.  An id that names no post of the
+// organization, or one with another external_ref, is a problem of the row.
+func matchImport(row *importRow, byID map[uint]*jobmodel.JobPost, byRef map[string]*jobmodel.JobPost) *jobmodel.JobPost {
+	r := row.record
+	if r.ID == 0 {
+		return byRef[r.ExternalRef]
+	}
+	jobPost := byID[r.ID]
+	if jobPost == nil {
+		row.errors = append(row.errors, fmt.Sprintf("no job post with id %d in your organization; leave id empty to create a new post", r.ID))
+		return nil
+	}
+	if r.ExternalRef != "" && (jobPost.ExternalRef == nil || *jobPost.ExternalRef != r.ExternalRef) {
+		row.errors = append(row.errors, fmt.Sprintf("external_ref doesn't match the job post with id %d", r.ID))
+	}
+	return jobPost
+}
+
+// prepareImport validates a row and turns it into the job post to save,
+// either a new one or existing with the content of the row.  Problems are
+// added to the row.
+func prepareImport(row *importRow, existing *jobmodel.JobPost, userID, organizationID uint, now time.Time) (*jobmodel.JobPost, string) {
+	r := row.record
+	if r.Title == "" {
+		row.errors = append(row.errors, "title is required")
+	} else if utf8.RuneCountInString(r.Title) > maxTitleLength {
+		row.errors = append(row.errors, fmt.Sprintf("title must be at most %d characters", maxTitleLength))
+	}
+	if utf8.RuneCountInString(r.ExternalRef) > maxExternalRefLength {
+		row.errors = append(row.errors, fmt.Sprintf("external_ref must be at most %d characters", maxExternalRefLength))
+	}
+	if r.Quantity < 0 {
+		row.errors = append(row.errors, "quantity must not be negative")
+	}
+	if existing != nil && existing.DeletedAt.Valid {
+		row.errors = append(row.errors, "the job post was deleted")
+		return nil, ""
+	}
+
+	jobPost := &jobmodel.JobPost{}
+	if existing != nil {
+		updated := *existing
+		jobPost = &updated
+	} else {
+		jobPost.UserID = userID
+		jobPost.OrganizationID = &organizationID
+		if r.ExternalRef != "" {
+			ref := r.ExternalRef
+			jobPost.ExternalRef = &ref
+		}
+		jobPost.State = r.State
+		jobPost.PublishAt = r.PublishAt
+		jobPost.ExpiresAt = r.ExpiresAt
+	}
+	jobPost.Title = r.Title
+	jobPost.Description = r.Description
+	jobPost.Location = r.Location
+	jobPost.JobPosition = r.JobPosition
+	jobPost.Quantity = r.Quantity
+	jobPost.SalaryRange = r.SalaryRange
+	jobPost.SetSalary(r.Salary)
+	if _, err := prepareSalary(jobPost); err != nil {
+		row.errors = append(row.errors, err.Error())
+	}
+
+	if existing == nil {
+		if err := prepareImportedLifecycle(jobPost, now); err != nil {
+			row.errors = append(row.errors, err.Error())
+		}
+		return jobPost, jobmodel.JobPostImportCreated
+	}
+	// A state or time that differs moves the post as PUT /api/jobs/:id/state
+	// would; empty ones keep the post's.
+	row.moved = lifecycleDiffers(existing, &r)
+	if row.moved {
+		req := jobmodel.JobPostStateRequest{State: r.State, PublishAt: r.PublishAt, ExpiresAt: r.ExpiresAt}
+		if err := changeState(jobPost, &req, now); err != nil {
+			row.errors = append(row.errors, err.Error())
+		}
+	}
+	before, after := newRevision(existing, userID), newRevision(jobPost, userID)
+	if before.SameContent(&after) && !row.moved {
+		return jobPost, jobmodel.JobPostImportUnchanged
+	}
+	return jobPost, jobmodel.JobPostImportUpdated
+}
+
+// prepareImportedLifecycle sets the state of a new imported post.  Unlike
+// posts created through the API, it may start paused, closed or archived, so
+// that exported posts can be imported elsewhere as they were.
+func prepareImportedLifecycle(jobPost *jobmodel.JobPost, now time.Time) error {
+	switch jobPost.State {
+	case jobmodel.JobPostStatePaused, jobmodel.JobPostStateClosed, jobmodel.JobPostStateArchived:
+		return applySchedule(jobPost, jobPost.PublishAt, jobPost.ExpiresAt, now)
+	}
+	return prepareLifecycle(jobPost, now)
+}
+
+// lifecycleDiffers reports whether a record asks for another state or other
+// times than jobPost has.
+func lifecycleDiffers(jobPost *jobmodel.JobPost, r *jobmodel.JobPostRecord) bool {
+	sameTime := func(current, wanted *time.Time) bool {
+		return wanted == nil || (current != nil && current.Equal(*wanted))
+	}
+	return (r.State != "" && r.State != jobPost.State) ||
+		!sameTime(jobPost.PublishAt, r.PublishAt) ||
+		!sameTime(jobPost.ExpiresAt, r.ExpiresAt)
+}
+
+// parseCSVImport reads a CSV file whose first row names the columns, in any
+// order.  Values that can't be read are reported on their row.
+func parseCSVImport(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff")))) // Spreadsheets often start with a BOM
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isJobPostColumn(name) {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrImportFormat, name)
+		}
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrImportFormat, name)
+		}
+		index[name] = i
+	}
+	if _, ok := index["title"]; !ok {
+		return nil, fmt.Errorf("%w: the title column is missing", ErrImportFormat)
+	}
+
+	var rows []importRow
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var row importRow
+		if err != nil {
+			// Rows with the wrong number of fields are still returned.
+			if !errors.Is(err, csv.ErrFieldCount) {
+				return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+			}
+			row.errors = append(row.errors, fmt.Sprintf("expected %d fields, found %d", len(header), len(fields)))
+			rows = append(rows, row)
+			continue
+		}
+		value := func(column string) string {
+			if i, ok := index[column]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		row.record = jobmodel.JobPostRecord{
+			ExternalRef: value("external_ref"),
+			Title:       value("title"),
+			Description: value("description"),
+			Location:    value("location"),
+			JobPosition: value("job_position"),
+			SalaryRange: value("salary_range"),
+			State:       strings.ToLower(value("state")),
+		}
+		if v := value("id"); v != "" {
+			id, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || id == 0 {
+				row.errors = append(row.errors, "id must be the ID of a job post")
+			}
+			row.record.ID = uint(id)
+		}
+		row.record.Currency = value("salary_currency")
+		row.record.Period = value("salary_period")
+		if v := value("quantity"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				row.errors = append(row.errors, "quantity must be a whole number")
+			}
+			row.record.Quantity = n
+		}
+		row.record.Min = parseImportAmount(&row, "salary_min", value("salary_min"))
+		row.record.Max = parseImportAmount(&row, "salary_max", value("salary_max"))
+		if v := value("salary_negotiable"); v != "" {
+			negotiable, ok := parseImportBool(v)
+			if !ok {
+				row.errors = append(row.errors, "salary_negotiable must be true or false")
+			}
+			row.record.Negotiable = negotiable
+		}
+		row.record.PublishAt = parseImportTime(&row, "publish_at", value("publish_at"))
+		row.record.ExpiresAt = parseImportTime(&row, "expires_at", value("expires_at"))
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSONImport reads a JSON array of job post records.  An element that
+// doesn't fit the record, e.g. with an unknown key, is reported on its row.
+func parseJSONImport(data []byte) ([]importRow, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	}
+	rows := make([]importRow, len(elements))
+	for i, element := range elements {
+		decoder := json.NewDecoder(bytes.NewReader(element))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rows[i].record); err != nil {
+			rows[i].errors = append(rows[i].errors, err.Error())
+			continue
+		}
+		r := &rows[i].record
+		for _, field := range []*string{&r.ExternalRef, &r.Title, &r.Description, &r.Location, &r.JobPosition, &r.SalaryRange} {
+			*field = strings.TrimSpace(*field)
+		}
+		r.State = strings.ToLower(strings.TrimSpace(r.State))
+	}
+	return rows, nil
+}
+
+func isJobPostColumn(name string) bool {
+	for _, column := range jobPostColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseImportAmount(row *importRow, column, value string) *int {
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(strings.ReplaceAll(value, ",", ""))
+	if err != nil {
+		row.errors = append(row.errors, column+" must be a whole number")
+		return nil
+	}
+	return &n
+}
+
+func parseImportBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1":
+		return true, true
+	case "false", "no", "n", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// parseImportTime reads an RFC 3339 time, or a date, which is taken as
+// midnight UTC.
+func parseImportTime(row *importRow, column, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, importDateLayout} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	row.errors = append(row.errors, column+" must be a date (2006-01-02) or an RFC 3339 time")
+	return nil
+}
+
+// ExportJobPosts returns the posts of userID's organization, as listed by
+// ListJobPostsByUserID, as records that ImportJobPosts reads back.
+func (s *JobService) ExportJobPosts(userID uint) ([]jobmodel.JobPostRecord, error) {
+	jobPosts, err := s.ListJobPostsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	records := make([]jobmodel.JobPostRecord, 0, len(jobPosts))
+	for _, jobPost := range jobPosts {
+		record := jobmodel.JobPostRecord{
+			ID:          jobPost.ID,
+			Title:       jobPost.Title,
+			Description: jobPost.Description,
+			Location:    jobPost.Location,
+			JobPosition: jobPost.JobPosition,
+			Quantity:    jobPost.Quantity,
+			SalaryRange: jobPost.SalaryRange,
+			Salary:      jobPost.Salary(),
+			State:       jobPost.State,
+			PublishAt:   jobPost.PublishAt,
+			ExpiresAt:   jobPost.ExpiresAt,
+		}
+		if jobPost.ExternalRef != nil {
+			record.ExternalRef = *jobPost.ExternalRef
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// WriteJobPostRecordsCSV writes records as a CSV file with a header row.
+func WriteJobPostRecordsCSV(w io.Writer, records []jobmodel.JobPostRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(jobPostColumns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		err := writer.Write([]string{
+			formatImportID(r.ID), r.ExternalRef, r.Title, r.Description, r.Location, r.JobPosition, strconv.Itoa(r.Quantity), r.SalaryRange,
+			formatImportAmount(r.Min), formatImportAmount(r.Max), r.Currency, r.Period, strconv.FormatBool(r.Negotiable),
+			r.State, formatImportTime(r.PublishAt), formatImportTime(r.ExpiresAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatImportID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func formatImportAmount(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func formatImportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
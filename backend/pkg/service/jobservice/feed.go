@@ -0,0 +1,26 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"fmt"
+	"time"
+)
+
+const maxFeedSize = 100
+
+// ListFeedJobPosts returns the open job posts most recently published, for
+// the public feeds.
+func (s *JobService) ListFeedJobPosts(limit int) ([]jobmodel.JobPost, error) {
+	if limit <= 0 || limit > maxFeedSize {
+		limit = maxFeedSize
+	}
+	var jobPosts []jobmodel.JobPost
+	err := s.DB.Preload("User").Scopes(openPosts(time.Now())).
+		Order("COALESCE(job_posts.publish_at, job_posts.created_at) DESC, job_posts.id DESC").
+		Limit(limit).
+		Find(&jobPosts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	return jobPosts, s.attachCompanyProfiles(jobPosts)
+}
@@ -0,0 +1,100 @@
+package jobservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// closeIfFilled closes a job post once as many of its applications are
+// accepted as it has positions, unless its organization turned that off.
+// Applicants still pending are notified if the organization wants them to
+// be.  It runs in the transaction that accepted an application or changed
+// the quantity, and reports whether the post was closed.
+func (s *JobService) closeIfFilled(tx *gorm.DB, jobID uint) (bool, error) {
+	// The lock makes concurrent acceptances for the same post take turns.
+	var jobPost jobmodel.JobPost
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&jobPost, jobID).Error; err != nil {
+		return false, fmt.Errorf("failed to retrieve job post: %w", err)
+	}
+	if jobPost.Quantity <= 0 || (jobPost.State != jobmodel.JobPostStateOpen && jobPost.State != jobmodel.JobPostStatePaused) {
+		return false, nil
+	}
+	var org orgmodel.Organization
+	if jobPost.OrganizationID != nil {
+		err := tx.First(&org, *jobPost.OrganizationID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, fmt.Errorf("failed to retrieve organization: %w", err)
+		}
+		if err == nil && !org.AutoCloseFilledJobs {
+			return false, nil
+		}
+	}
+
+	var accepted int64
+	err := tx.Model(&jobmodel.JobApplication{}).
+		Where("job_id = ? AND status = ?", jobID, jobmodel.JobApplicationStatusAccepted).
+		Count(&accepted).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to count accepted applications: %w", err)
+	}
+	if accepted < int64(jobPost.Quantity) {
+		return false, nil
+	}
+
+	err = tx.Model(&jobPost).Updates(map[string]interface{}{"state": jobmodel.JobPostStateClosed, "status": false}).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to close job post: %w", err)
+	}
+	if org.NotifyPendingOnFilled {
+		if err := s.notifyPendingApplicants(tx, &jobPost, &org); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// notifyPendingApplicants tells the applicants still waiting on a filled job
+// post that it closed.
+func (s *JobService) notifyPendingApplicants(tx *gorm.DB, jobPost *jobmodel.JobPost, org *orgmodel.Organization) error {
+	var pending []jobmodel.JobApplication
+	err := tx.Preload("User").
+		Where("job_id = ? AND status = ?", jobPost.ID, jobmodel.JobApplicationStatusPending).
+		Find(&pending).Error
+	if err != nil {
+		return fmt.Errorf("failed to retrieve pending applications: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// The notice names the company as applicants see it on the post.
+	companyName := org.Name
+	posts := []jobmodel.JobPost{*jobPost}
+	if err := tx.First(&posts[0].User, posts[0].UserID).Error; err != nil {
+		return fmt.Errorf("failed to retrieve job poster: %w", err)
+	}
+	if err := s.attachCompanyProfiles(posts); err != nil {
+		return err
+	}
+	if name := DisplayCompanyName(&posts[0]); name != "" {
+		companyName = name
+	}
+
+	notifications := make([]authmodel.Notification, 0, len(pending))
+	for _, application := range pending {
+		notifications = append(notifications, authmodel.Notification{
+			UserID:  application.UserID,
+			Message: orgmodel.RenderFilledJobNotice(org.FilledJobTemplate, application.User.Name, jobPost.Title, companyName),
+		})
+	}
+	if err := tx.Create(&notifications).Error; err != nil {
+		return fmt.Errorf("failed to notify pending applicants: %w", err)
+	}
+	return nil
+}
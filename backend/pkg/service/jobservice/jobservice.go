@@ -1,13 +1,18 @@
 package jobservice
 
 import (
+	"backend/pkg/model/authmodel"
 	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
 	"backend/pkg/pdfextractor"
+	"backend/pkg/search"
 	"backend/pkg/service/geminiservice"
+	"backend/pkg/service/orgservice"
+	"backend/pkg/storage"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,18 +22,19 @@ import (
 type IJobService interface {
 	CreateJobPost(jobPost *jobmodel.JobPost) error
 	GetJobPostByID(id uint) (*jobmodel.JobPost, error)
-	UpdateJobPost(jobPost *jobmodel.JobPost) error
+	UpdateJobPost(jobPost *jobmodel.JobPost, userID uint) error
 	DeleteJobPost(jobID, userID uint) error
-	ListJobPosts() ([]jobmodel.JobPost, error) // Modified return type
+	ListJobPosts(query jobmodel.JobPostQuery) (*jobmodel.JobPostPage, error)
 	ListJobPostsByCompanyID(companyID uint) ([]jobmodel.JobPost, error)
 	ListOpenJobPosts() ([]jobmodel.JobPost, error)
 	ListClosedJobPosts() ([]jobmodel.JobPost, error)
+	ListOpenJobPostsByUserID(userID uint) ([]jobmodel.JobPost, error)
 	CreateJobApplication(application *jobmodel.JobApplication, resumeFile []byte) (string, error)
 	GetJobApplicationByID(id uint) (*jobmodel.JobApplication, error)
-	UpdateJobApplication(application *jobmodel.JobApplication) error
+	UpdateJobApplication(application *jobmodel.JobApplication, userID uint) error
 	ListJobApplicationsByJobID(jobID uint) ([]jobmodel.JobApplication, error)
 	ListJobApplicationsByUserID(userID uint) ([]jobmodel.JobApplication, error)
-	ListJobApplicationsWithFilter(status string, userID, jobID uint) ([]jobmodel.JobApplication, error) // CRITICAL: New method
+	ListJobApplicationsWithFilter(status string, userID, jobID, ownerID uint) ([]jobmodel.JobApplication, error) // CRITICAL: New method
 	SaveJob(userID, jobID uint) error
 	UnsaveJob(userID, jobID uint) error
 	ListSavedJobs(userID uint) ([]jobmodel.SavedJob, error)
@@ -36,21 +42,40 @@ type IJobService interface {
 	GetAllApplicants() ([]jobmodel.JobApplication, error)
 	ListJobPostsByUserID(userID uint) ([]jobmodel.JobPost, error)
 	CountApplicationsByJobID(jobID uint) (int64, error)
+	WithdrawJobApplication(applicationID, userID uint) error
+	ReconcileApplicantCounts() (int64, error)
+	SearchJobPosts(query string, offset, limit int) (*jobmodel.JobPostSearchPage, error)
+	RebuildSearchIndex() (int, error)
+	ReindexCompanyJobPosts(userID uint) error
+	ReindexJobPosts(ids ...uint)
+	ChangeJobPostState(jobID, userID uint, req *jobmodel.JobPostStateRequest) (*jobmodel.JobPost, error)
+	RunScheduledTransitions(now time.Time) (opened, closed int, err error)
+	VisibleJobPosts(jobPosts []jobmodel.JobPost, viewerID uint) ([]jobmodel.JobPost, error)
+	ListJobPostRevisions(jobID, userID uint) ([]jobmodel.JobPostRevision, error)
+	ImportJobPosts(userID uint, data []byte, format string, dryRun bool) (*jobmodel.JobPostImportReport, error)
+	ExportJobPosts(userID uint) ([]jobmodel.JobPostRecord, error)
+	ListFeedJobPosts(limit int) ([]jobmodel.JobPost, error)
+	AuthorizeJobPostAccess(jobID, userID uint, write bool) (*jobmodel.JobPost, error)
+	AuthorizeApplicationAccess(applicationID, userID uint) (*jobmodel.JobApplication, error)
 }
 
 type JobService struct {
 	DB            *gorm.DB
 	PdfExtractor  pdfextractor.IPdfExtractor
 	GeminiService geminiservice.IGeminiService // Inject Gemini Service
+	Storage       storage.IStorage             // Where resumes are kept
+	Search        search.IIndex                // Full-text index of open job posts
 }
 
 // NewJobService creates a new JobService, injecting dependencies.
-func NewJobService(db *gorm.DB, pdfExtractor pdfextractor.IPdfExtractor, geminiService geminiservice.IGeminiService) *JobService {
-	return &JobService{DB: db, PdfExtractor: pdfExtractor, GeminiService: geminiService}
+func NewJobService(db *gorm.DB, pdfExtractor pdfextractor.IPdfExtractor, geminiService geminiservice.IGeminiService, store storage.IStorage, index search.IIndex) *JobService {
+	return &JobService{DB: db, PdfExtractor: pdfExtractor, GeminiService: geminiService, Storage: store, Search: index}
 }
 
 var ErrDuplicateSave = errors.New("job already saved by this user")
 var ErrUnauthorized = errors.New("unauthorized")
+var ErrInvalidStatus = errors.New("invalid application status")
+var ErrCannotWithdraw = errors.New("only pending applications can be withdrawn")
 
 const (
 	errInvalidJobID    = "Invalid job ID"
@@ -59,8 +84,48 @@ const (
 	errDeleteJobPost   = "Failed to delete job post"
 )
 
+// CreateJobPost creates a job post for the organization of jobPost.UserID.
+// Viewers can't post jobs.
 func (s *JobService) CreateJobPost(jobPost *jobmodel.JobPost) error {
-	return s.DB.Create(jobPost).Error
+	member, err := orgservice.EnsureMembership(s.DB, jobPost.UserID)
+	if err != nil {
+		return err
+	}
+	if !orgmodel.CanManageJobs(member.Role) {
+		return ErrUnauthorized
+	}
+	jobPost.OrganizationID = &member.OrganizationID
+	jobPost.ApplicantCount = 0 // Counted by the service, never set by clients
+	jobPost.ExternalRef = nil  // Only set by imports
+	if _, err := prepareSalary(jobPost); err != nil {
+		return err
+	}
+	if err := prepareLifecycle(jobPost, time.Now()); err != nil {
+		return err
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		return insertJobPost(tx, jobPost)
+	})
+	if err != nil {
+		return err
+	}
+	s.reindexJobPost(jobPost.ID)
+	return nil
+}
+
+// insertJobPost saves a new, validated job post with its first revision.
+func insertJobPost(tx *gorm.DB, jobPost *jobmodel.JobPost) error {
+	if err := tx.Create(jobPost).Error; err != nil {
+		return err
+	}
+	// Create leaves out a false Status, which the column defaults to true.
+	if !jobPost.Status {
+		if err := tx.Model(jobPost).Update("status", false).Error; err != nil {
+			return err
+		}
+	}
+	_, err := recordRevision(tx, jobPost.ID, jobPost.UserID)
+	return err
 }
 
 func (s *JobService) GetJobPostByID(id uint) (*jobmodel.JobPost, error) {
@@ -69,38 +134,86 @@ func (s *JobService) GetJobPostByID(id uint) (*jobmodel.JobPost, error) {
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil // Return nil, nil if not found
 	}
-	return &jobPost, err
+	if err != nil {
+		return nil, err
+	}
+	posts := []jobmodel.JobPost{jobPost}
+	if err := s.attachCompanyProfiles(posts); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
 }
 
-func (s *JobService) UpdateJobPost(jobPost *jobmodel.JobPost) error {
-	result := s.DB.Model(jobPost).Updates(jobPost)
-	if result.Error != nil {
-		return result.Error
+// UpdateJobPost updates a job post userID may manage.
+func (s *JobService) UpdateJobPost(jobPost *jobmodel.JobPost, userID uint) error {
+	existing, err := s.AuthorizeJobPostAccess(jobPost.ID, userID, true)
+	if err != nil {
+		return err
 	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound // Or a custom error indicating no update happened
+	// Ownership can't be transferred through an update, and the applicant
+	// count is maintained by the service.
+	jobPost.UserID = existing.UserID
+	jobPost.OrganizationID = existing.OrganizationID
+	jobPost.ApplicantCount = existing.ApplicantCount
+	jobPost.ExternalRef = existing.ExternalRef
+	// The state only changes through ChangeJobPostState.
+	jobPost.State = existing.State
+	jobPost.Status = existing.Status
+	jobPost.PublishAt = existing.PublishAt
+	jobPost.ExpiresAt = existing.ExpiresAt
+	// A salary in the request replaces the old one; without one it is kept.
+	hasSalary, err := prepareSalary(jobPost)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-func (s *JobService) DeleteJobPost(jobID, userID uint) error {
-	// 1. Retrieve the job post to check the owner.
-	var jobPost jobmodel.JobPost
-	result := s.DB.First(&jobPost, jobID)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return gorm.ErrRecordNotFound // Job post not found
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// The applicant count and the state read above may be stale by now;
+		// applications and the scheduler change them in their own
+		// transactions.
+		result := tx.Model(jobPost).Omit(append([]string{"applicant_count"}, lifecycleColumns...)...).Updates(jobPost)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound // Or a custom error indicating no update happened
 		}
-		return fmt.Errorf("failed to retrieve job post: %w", result.Error)
+		if hasSalary {
+			if err := saveSalary(tx, jobPost); err != nil {
+				return err
+			}
+		}
+		// Every edit of the content is kept, so applications stay tied to
+		// the text they were scored against.
+		if _, err := recordRevision(tx, jobPost.ID, userID); err != nil {
+			return err
+		}
+		// Lowering the quantity to the accepted count fills the post.
+		filled, err := s.closeIfFilled(tx, jobPost.ID)
+		if err != nil {
+			return err
+		}
+		if filled {
+			jobPost.State = jobmodel.JobPostStateClosed
+			jobPost.Status = false
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.reindexJobPost(jobPost.ID)
+	return nil
+}
 
-	// 2. Check if the user is the owner of the job post.
-	if jobPost.UserID != userID {
-		return ErrUnauthorized // Unauthorized access
+func (s *JobService) DeleteJobPost(jobID, userID uint) error {
+	// 1. Check that the user may manage the job post.
+	if _, err := s.AuthorizeJobPostAccess(jobID, userID, true); err != nil {
+		return err
 	}
 
-	// 3. Delete the job post.
-	result = s.DB.Delete(&jobmodel.JobPost{}, jobID)
+	// 2. Delete the job post.
+	result := s.DB.Delete(&jobmodel.JobPost{}, jobID)
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete job post: %w", result.Error)
 	}
@@ -109,111 +222,135 @@ func (s *JobService) DeleteJobPost(jobID, userID uint) error {
 		// Because the jobPost already retrieves in the step before.
 		return gorm.ErrRecordNotFound // Should not happen, but good to check
 	}
+	s.reindexJobPost(jobID)
 
 	return nil
 }
 
-// ListJobPosts retrieves all job posts, preloading the associated User.
-func (s *JobService) ListJobPosts() ([]jobmodel.JobPost, error) {
-	var jobPosts []jobmodel.JobPost
-	err := s.DB.Preload("User").Find(&jobPosts).Error // Preload the User
-	return jobPosts, err
-}
-
-// ListJobPostsByCompanyID now filters by UserID (due to model change) and preloads User.
+// ListJobPostsByCompanyID lists the job posts of the organization the company
+// user belongs to, and preloads User.
 func (s *JobService) ListJobPostsByCompanyID(companyID uint) ([]jobmodel.JobPost, error) {
 	var jobPosts []jobmodel.JobPost
 	// VERY IMPORTANT: Exclude soft-deleted records.
-	err := s.DB.Preload("User").Where("user_id = ? AND deleted_at IS NULL", companyID).Find(&jobPosts).Error // Preload User
-	return jobPosts, err
+	err := s.DB.Preload("User").Scopes(s.organizationPosts(companyID)).Where("deleted_at IS NULL").Find(&jobPosts).Error // Preload User
+	if err != nil {
+		return nil, err
+	}
+	return jobPosts, s.attachCompanyProfiles(jobPosts)
 }
 
 // Added: List only open job posts
 func (s *JobService) ListOpenJobPosts() ([]jobmodel.JobPost, error) {
 	var jobPosts []jobmodel.JobPost
-	err := s.DB.Where("status = ?", true).Find(&jobPosts).Error // true for open
+	err := s.DB.Scopes(openPosts(time.Now())).Find(&jobPosts).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobPosts, s.attachCompanyProfiles(jobPosts)
+}
+
+// ListOpenJobPostsByUserID lists the open job posts of the organization the
+// company user belongs to.
+func (s *JobService) ListOpenJobPostsByUserID(userID uint) ([]jobmodel.JobPost, error) {
+	var jobPosts []jobmodel.JobPost
+	err := s.DB.Scopes(s.organizationPosts(userID), openPosts(time.Now())).Order("created_at DESC").Find(&jobPosts).Error
 	return jobPosts, err
 }
 
 // Added: List only closed job posts
 func (s *JobService) ListClosedJobPosts() ([]jobmodel.JobPost, error) {
 	var jobPosts []jobmodel.JobPost
-	err := s.DB.Where("status = ?", false).Find(&jobPosts).Error // false for closed
-	return jobPosts, err
+	err := s.DB.Scopes(closedPosts(time.Now())).Find(&jobPosts).Error // Paused, closed or expired
+	if err != nil {
+		return nil, err
+	}
+	return jobPosts, s.attachCompanyProfiles(jobPosts)
 }
 
 // CreateJobApplication handles job application creation and resume upload.
 func (s *JobService) CreateJobApplication(application *jobmodel.JobApplication, resumeFile []byte) (string, error) {
-	uniqueID := uuid.New().String()
-	fileName := uniqueID + ".pdf"
-	filePath := filepath.Join("uploads", "resumes", fileName)
-
-	// 1. Create directory.
-	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	target, err := s.GetJobPostByID(application.JobID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to get job post: %w", err)
+	}
+	if target == nil || !target.IsOpen(time.Now()) {
+		return "", ErrJobPostNotOpen
 	}
 
-	// 2. Save resume file.
-	err = os.WriteFile(filePath, resumeFile, 0644)
+	uniqueID := uuid.New().String()
+	key := "resumes/" + uniqueID + ".pdf"
+	filePath := s.Storage.Path(key)
+
+	// 1. Save resume file.
+	err = s.Storage.Save(key, resumeFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to save resume file: %w", err)
 	}
 
-	// 3. Extract text from PDF.
+	// 2. Extract text from PDF.
 	extractedText, err := s.PdfExtractor.ExtractText(filePath)
 	if err != nil {
-		os.Remove(filePath) // Clean up if extraction fails.
+		s.Storage.Delete(key) // Clean up if extraction fails.
 		return "", fmt.Errorf("failed to extract text from PDF: %w", err)
 	}
 
-	// 4. Set file path.
+	// 3. Set file path.
 	application.ResumeFile = filePath
 
 	// --- Transaction Start ---
 	tx := s.DB.Begin()
 	if tx.Error != nil {
-		os.Remove(filePath) // Clean up on transaction start failure
+		s.Storage.Delete(key) // Clean up on transaction start failure
 		return "", fmt.Errorf("failed to begin database transaction: %w", tx.Error)
 	}
+	committed := false
 	defer func() {
-		if r := recover(); r != nil {
+		if r := recover(); r != nil && !committed {
 			tx.Rollback()
-			os.Remove(filePath) // Clean up on panic
+			s.Storage.Delete(key) // Clean up on panic
 		}
 	}()
 
-	// 5. Save the initial application (before Gemini processing).
+	// 4. Save the initial application (before Gemini processing).
 	err = tx.Create(application).Error
 	if err != nil {
 		tx.Rollback()
-		os.Remove(filePath) // Clean up on database error
+		s.Storage.Delete(key) // Clean up on database error
 		return "", fmt.Errorf("failed to save application: %w", err)
 	}
-
-	// 6. Get the JobPost (needed for the Gemini prompt).
-	jobPost, err := s.GetJobPostByID(application.JobID) // Use GetJobPostByID (soft-delete aware)
-	if err != nil {
+	if err := adjustApplicantCount(tx, application.JobID, 1); err != nil {
 		tx.Rollback()
-		os.Remove(filePath)
-		return "", fmt.Errorf("failed to get job post: %w", err)
+		s.Storage.Delete(key)
+		return "", err
 	}
-	if jobPost == nil { // Handle case where GetJobPostByID returns nil, nil
+	// The resume is scored against the current revision of the post.
+	revision, err := recordRevision(tx, application.JobID, target.UserID)
+	if err != nil {
 		tx.Rollback()
-		os.Remove(filePath)
-		return "", fmt.Errorf("job post with id %d not found", application.JobID)
+		s.Storage.Delete(key)
+		return "", err
+	}
+	application.RevisionID = &revision.ID
+
+	// --- Transaction Commit ---
+	// The job post row stays locked until the commit, so the application
+	// is committed before the slow call to Gemini.
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()         // Rollback for any commit error
+		s.Storage.Delete(key) // Clean up
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	committed = true
 
-	// 7. Call Gemini (with job description and resume text).
-	summary, score, questions, err := s.GeminiService.GenerateContent(jobPost.Description, extractedText)
+	// 5. Call Gemini (with job description and resume text).
+	summary, score, questions, err := s.GeminiService.GenerateContent(revision.Description, extractedText)
 	if err != nil {
 		// Log and continue.  Don't prevent application submission on Gemini failure.
 		fmt.Printf("Gemini API call failed: %v\n", err)
 		summary = "Resume analysis with Gemini failed." // Set a default summary
-		// We do NOT rollback here.  We still want the application to be saved.
 	}
 
-	// 8. Store Gemini results in the JobApplication.
+	// 6. Store Gemini results in the JobApplication.
 	application.GeminiSummary = summary // Always store the summary
 	if score != nil {
 		application.Score = score // Store score (if available)
@@ -222,30 +359,30 @@ func (s *JobService) CreateJobApplication(application *jobmodel.JobApplication,
 		application.Questions = questions
 	}
 
-	if err := tx.Save(application).Error; err != nil {
-		tx.Rollback() // Rollback if saving to job application fails
-		os.Remove(filePath)
-		return "", fmt.Errorf("failed to save Gemini data: %w", err)
-	}
-	// 9. Create a Message with the QUESTIONS (if any).
-	if questions != nil && *questions != "" { // Check if questions are present
-		message := jobmodel.Message{
-			SenderID:    jobPost.UserID,     // Use the system user ID.
-			ReceiverID:  application.UserID, // Send to the applicant.
-			MessageText: *questions,         // Use the *questions* from Gemini.
+	// 7. Save the results and a Message with the QUESTIONS (if any) in a
+	// second, short transaction.  The application is already submitted, so
+	// a failure here is logged rather than returned.
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(application).
+			Select("gemini_summary", "score", "questions").
+			Updates(application).Error
+		if err != nil {
+			return fmt.Errorf("failed to save Gemini data: %w", err)
 		}
-		if err := tx.Create(&message).Error; err != nil {
-			tx.Rollback() // Rollback if message creation fails
-			os.Remove(filePath)
-			return "", fmt.Errorf("failed to create message: %w", err)
+		if questions != nil && *questions != "" { // Check if questions are present
+			message := jobmodel.Message{
+				SenderID:    target.UserID,      // Use the system user ID.
+				ReceiverID:  application.UserID, // Send to the applicant.
+				MessageText: *questions,         // Use the *questions* from Gemini.
+			}
+			if err := tx.Create(&message).Error; err != nil {
+				return fmt.Errorf("failed to create message: %w", err)
+			}
 		}
-	}
-
-	// --- Transaction Commit ---
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()       // Rollback for any commit error
-		os.Remove(filePath) // Clean up
-		return "", fmt.Errorf("failed to commit transaction: %w", err)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save the resume analysis of application %d: %v", application.ID, err)
 	}
 
 	return filePath, nil
@@ -267,14 +404,51 @@ func (s *JobService) GetJobApplicationByID(id uint) (*jobmodel.JobApplication, e
 	return &application, nil
 }
 
-func (s *JobService) UpdateJobApplication(application *jobmodel.JobApplication) error {
-	result := s.DB.Model(application).Updates(application)
-	if result.Error != nil {
-		return result.Error
+// UpdateJobApplication changes the status of an application.  Only owners and
+// recruiters of the organization that owns the job post may do this.
+// Accepting the last position of a post closes it; see closeIfFilled.
+func (s *JobService) UpdateJobApplication(application *jobmodel.JobApplication, userID uint) error {
+	existing, err := s.GetJobApplicationByID(application.ID)
+	if err != nil {
+		return err
 	}
-	if result.RowsAffected == 0 {
+	if existing == nil {
 		return gorm.ErrRecordNotFound
 	}
+	if ok, err := s.canAccessJobPost(&existing.JobPost, userID, true); err != nil {
+		return err
+	} else if !ok {
+		return ErrUnauthorized
+	}
+
+	switch application.Status {
+	case jobmodel.JobApplicationStatusPending, jobmodel.JobApplicationStatusAccepted, jobmodel.JobApplicationStatusRejected:
+	default:
+		return ErrInvalidStatus
+	}
+
+	var filled bool
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the status is writable; the resume and Gemini results are not.
+		result := tx.Model(&jobmodel.JobApplication{ID: application.ID}).Update("status", application.Status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if application.Status == jobmodel.JobApplicationStatusAccepted && existing.Status != jobmodel.JobApplicationStatusAccepted {
+			filled, err = s.closeIfFilled(tx, existing.JobID)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if filled {
+		s.reindexJobPost(existing.JobID)
+	}
 	return nil
 }
 
@@ -335,7 +509,21 @@ func (s *JobService) ListSavedJobs(userID uint) ([]jobmodel.SavedJob, error) {
 		Preload("JobPost.User").
 		Where("user_id = ?", userID).
 		Find(&savedJobs).Error
-	return savedJobs, err
+	if err != nil {
+		return nil, err
+	}
+
+	jobPosts := make([]jobmodel.JobPost, len(savedJobs))
+	for i := range savedJobs {
+		jobPosts[i] = savedJobs[i].JobPost
+	}
+	if err := s.attachCompanyProfiles(jobPosts); err != nil {
+		return nil, err
+	}
+	for i := range savedJobs {
+		savedJobs[i].JobPost.CompanyProfile = jobPosts[i].CompanyProfile
+	}
+	return savedJobs, nil
 }
 
 func (s *JobService) IsJobSaved(userID, jobID uint) (bool, error) {
@@ -354,7 +542,8 @@ func (s *JobService) GetAllApplicants() ([]jobmodel.JobApplication, error) {
 }
 
 // ListJobApplicationsWithFilter retrieves job applications with optional filters.
-func (s *JobService) ListJobApplicationsWithFilter(status string, userID, jobID uint) ([]jobmodel.JobApplication, error) {
+// ownerID, when non-zero, limits the result to applications for job posts of that user's organization.
+func (s *JobService) ListJobApplicationsWithFilter(status string, userID, jobID, ownerID uint) ([]jobmodel.JobApplication, error) {
 	var applications []jobmodel.JobApplication
 	query := s.DB.Model(&jobmodel.JobApplication{})
 
@@ -378,6 +567,9 @@ func (s *JobService) ListJobApplicationsWithFilter(status string, userID, jobID
 	if jobID != 0 {
 		query = query.Where("job_id = ?", jobID)
 	}
+	if ownerID != 0 {
+		query = query.Where("job_id IN (?)", s.DB.Model(&jobmodel.JobPost{}).Select("id").Scopes(s.organizationPosts(ownerID)))
+	}
 
 	err := query.Find(&applications).Error
 	return applications, err
@@ -385,10 +577,81 @@ func (s *JobService) ListJobApplicationsWithFilter(status string, userID, jobID
 
 func (s *JobService) ListJobPostsByUserID(userID uint) ([]jobmodel.JobPost, error) {
 	var jobPosts []jobmodel.JobPost
-	err := s.DB.Preload("User").
-		Where("user_id = ? AND deleted_at IS NULL", userID). // Exclude soft-deleted job posts
+	err := s.DB.Preload("User").Scopes(s.organizationPosts(userID)).
+		Where("deleted_at IS NULL"). // Exclude soft-deleted job posts
 		Find(&jobPosts).Error
-	return jobPosts, err
+	if err != nil {
+		return nil, err
+	}
+	return jobPosts, s.attachCompanyProfiles(jobPosts)
+}
+
+// attachCompanyProfiles fills in CompanyProfile of each post.  A post of an
+// organization shows the profile of the organization's first owner, so every
+// recruiter's posts carry the same company.
+func (s *JobService) attachCompanyProfiles(jobPosts []jobmodel.JobPost) error {
+	if len(jobPosts) == 0 {
+		return nil
+	}
+	orgIDs := make([]uint, 0, len(jobPosts))
+	for _, jobPost := range jobPosts {
+		if jobPost.OrganizationID != nil {
+			orgIDs = append(orgIDs, *jobPost.OrganizationID)
+		}
+	}
+	ownerByOrgID := make(map[uint]uint, len(orgIDs))
+	if len(orgIDs) > 0 {
+		var owners []orgmodel.OrganizationMember
+		err := s.DB.Where("organization_id IN ? AND role = ?", orgIDs, orgmodel.RoleOwner).
+			Order("created_at ASC").
+			Find(&owners).Error
+		if err != nil {
+			return fmt.Errorf("failed to load organization owners: %w", err)
+		}
+		for _, owner := range owners {
+			if _, ok := ownerByOrgID[owner.OrganizationID]; !ok {
+				ownerByOrgID[owner.OrganizationID] = owner.UserID
+			}
+		}
+	}
+	profileUserID := func(jobPost *jobmodel.JobPost) uint {
+		if jobPost.OrganizationID != nil {
+			if ownerID, ok := ownerByOrgID[*jobPost.OrganizationID]; ok {
+				return ownerID
+			}
+		}
+		return jobPost.UserID
+	}
+
+	userIDs := make([]uint, 0, len(jobPosts))
+	for i := range jobPosts {
+		userIDs = append(userIDs, profileUserID(&jobPosts[i]))
+	}
+
+	var profiles []authmodel.CompanyProfile
+	if err := s.DB.Where("user_id IN ?", userIDs).Find(&profiles).Error; err != nil {
+		return fmt.Errorf("failed to load company profiles: %w", err)
+	}
+	byUserID := make(map[uint]*authmodel.CompanyProfile, len(profiles))
+	for i := range profiles {
+		byUserID[profiles[i].UserID] = &profiles[i]
+	}
+	for i := range jobPosts {
+		jobPosts[i].CompanyProfile = byUserID[profileUserID(&jobPosts[i])]
+	}
+	return nil
+}
+
+// DisplayCompanyName returns the company name shown on a job post: the name
+// on the company profile rather than the one the user registered with.
+func DisplayCompanyName(jobPost *jobmodel.JobPost) string {
+	if jobPost.CompanyProfile != nil && jobPost.CompanyProfile.CompanyName != "" {
+		return jobPost.CompanyProfile.CompanyName
+	}
+	if jobPost.User.CompanyName != nil {
+		return *jobPost.User.CompanyName
+	}
+	return ""
 }
 
 // CountApplicationsByJobID counts applications for a specific job.
@@ -397,3 +660,75 @@ func (s *JobService) CountApplicationsByJobID(jobID uint) (int64, error) {
 	err := s.DB.Model(&jobmodel.JobApplication{}).Where("job_id = ?", jobID).Count(&count).Error
 	return count, err
 }
+
+// AuthorizeJobPostAccess returns the job post if userID may access it: every
+// member of the post's organization may read it, owners and recruiters may
+// also change it.  It returns gorm.ErrRecordNotFound when the post doesn't
+// exist and ErrUnauthorized when the user has no access.
+func (s *JobService) AuthorizeJobPostAccess(jobID, userID uint, write bool) (*jobmodel.JobPost, error) {
+	jobPost, err := s.GetJobPostByID(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job post: %w", err)
+	}
+	if jobPost == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	ok, err := s.canAccessJobPost(jobPost, userID, write)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	return jobPost, nil
+}
+
+// AuthorizeApplicationAccess returns the application if userID is either the
+// applicant or a member of the organization that owns the job post.
+func (s *JobService) AuthorizeApplicationAccess(applicationID, userID uint) (*jobmodel.JobApplication, error) {
+	application, err := s.GetJobApplicationByID(applicationID)
+	if err != nil {
+		return nil, err
+	}
+	if application == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if application.UserID == userID {
+		return application, nil
+	}
+	ok, err := s.canAccessJobPost(&application.JobPost, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	return application, nil
+}
+
+// canAccessJobPost resolves access to a job post through the membership of
+// its organization.  Posts without an organization fall back to the user who
+// created them.
+func (s *JobService) canAccessJobPost(jobPost *jobmodel.JobPost, userID uint, write bool) (bool, error) {
+	if jobPost.OrganizationID == nil {
+		return jobPost.UserID == userID, nil
+	}
+
+	var member orgmodel.OrganizationMember
+	err := s.DB.Where("organization_id = ? AND user_id = ?", *jobPost.OrganizationID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to check organization membership: %w", err)
+	}
+	return !write || orgmodel.CanManageJobs(member.Role), nil
+}
+
+// organizationPosts limits a job post query to the posts of userID's
+// organization, plus the user's own posts that predate organizations.
+func (s *JobService) organizationPosts(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(organization_id IN (?) OR (organization_id IS NULL AND user_id = ?))",
+			orgservice.MemberOrganizationIDs(s.DB, userID), userID)
+	}
+}
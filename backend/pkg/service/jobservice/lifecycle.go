@@ -0,0 +1,253 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidState           = errors.New("state must be draft, scheduled, open, paused, closed or archived")
+	ErrInvalidStateTransition = errors.New("job post can't move to that state")
+	ErrInvalidSchedule        = errors.New("invalid publish_at or expires_at")
+	ErrJobPostNotOpen         = errors.New("job post is not open for applications")
+)
+
+// jobPostTransitions lists the states each state may move to by hand.  The
+// scheduler also opens scheduled posts and closes expired ones.
+var jobPostTransitions = map[string][]string{
+	jobmodel.JobPostStateDraft:     {jobmodel.JobPostStateScheduled, jobmodel.JobPostStateOpen, jobmodel.JobPostStateArchived},
+	jobmodel.JobPostStateScheduled: {jobmodel.JobPostStateDraft, jobmodel.JobPostStateOpen, jobmodel.JobPostStateArchived},
+	jobmodel.JobPostStateOpen:      {jobmodel.JobPostStatePaused, jobmodel.JobPostStateClosed},
+	jobmodel.JobPostStatePaused:    {jobmodel.JobPostStateOpen, jobmodel.JobPostStateClosed},
+	jobmodel.JobPostStateClosed:    {jobmodel.JobPostStateOpen, jobmodel.JobPostStateArchived},
+	jobmodel.JobPostStateArchived:  {},
+}
+
+// lifecycleColumns are written by state changes.
+var lifecycleColumns = []string{"state", "status", "publish_at", "expires_at"}
+
+// openPosts limits a job post query to the posts taking applications at now.
+func openPosts(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("job_posts.state = ? AND (job_posts.expires_at IS NULL OR job_posts.expires_at > ?)", jobmodel.JobPostStateOpen, now)
+	}
+}
+
+// closedPosts limits a job post query to listed posts that don't take
+// applications.
+func closedPosts(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(job_posts.state IN ? OR (job_posts.state = ? AND job_posts.expires_at <= ?))",
+			[]string{jobmodel.JobPostStatePaused, jobmodel.JobPostStateClosed}, jobmodel.JobPostStateOpen, now)
+	}
+}
+
+// publicPosts limits a job post query to the posts anyone may see.
+func publicPosts(db *gorm.DB) *gorm.DB {
+	return db.Where("job_posts.state IN ?", jobmodel.PublicJobPostStates)
+}
+
+// prepareLifecycle sets the initial state of a new job post.  Without a state
+// a post opens right away, or at PublishAt if that is in the future.
+func prepareLifecycle(jobPost *jobmodel.JobPost, now time.Time) error {
+	if jobPost.State == "" {
+		jobPost.State = jobmodel.JobPostStateOpen
+		if jobPost.PublishAt != nil && jobPost.PublishAt.After(now) {
+			jobPost.State = jobmodel.JobPostStateScheduled
+		}
+	}
+	switch jobPost.State {
+	case jobmodel.JobPostStateDraft, jobmodel.JobPostStateScheduled, jobmodel.JobPostStateOpen:
+	default:
+		if _, ok := jobPostTransitions[jobPost.State]; ok {
+			return fmt.Errorf("%w: new posts start as draft, scheduled or open", ErrInvalidStateTransition)
+		}
+		return ErrInvalidState
+	}
+	return applySchedule(jobPost, jobPost.PublishAt, jobPost.ExpiresAt, now)
+}
+
+// applySchedule checks the timestamps of a post entering or staying in its
+// state and stores them, together with the matching Status.
+func applySchedule(jobPost *jobmodel.JobPost, publishAt, expiresAt *time.Time, now time.Time) error {
+	switch jobPost.State {
+	case jobmodel.JobPostStateScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return fmt.Errorf("%w: a scheduled post needs publish_at in the future", ErrInvalidSchedule)
+		}
+	case jobmodel.JobPostStateOpen:
+		// The publish time of an open post is when it actually opened.
+		if publishAt == nil || publishAt.After(now) {
+			publishAt = &now
+		}
+		if expiresAt != nil && !expiresAt.After(now) {
+			return fmt.Errorf("%w: expires_at must be in the future to open the post", ErrInvalidSchedule)
+		}
+	}
+	if publishAt != nil && expiresAt != nil && !expiresAt.After(*publishAt) {
+		return fmt.Errorf("%w: expires_at must be after publish_at", ErrInvalidSchedule)
+	}
+	jobPost.PublishAt = publishAt
+	jobPost.ExpiresAt = expiresAt
+	jobPost.Status = jobPost.State == jobmodel.JobPostStateOpen
+	return nil
+}
+
+// ChangeJobPostState moves a job post userID may manage to another state,
+// or only changes its schedule.
+func (s *JobService) ChangeJobPostState(jobID, userID uint, req *jobmodel.JobPostStateRequest) (*jobmodel.JobPost, error) {
+	jobPost, err := s.AuthorizeJobPostAccess(jobID, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := changeState(jobPost, req, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(jobPost).Select(lifecycleColumns).Updates(jobPost).Error; err != nil {
+		return nil, fmt.Errorf("failed to change job post state: %w", err)
+	}
+	s.reindexJobPost(jobPost.ID)
+	return jobPost, nil
+}
+
+// changeState applies req to jobPost if the move is allowed, without saving
+// it.
+func changeState(jobPost *jobmodel.JobPost, req *jobmodel.JobPostStateRequest, now time.Time) error {
+	target := req.State
+	if target == "" {
+		target = jobPost.State
+	}
+	if _, ok := jobPostTransitions[target]; !ok {
+		return ErrInvalidState
+	}
+	if target == jobmodel.JobPostStateArchived && jobPost.State == target {
+		return fmt.Errorf("%w: archived posts can't be changed", ErrInvalidStateTransition)
+	}
+	if target != jobPost.State && !canTransition(jobPost.State, target) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidStateTransition, jobPost.State, target)
+	}
+
+	publishAt, expiresAt := jobPost.PublishAt, jobPost.ExpiresAt
+	if req.PublishAt != nil {
+		publishAt = req.PublishAt
+	}
+	if req.ExpiresAt != nil {
+		expiresAt = req.ExpiresAt
+	}
+	jobPost.State = target
+	return applySchedule(jobPost, publishAt, expiresAt, now)
+}
+
+func canTransition(from, to string) bool {
+	for _, state := range jobPostTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RunScheduledTransitions opens the scheduled posts whose publish time has
+// come and closes the open posts that expired.  It is safe to run from
+// several instances at once.
+func (s *JobService) RunScheduledTransitions(now time.Time) (opened, closed int, err error) {
+	var due []uint
+	err = s.DB.Model(&jobmodel.JobPost{}).
+		Where("state = ? AND publish_at <= ?", jobmodel.JobPostStateScheduled, now).
+		Pluck("id", &due).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find scheduled job posts: %w", err)
+	}
+	for _, id := range due {
+		// The state in the condition keeps a post changed meanwhile as it is.
+		result := s.DB.Model(&jobmodel.JobPost{}).
+			Where("id = ? AND state = ?", id, jobmodel.JobPostStateScheduled).
+			Updates(map[string]interface{}{"state": jobmodel.JobPostStateOpen, "status": true})
+		if result.Error != nil {
+			return opened, closed, fmt.Errorf("failed to open job post %d: %w", id, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			opened++
+			s.reindexJobPost(id)
+		}
+	}
+
+	var expired []uint
+	err = s.DB.Model(&jobmodel.JobPost{}).
+		Where("state = ? AND expires_at <= ?", jobmodel.JobPostStateOpen, now).
+		Pluck("id", &expired).Error
+	if err != nil {
+		return opened, closed, fmt.Errorf("failed to find expired job posts: %w", err)
+	}
+	for _, id := range expired {
+		result := s.DB.Model(&jobmodel.JobPost{}).
+			Where("id = ? AND state = ?", id, jobmodel.JobPostStateOpen).
+			Updates(map[string]interface{}{"state": jobmodel.JobPostStateClosed, "status": false})
+		if result.Error != nil {
+			return opened, closed, fmt.Errorf("failed to close job post %d: %w", id, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			closed++
+			s.reindexJobPost(id)
+		}
+	}
+	return opened, closed, nil
+}
+
+// RunScheduler runs the scheduled transitions every interval until ctx is
+// done.  Failures are logged and retried on the next tick.
+func (s *JobService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		opened, closed, err := s.RunScheduledTransitions(time.Now())
+		if err != nil {
+			log.Printf("job scheduler: %v", err)
+		} else if opened > 0 || closed > 0 {
+			log.Printf("job scheduler: opened %d and closed %d job posts", opened, closed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// BackfillJobPostStates gives the posts closed before lifecycle states
+// existed, which the new column defaulted to open, their closed state.  It
+// returns how many posts were updated.
+func (s *JobService) BackfillJobPostStates() (int64, error) {
+	result := s.DB.Unscoped().Model(&jobmodel.JobPost{}).
+		Where("state = ? AND status = ?", jobmodel.JobPostStateOpen, false).
+		Update("state", jobmodel.JobPostStateClosed)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to backfill job post states: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// VisibleJobPosts drops the posts viewerID may not see: drafts, scheduled
+// and archived posts are only shown to members of the post's organization.
+func (s *JobService) VisibleJobPosts(jobPosts []jobmodel.JobPost, viewerID uint) ([]jobmodel.JobPost, error) {
+	visible := make([]jobmodel.JobPost, 0, len(jobPosts))
+	for i := range jobPosts {
+		if !jobPosts[i].IsPublic() {
+			ok, err := s.canAccessJobPost(&jobPosts[i], viewerID, false)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		visible = append(visible, jobPosts[i])
+	}
+	return visible, nil
+}
@@ -0,0 +1,215 @@
+package jobservice
+
+import (
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("sort must be newest, oldest, title or title_desc")
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// jobPostSort is the column a listing is ordered by.  Ties are broken by ID
+// in the same direction, so the order is total and cursors are stable.
+type jobPostSort struct {
+	column string
+	desc   bool
+}
+
+var jobPostSorts = map[string]jobPostSort{
+	jobmodel.JobPostSortNewest:    {"created_at", true},
+	jobmodel.JobPostSortOldest:    {"created_at", false},
+	jobmodel.JobPostSortTitle:     {"title", false},
+	jobmodel.JobPostSortTitleDesc: {"title", true},
+}
+
+// listingCursor is the position after the last post of a page.
+type listingCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// ListJobPosts returns one page of the job posts matching query, preloading
+// the associated User.
+func (s *JobService) ListJobPosts(query jobmodel.JobPostQuery) (*jobmodel.JobPostPage, error) {
+	if query.Sort == "" {
+		query.Sort = jobmodel.JobPostSortNewest
+	}
+	sort, ok := jobPostSorts[query.Sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	} else if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	filtered, err := s.filterJobPosts(&query)
+	if err != nil {
+		return nil, err
+	}
+	page := &jobmodel.JobPostPage{}
+	if err := filtered.Session(&gorm.Session{}).Model(&jobmodel.JobPost{}).Count(&page.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count job posts: %w", err)
+	}
+
+	listing := filtered.Session(&gorm.Session{})
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{} = cursor.Value
+		if sort.column == "created_at" {
+			if value, err = time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		}
+		op := ">"
+		if sort.desc {
+			op = "<"
+		}
+		listing = listing.Where(fmt.Sprintf("(job_posts.%[1]s %[2]s ? OR (job_posts.%[1]s = ? AND job_posts.id %[2]s ?))", sort.column, op),
+			value, value, cursor.ID)
+	}
+	direction := "ASC"
+	if sort.desc {
+		direction = "DESC"
+	}
+	// One extra row tells whether there is a next page.
+	err = listing.Preload("User").
+		Order(fmt.Sprintf("job_posts.%s %s, job_posts.id %s", sort.column, direction, direction)).
+		Limit(limit + 1).
+		Find(&page.JobPosts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	if len(page.JobPosts) > limit {
+		page.JobPosts = page.JobPosts[:limit]
+		last := page.JobPosts[limit-1]
+		cursor := listingCursor{Sort: query.Sort, ID: last.ID, Value: last.Title}
+		if sort.column == "created_at" {
+			cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+		}
+		page.NextCursor = encodeCursor(cursor)
+	}
+	return page, s.attachCompanyProfiles(page.JobPosts)
+}
+
+// filterJobPosts applies the filters of query to a job post query.
+func (s *JobService) filterJobPosts(query *jobmodel.JobPostQuery) (*gorm.DB, error) {
+	if err := filterSalary(query); err != nil {
+		return nil, err
+	}
+	db := s.DB.Model(&jobmodel.JobPost{}).Scopes(publicPosts)
+	for _, word := range strings.Fields(query.Keyword) {
+		pattern := "%" + escapeLike(word) + "%"
+		db = db.Where("(job_posts.title LIKE ? OR job_posts.description LIKE ?)", pattern, pattern)
+	}
+	if location := strings.TrimSpace(query.Location); location != "" {
+		db = db.Where("job_posts.location LIKE ?", "%"+escapeLike(location)+"%")
+	}
+	if position := strings.TrimSpace(query.JobPosition); position != "" {
+		db = db.Where("job_posts.job_position LIKE ?", "%"+escapeLike(position)+"%")
+	}
+	if query.Status != nil && *query.Status {
+		db = db.Scopes(openPosts(time.Now()))
+	} else if query.Status != nil {
+		db = db.Scopes(closedPosts(time.Now()))
+	}
+	if query.CompanyID != nil {
+		var profile authmodel.CompanyProfile
+		err := s.DB.Select("id", "user_id").First(&profile, *query.CompanyID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return db.Where("1 = 0"), nil // An unknown company has no posts
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to retrieve company profile: %w", err)
+		}
+		db = db.Scopes(s.organizationPosts(profile.UserID))
+	}
+	if query.SalaryCurrency != "" {
+		db = db.Where("job_posts.salary_currency = ?", query.SalaryCurrency)
+	}
+	// Amounts are compared per year, so a yearly salary matches a monthly
+	// filter.  A post with one bound has that bound on both ends; posts
+	// without an amount never match.
+	factor, _ := yearlyFactor(query.SalaryPeriod)
+	if query.SalaryMin != nil {
+		db = db.Where("COALESCE("+yearlySalarySQL("salary_max")+", "+yearlySalarySQL("salary_min")+") >= ?", int64(*query.SalaryMin)*factor)
+	}
+	if query.SalaryMax != nil {
+		db = db.Where("COALESCE("+yearlySalarySQL("salary_min")+", "+yearlySalarySQL("salary_max")+") <= ?", int64(*query.SalaryMax)*factor)
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("job_posts.created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		db = db.Where("job_posts.created_at < ?", *query.CreatedTo)
+	}
+	return db, nil
+}
+
+// filterSalary validates the salary filters of query and fills in the
+// defaults: with an amount, THB per month.
+func filterSalary(query *jobmodel.JobPostQuery) error {
+	query.SalaryCurrency = strings.ToUpper(strings.TrimSpace(query.SalaryCurrency))
+	query.SalaryPeriod = strings.ToLower(strings.TrimSpace(query.SalaryPeriod))
+	if query.SalaryMin != nil || query.SalaryMax != nil {
+		if query.SalaryCurrency == "" {
+			query.SalaryCurrency = jobmodel.DefaultSalaryCurrency
+		}
+		if query.SalaryPeriod == "" {
+			query.SalaryPeriod = jobmodel.SalaryPeriodMonth
+		}
+	}
+	if query.SalaryCurrency != "" && !currencyPattern.MatchString(query.SalaryCurrency) {
+		return fmt.Errorf("%w: salary_currency must be a three-letter code such as THB", ErrInvalidSalary)
+	}
+	if query.SalaryPeriod != "" {
+		if _, ok := yearlyFactor(query.SalaryPeriod); !ok {
+			return fmt.Errorf("%w: salary_period must be hour, day, month or year", ErrInvalidSalary)
+		}
+	}
+	return nil
+}
+
+func encodeCursor(cursor listingCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor, which must come from a listing with the same
+// sort order.
+func decodeCursor(raw, sort string) (*listingCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor listingCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
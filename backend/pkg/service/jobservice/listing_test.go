@@ -0,0 +1,34 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := listingCursor{Sort: jobmodel.JobPostSortTitle, Value: "Go Developer", ID: 42}
+	got, err := decodeCursor(encodeCursor(want), jobmodel.JobPostSortTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Errorf("decodeCursor() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestDecodeCursorRejectsOtherCursors(t *testing.T) {
+	tests := []struct {
+		name, raw string
+	}{
+		{"not base64", "***"},
+		{"not JSON", "bm90IGpzb24"},
+		{"another sort", encodeCursor(listingCursor{Sort: jobmodel.JobPostSortNewest, Value: "x", ID: 1})},
+		{"no ID", encodeCursor(listingCursor{Sort: jobmodel.JobPostSortTitle, Value: "x"})},
+	}
+	for _, tt := range tests {
+		if _, err := decodeCursor(tt.raw, jobmodel.JobPostSortTitle); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: decodeCursor() error = %v, want %v", tt.name, err, ErrInvalidCursor)
+		}
+	}
+}
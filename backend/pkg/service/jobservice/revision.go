@@ -0,0 +1,92 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordRevision saves the current content of a job post as its next
+// revision, unless the latest revision already holds that content.  It
+// returns the revision matching the post, and runs in the transaction that
+// changed the post.
+func recordRevision(tx *gorm.DB, jobID, editorID uint) (*jobmodel.JobPostRevision, error) {
+	// The lock keeps concurrent edits from taking the same number.
+	var jobPost jobmodel.JobPost
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&jobPost, jobID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve job post: %w", err)
+	}
+	revision := newRevision(&jobPost, editorID)
+	revision.Number = 1
+
+	var latest jobmodel.JobPostRevision
+	err := tx.Where("job_id = ?", jobID).Order("number DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		if latest.SameContent(&revision) {
+			return &latest, nil
+		}
+		revision.Number = latest.Number + 1
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to retrieve job post revision: %w", err)
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, fmt.Errorf("failed to save job post revision: %w", err)
+	}
+	return &revision, nil
+}
+
+// newRevision copies the content of a job post into an unsaved revision.
+func newRevision(jobPost *jobmodel.JobPost, editorID uint) jobmodel.JobPostRevision {
+	return jobmodel.JobPostRevision{
+		JobID:       jobPost.ID,
+		EditorID:    editorID,
+		Title:       jobPost.Title,
+		Description: jobPost.Description,
+		Location:    jobPost.Location,
+		JobPosition: jobPost.JobPosition,
+		Quantity:    jobPost.Quantity,
+		SalaryRange: jobPost.SalaryRange,
+		Salary:      jobPost.Salary(),
+	}
+}
+
+// ListJobPostRevisions returns the revisions of a job post userID's
+// organization owns, oldest first.
+func (s *JobService) ListJobPostRevisions(jobID, userID uint) ([]jobmodel.JobPostRevision, error) {
+	if _, err := s.AuthorizeJobPostAccess(jobID, userID, false); err != nil {
+		return nil, err
+	}
+	var revisions []jobmodel.JobPostRevision
+	if err := s.DB.Where("job_id = ?", jobID).Order("number ASC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve job post revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// BackfillJobPostRevisions gives the job posts created before revisions were
+// kept a first revision holding their current content.  Their earlier
+// applications stay unlinked, since the text they were scored against is
+// unknown.  It returns how many revisions were created.
+func (s *JobService) BackfillJobPostRevisions() (int, error) {
+	var jobPosts []jobmodel.JobPost
+	err := s.DB.Select("id", "user_id").
+		Where("NOT EXISTS (SELECT 1 FROM job_post_revisions WHERE job_post_revisions.job_id = job_posts.id)").
+		Find(&jobPosts).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	for i, jobPost := range jobPosts {
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			_, err := recordRevision(tx, jobPost.ID, jobPost.UserID)
+			return err
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(jobPosts), nil
+}
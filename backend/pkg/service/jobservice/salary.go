@@ -0,0 +1,259 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSalary = errors.New("invalid salary")
+
+const (
+	// maxSalaryAmount bounds salary amounts, which catches typos and keeps
+	// them well inside an int.
+	maxSalaryAmount = 1_000_000_000
+	// Salaries are compared per year, assuming 8-hour days and 22 working
+	// days a month.
+	hoursPerYear = 8 * 22 * 12
+	daysPerYear  = 22 * 12
+)
+
+// salaryColumns are written together: a new salary replaces the old one as a
+// whole.
+var salaryColumns = []string{"salary_range", "salary_min", "salary_max", "salary_currency", "salary_period", "salary_negotiable"}
+
+// normalizeSalary validates a salary and fills in the defaults: THB, and per
+// month, for a salary with an amount.
+func normalizeSalary(s *jobmodel.Salary) error {
+	s.Currency = strings.ToUpper(strings.TrimSpace(s.Currency))
+	s.Period = strings.ToLower(strings.TrimSpace(s.Period))
+	if s.Min != nil || s.Max != nil {
+		if s.Currency == "" {
+			s.Currency = jobmodel.DefaultSalaryCurrency
+		}
+		if s.Period == "" {
+			s.Period = jobmodel.SalaryPeriodMonth
+		}
+	}
+	if s.Currency != "" && !currencyPattern.MatchString(s.Currency) {
+		return fmt.Errorf("%w: currency must be a three-letter code such as THB", ErrInvalidSalary)
+	}
+	if s.Period != "" {
+		if _, ok := yearlyFactor(s.Period); !ok {
+			return fmt.Errorf("%w: period must be hour, day, month or year", ErrInvalidSalary)
+		}
+	}
+	for _, amount := range []*int{s.Min, s.Max} {
+		if amount != nil && (*amount < 0 || *amount > maxSalaryAmount) {
+			return fmt.Errorf("%w: amounts must be between 0 and %d", ErrInvalidSalary, maxSalaryAmount)
+		}
+	}
+	if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+		return fmt.Errorf("%w: minimum is above maximum", ErrInvalidSalary)
+	}
+	return nil
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// prepareSalary validates the salary of a job post about to be saved.  A post
+// with only the free-text SalaryRange, as older clients send it, gets the
+// structured fields parsed from it; a post with only structured fields gets
+// SalaryRange written from them.  It reports whether the post carries a
+// salary at all.
+func prepareSalary(jobPost *jobmodel.JobPost) (bool, error) {
+	jobPost.SalaryRange = strings.TrimSpace(jobPost.SalaryRange)
+	salary := jobPost.Salary()
+	if salary.IsZero() {
+		if jobPost.SalaryRange == "" {
+			return false, nil
+		}
+		salary, _ = ParseSalaryRange(jobPost.SalaryRange)
+	}
+	if err := normalizeSalary(&salary); err != nil {
+		return false, err
+	}
+	jobPost.SetSalary(salary)
+	if jobPost.SalaryRange == "" {
+		jobPost.SalaryRange = salary.String()
+	}
+	return true, nil
+}
+
+// yearlyFactor converts an amount per period to an amount per year.
+func yearlyFactor(period string) (int64, bool) {
+	switch period {
+	case jobmodel.SalaryPeriodHour:
+		return hoursPerYear, true
+	case jobmodel.SalaryPeriodDay:
+		return daysPerYear, true
+	case jobmodel.SalaryPeriodMonth:
+		return 12, true
+	case jobmodel.SalaryPeriodYear:
+		return 1, true
+	}
+	return 0, false
+}
+
+// yearlySalarySQL is a salary column of job_posts converted to an amount per
+// year.
+func yearlySalarySQL(column string) string {
+	return fmt.Sprintf("(job_posts.%s * CASE job_posts.salary_period WHEN '%s' THEN %d WHEN '%s' THEN %d WHEN '%s' THEN 1 ELSE 12 END)",
+		column, jobmodel.SalaryPeriodHour, hoursPerYear, jobmodel.SalaryPeriodDay, daysPerYear, jobmodel.SalaryPeriodYear)
+}
+
+var (
+	salaryAmountPattern = regexp.MustCompile(`(\d[\d,]*(?:\.\d+)?)\s*(k|K|พัน|หมื่น|แสน|ล้าน)?`)
+	salaryMultipliers   = map[string]float64{"k": 1e3, "K": 1e3, "พัน": 1e3, "หมื่น": 1e4, "แสน": 1e5, "ล้าน": 1e6}
+
+	// Keywords are matched against the lower-cased text, in this order.
+	salaryCurrencies = []struct {
+		code     string
+		keywords []string
+	}{
+		{"SGD", []string{"sgd", "s$"}},
+		{"USD", []string{"usd", "us$", "$"}},
+		{"EUR", []string{"eur", "€"}},
+		{"GBP", []string{"gbp", "£"}},
+		{"JPY", []string{"jpy", "yen", "¥"}},
+		{"THB", []string{"thb", "baht", "บาท", "฿"}},
+	}
+	salaryPeriods = []struct {
+		period  string
+		pattern *regexp.Regexp
+	}{
+		{jobmodel.SalaryPeriodHour, regexp.MustCompile(`\b(hour|hourly|hr|hrs)\b|ชั่วโมง|ชม\.`)},
+		{jobmodel.SalaryPeriodDay, regexp.MustCompile(`\b(day|daily)\b|ต่อวัน|/วัน|วันละ`)},
+		{jobmodel.SalaryPeriodYear, regexp.MustCompile(`\b(year|yearly|annual|annually|yr|p\.a)\b|ต่อปี|/ปี|ปีละ`)},
+	}
+	salaryNegotiable = regexp.MustCompile(`\bnego|ตามตกลง|ต่อรอง|ตามโครงสร้าง`)
+	salaryFromOnly   = regexp.MustCompile(`\+|\b(from|starting|start|at least|min|minimum)\b|ขึ้นไป|เริ่มต้น|ขั้นต่ำ`)
+	salaryUpToOnly   = regexp.MustCompile(`\b(up to|max|maximum)\b|ไม่เกิน|สูงสุด`)
+)
+
+// ParseSalaryRange reads structured compensation from a free-text salary
+// range such as "30,000 - 45,000 บาท", "50k+ THB/month", "$20/hr" or
+// "Negotiable".  Amounts without a currency are THB and without a period are
+// per month.  It reports false when nothing in the text was understood.
+func ParseSalaryRange(text string) (jobmodel.Salary, bool) {
+	var salary jobmodel.Salary
+	lower := strings.ToLower(text)
+	salary.Negotiable = salaryNegotiable.MatchString(lower)
+
+	type amount struct {
+		value      float64
+		multiplier float64
+	}
+	var amounts []amount
+	for _, match := range salaryAmountPattern.FindAllStringSubmatch(text, 2) {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		multiplier := 1.0
+		if m, ok := salaryMultipliers[match[2]]; ok {
+			multiplier = m
+		}
+		amounts = append(amounts, amount{value, multiplier})
+	}
+	// "30-45k" means 30k to 45k.
+	if len(amounts) == 2 && amounts[0].multiplier == 1 && amounts[1].multiplier > 1 && amounts[0].value < 1000 {
+		amounts[0].multiplier = amounts[1].multiplier
+	}
+	bounds := make([]int, 0, len(amounts))
+	for _, a := range amounts {
+		v := math.Round(a.value * a.multiplier)
+		if v > maxSalaryAmount {
+			return jobmodel.Salary{}, false
+		}
+		bounds = append(bounds, int(v))
+	}
+
+	switch len(bounds) {
+	case 0:
+		return salary, salary.Negotiable
+	case 1:
+		switch {
+		case salaryUpToOnly.MatchString(lower):
+			salary.Max = &bounds[0]
+		case salaryFromOnly.MatchString(lower):
+			salary.Min = &bounds[0]
+		default:
+			salary.Min, salary.Max = &bounds[0], &bounds[0]
+		}
+	default:
+		if bounds[0] > bounds[1] {
+			bounds[0], bounds[1] = bounds[1], bounds[0]
+		}
+		salary.Min, salary.Max = &bounds[0], &bounds[1]
+	}
+
+	salary.Currency = jobmodel.DefaultSalaryCurrency
+	for _, c := range salaryCurrencies {
+		if containsAny(lower, c.keywords) {
+			salary.Currency = c.code
+			break
+		}
+	}
+	salary.Period = jobmodel.SalaryPeriodMonth
+	for _, p := range salaryPeriods {
+		if p.pattern.MatchString(lower) {
+			salary.Period = p.period
+			break
+		}
+	}
+	return salary, true
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// BackfillSalaries parses the SalaryRange of job posts saved before salaries
+// were structured.  Ranges that can't be parsed are left as free text.  It
+// returns how many posts were updated.
+func (s *JobService) BackfillSalaries() (int, error) {
+	var jobPosts []jobmodel.JobPost
+	err := s.DB.Unscoped().Select("id", "salary_range").
+		Where("salary_range <> '' AND salary_min IS NULL AND salary_max IS NULL AND salary_negotiable = ? AND (salary_period IS NULL OR salary_period = '')", false).
+		Find(&jobPosts).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	updated := 0
+	for i := range jobPosts {
+		salary, ok := ParseSalaryRange(jobPosts[i].SalaryRange)
+		if !ok || normalizeSalary(&salary) != nil {
+			continue
+		}
+		jobPosts[i].SetSalary(salary)
+		err := s.DB.Unscoped().Model(&jobPosts[i]).
+			Select(salaryColumns[1:]). // SalaryRange stays as written
+			Updates(&jobPosts[i]).Error
+		if err != nil {
+			return updated, fmt.Errorf("failed to update salary of job post %d: %w", jobPosts[i].ID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// saveSalary writes the salary columns of a job post, including the empty
+// ones that Updates would skip.
+func saveSalary(db *gorm.DB, jobPost *jobmodel.JobPost) error {
+	if err := db.Model(jobPost).Select(salaryColumns).Updates(jobPost).Error; err != nil {
+		return fmt.Errorf("failed to update salary: %w", err)
+	}
+	return nil
+}
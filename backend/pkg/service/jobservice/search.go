@@ -0,0 +1,150 @@
+package jobservice
+
+import (
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/search"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchSize = 20
+	maxSearchSize     = 50
+	// snippetWidth is the length, in characters, of a highlighted snippet.
+	snippetWidth = 160
+)
+
+// SearchJobPosts returns one page of the open job posts matching query, best
+// match first, with highlighted snippets of the matching fields.
+func (s *JobService) SearchJobPosts(query string, offset, limit int) (*jobmodel.JobPostSearchPage, error) {
+	if limit <= 0 {
+		limit = defaultSearchSize
+	} else if limit > maxSearchSize {
+		limit = maxSearchSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	hits, total, err := s.Search.Search(query, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &jobmodel.JobPostSearchPage{Results: []jobmodel.JobPostSearchResult{}, Total: total}
+	if next := offset + len(hits); int64(next) < total {
+		page.NextOffset = &next
+	}
+	if len(hits) == 0 {
+		return page, nil
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var jobPosts []jobmodel.JobPost
+	if err := s.DB.Preload("User").Scopes(openPosts(time.Now())).Where("id IN ?", ids).Find(&jobPosts).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	if err := s.attachCompanyProfiles(jobPosts); err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*jobmodel.JobPost, len(jobPosts))
+	for i := range jobPosts {
+		byID[jobPosts[i].ID] = &jobPosts[i]
+	}
+
+	// Hits for posts closed or deleted since they were indexed are dropped.
+	for _, hit := range hits {
+		jobPost, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		highlights := make(map[string]string)
+		for field, text := range map[string]string{
+			"title":        jobPost.Title,
+			"description":  jobPost.Description,
+			"job_position": jobPost.JobPosition,
+			"company_name": DisplayCompanyName(jobPost),
+		} {
+			if snippet := search.Highlight(text, query, snippetWidth); strings.Contains(snippet, "<mark>") {
+				highlights[field] = snippet
+			}
+		}
+		page.Results = append(page.Results, jobmodel.JobPostSearchResult{
+			JobPost:    *jobPost,
+			Score:      hit.Score,
+			Highlights: highlights,
+		})
+	}
+	return page, nil
+}
+
+// RebuildSearchIndex indexes every open job post.  It returns how many posts
+// were indexed.
+func (s *JobService) RebuildSearchIndex() (int, error) {
+	var jobPosts []jobmodel.JobPost
+	if err := s.DB.Preload("User").Scopes(openPosts(time.Now())).Find(&jobPosts).Error; err != nil {
+		return 0, fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	if err := s.attachCompanyProfiles(jobPosts); err != nil {
+		return 0, err
+	}
+	for i := range jobPosts {
+		if err := s.Search.Put(searchDocument(&jobPosts[i])); err != nil {
+			return i, err
+		}
+	}
+	return len(jobPosts), nil
+}
+
+// ReindexCompanyJobPosts refreshes the index entries of the posts of userID's
+// organization, after the company name changed.
+func (s *JobService) ReindexCompanyJobPosts(userID uint) error {
+	var ids []uint
+	if err := s.DB.Model(&jobmodel.JobPost{}).Scopes(s.organizationPosts(userID)).Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to retrieve job posts: %w", err)
+	}
+	for _, id := range ids {
+		s.reindexJobPost(id)
+	}
+	return nil
+}
+
+// ReindexJobPosts brings the index entries of the given job posts up to date,
+// after they were changed or deleted outside this service.
+func (s *JobService) ReindexJobPosts(ids ...uint) {
+	for _, id := range ids {
+		s.reindexJobPost(id)
+	}
+}
+
+// reindexJobPost brings the index entry of a job post up to date: open posts
+// are indexed, all others removed.  The index can be rebuilt from
+// the database, so failures are logged rather than failing the write.
+func (s *JobService) reindexJobPost(id uint) {
+	jobPost, err := s.GetJobPostByID(id)
+	if err != nil {
+		log.Printf("search: failed to load job post %d: %v", id, err)
+		return
+	}
+	if jobPost == nil || !jobPost.IsOpen(time.Now()) {
+		err = s.Search.Delete(id)
+	} else {
+		err = s.Search.Put(searchDocument(jobPost))
+	}
+	if err != nil {
+		log.Printf("search: %v", err)
+	}
+}
+
+func searchDocument(jobPost *jobmodel.JobPost) search.Document {
+	return search.Document{
+		ID:          jobPost.ID,
+		Title:       jobPost.Title,
+		Description: jobPost.Description,
+		Position:    jobPost.JobPosition,
+		Company:     DisplayCompanyName(jobPost),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"backend/pkg/pdfextractor"
 	"backend/pkg/service/geminiservice"
 	"backend/pkg/service/jobservice"
+	"backend/pkg/service/orgservice"
 	"errors"
 	"fmt"
 
@@ -140,10 +141,17 @@ func (s *MessageService) GetMessagesForUser(userID uint, loggedInUserID uint, lo
 		// Applicants can see all messages sent to or from them.
 		query = query.Where("sender_id = ? OR receiver_id = ?", userID, userID)
 	} else if loggedInUserType == "company" {
-		// Companies can only see messages related to their job postings.
-		query = query.Joins("JOIN job_applications ON messages.receiver_id = job_applications.user_id").
-			Joins("JOIN job_posts ON job_applications.job_id = job_posts.id").
-			Where("job_posts.user_id = ? AND (messages.sender_id = ? OR messages.receiver_id = ?)", loggedInUserID, userID, userID)
+		// Companies can only see messages with applicants to their
+		// organization's job posts, exchanged with any member of the team.
+		orgIDs := orgservice.MemberOrganizationIDs(s.DB, loggedInUserID)
+		jobIDs := s.DB.Model(&jobmodel.JobPost{}).Select("id").
+			Where("organization_id IN (?) OR (organization_id IS NULL AND user_id = ?)", orgIDs, loggedInUserID)
+		applied := s.DB.Model(&jobmodel.JobApplication{}).Select("1").
+			Where("job_applications.user_id = ? AND job_applications.job_id IN (?)", userID, jobIDs)
+		members := orgservice.MemberUserIDs(s.DB, loggedInUserID)
+		query = query.Where("sender_id = ? OR receiver_id = ?", userID, userID).
+			Where("sender_id IN (?) OR receiver_id IN (?) OR sender_id = ? OR receiver_id = ?", members, members, loggedInUserID, loggedInUserID).
+			Where("EXISTS (?)", applied)
 
 	} else {
 		// Handle other user types (or return an error)
@@ -0,0 +1,568 @@
+package orgservice
+
+import (
+	"backend/pkg/mailer"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoOrganization        = errors.New("user does not belong to an organization")
+	ErrNotCompany            = errors.New("only company accounts can join an organization")
+	ErrNotOwner              = errors.New("only organization owners can do this")
+	ErrInvalidRole           = errors.New("role must be owner, recruiter or viewer")
+	ErrMemberNotFound        = errors.New("member not found")
+	ErrLastOwner             = errors.New("an organization must keep at least one owner")
+	ErrAlreadyMember         = errors.New("user already belongs to an organization")
+	ErrInvitationNotFound    = errors.New("invalid or expired invitation")
+	ErrInvitationForOther    = errors.New("invitation was sent to a different email address")
+	ErrEmailNotVerified      = errors.New("verify your email address before accepting an invitation")
+	ErrOrganizationNameBlank = errors.New("name must not be empty")
+	ErrInvalidTemplate       = errors.New("filled_job_template must be at most 1000 characters and only use {applicant_name}, {job_title} and {company_name}")
+)
+
+// maxTemplateLength bounds FilledJobTemplate, in characters.
+const maxTemplateLength = 1000
+
+var templatePlaceholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+// IOrgService interface
+type IOrgService interface {
+	GetMembership(userID uint) (*orgmodel.OrganizationMember, error)
+	UpdateOrganization(userID uint, req *orgmodel.UpdateOrganizationRequest) (*orgmodel.Organization, error)
+	ListMembers(userID uint) ([]orgmodel.MemberResponse, error)
+	UpdateMemberRole(userID, memberUserID uint, role string) error
+	RemoveMember(userID, memberUserID uint) error
+	InviteMember(userID uint, req *orgmodel.InviteMemberRequest) (*orgmodel.OrganizationInvitation, error)
+	ListInvitations(userID uint) ([]orgmodel.OrganizationInvitation, error)
+	RevokeInvitation(userID, invitationID uint) error
+	AcceptInvitation(userID uint, token string) (*orgmodel.OrganizationMember, error)
+	BackfillOrganizations() (int, error)
+}
+
+type OrgService struct {
+	DB     *gorm.DB
+	Mailer mailer.IMailer
+}
+
+// NewOrgService creates a new OrgService.
+func NewOrgService(db *gorm.DB, mailer mailer.IMailer) *OrgService {
+	return &OrgService{DB: db, Mailer: mailer}
+}
+
+// CreatePersonalOrganization creates a one-person organization owned by the
+// company user.  Every company account starts out with one.
+func CreatePersonalOrganization(tx *gorm.DB, user *authmodel.User) (*orgmodel.OrganizationMember, error) {
+	name := user.Name
+	if user.CompanyName != nil && *user.CompanyName != "" {
+		name = *user.CompanyName
+	}
+	org := orgmodel.Organization{Name: name}
+	if err := tx.Create(&org).Error; err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	member := orgmodel.OrganizationMember{OrganizationID: org.ID, UserID: user.ID, Role: orgmodel.RoleOwner, Organization: org}
+	if err := tx.Create(&member).Error; err != nil {
+		return nil, fmt.Errorf("failed to add organization owner: %w", err)
+	}
+	return &member, nil
+}
+
+// FindMembership returns the user's membership with its organization, or nil
+// when the user doesn't belong to one.
+func FindMembership(db *gorm.DB, userID uint) (*orgmodel.OrganizationMember, error) {
+	var member orgmodel.OrganizationMember
+	err := db.Preload("Organization").Where("user_id = ?", userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve organization membership: %w", err)
+	}
+	return &member, nil
+}
+
+// EnsureMembership returns the user's membership, creating a personal
+// organization for a company user who has none (e.g. after leaving a team).
+func EnsureMembership(db *gorm.DB, userID uint) (*orgmodel.OrganizationMember, error) {
+	member, err := FindMembership(db, userID)
+	if err != nil || member != nil {
+		return member, err
+	}
+
+	var user authmodel.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user.UserType != authmodel.UserTypeCompany {
+		return nil, ErrNotCompany
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		member, err = CreatePersonalOrganization(tx, &user)
+		return err
+	})
+	return member, err
+}
+
+// MemberOrganizationIDs is a subquery selecting the organization of userID,
+// for use in "organization_id IN (?)" conditions.
+func MemberOrganizationIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&orgmodel.OrganizationMember{}).Select("organization_id").Where("user_id = ?", userID)
+}
+
+// MemberUserIDs is a subquery selecting every member of the organization
+// userID belongs to, including userID.
+func MemberUserIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&orgmodel.OrganizationMember{}).Select("user_id").
+		Where("organization_id IN (?)", MemberOrganizationIDs(db, userID))
+}
+
+// GetMembership returns the caller's membership with its organization.
+func (s *OrgService) GetMembership(userID uint) (*orgmodel.OrganizationMember, error) {
+	member, err := FindMembership(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNoOrganization
+	}
+	return member, nil
+}
+
+// UpdateOrganization renames the caller's organization and changes its
+// settings.  Nil fields are left unchanged.
+func (s *OrgService) UpdateOrganization(userID uint, req *orgmodel.UpdateOrganizationRequest) (*orgmodel.Organization, error) {
+	member, err := s.ownerMembership(userID)
+	if err != nil {
+		return nil, err
+	}
+	org := member.Organization
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrOrganizationNameBlank
+		}
+		org.Name = name
+	}
+	if req.AutoCloseFilledJobs != nil {
+		org.AutoCloseFilledJobs = *req.AutoCloseFilledJobs
+	}
+	if req.NotifyPendingOnFilled != nil {
+		org.NotifyPendingOnFilled = *req.NotifyPendingOnFilled
+	}
+	if req.FilledJobTemplate != nil {
+		template := strings.TrimSpace(*req.FilledJobTemplate)
+		if err := validateTemplate(template); err != nil {
+			return nil, err
+		}
+		org.FilledJobTemplate = template
+	}
+	if err := s.DB.Save(&org).Error; err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return &org, nil
+}
+
+// ListMembers lists the members of the caller's organization.
+func (s *OrgService) ListMembers(userID uint) ([]orgmodel.MemberResponse, error) {
+	member, err := s.GetMembership(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []orgmodel.OrganizationMember
+	err = s.DB.Preload("User").
+		Where("organization_id = ?", member.OrganizationID).
+		Order("created_at ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve members: %w", err)
+	}
+
+	response := make([]orgmodel.MemberResponse, 0, len(members))
+	for _, m := range members {
+		response = append(response, orgmodel.MemberResponse{
+			UserID:   m.UserID,
+			Name:     m.User.Name,
+			Email:    m.User.Email,
+			Role:     m.Role,
+			JoinedAt: m.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// UpdateMemberRole changes the role of a member.  Owners only.
+func (s *OrgService) UpdateMemberRole(userID, memberUserID uint, role string) error {
+	if !orgmodel.ValidRole(role) {
+		return ErrInvalidRole
+	}
+	owner, err := s.ownerMembership(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		target, err := findTeammate(tx, owner.OrganizationID, memberUserID)
+		if err != nil {
+			return err
+		}
+		if target.Role == orgmodel.RoleOwner && role != orgmodel.RoleOwner {
+			if err := ensureAnotherOwner(tx, target); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(target).Update("role", role).Error; err != nil {
+			return fmt.Errorf("failed to update member role: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveMember removes a member from the caller's organization.  Owners can
+// remove anyone; every member can remove themselves (leave).
+func (s *OrgService) RemoveMember(userID, memberUserID uint) error {
+	caller, err := s.GetMembership(userID)
+	if err != nil {
+		return err
+	}
+	if userID != memberUserID && caller.Role != orgmodel.RoleOwner {
+		return ErrNotOwner
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		target, err := findTeammate(tx, caller.OrganizationID, memberUserID)
+		if err != nil {
+			return err
+		}
+		if target.Role == orgmodel.RoleOwner {
+			if err := ensureAnotherOwner(tx, target); err != nil {
+				return err
+			}
+		}
+		// Job posts stay with the organization; the removed user gets a new
+		// personal organization the next time they need one.
+		if err := tx.Delete(target).Error; err != nil {
+			return fmt.Errorf("failed to remove member: %w", err)
+		}
+		return nil
+	})
+}
+
+// InviteMember invites an email address to the caller's organization and
+// emails the invitation token.  Owners only.
+func (s *OrgService) InviteMember(userID uint, req *orgmodel.InviteMemberRequest) (*orgmodel.OrganizationInvitation, error) {
+	if !orgmodel.ValidRole(req.Role) {
+		return nil, ErrInvalidRole
+	}
+	owner, err := s.ownerMembership(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	invitation := orgmodel.OrganizationInvitation{
+		OrganizationID: owner.OrganizationID,
+		Email:          strings.TrimSpace(req.Email),
+		Role:           req.Role,
+		TokenHash:      hashToken(token),
+		InvitedByID:    userID,
+		ExpiresAt:      time.Now().Add(invitationTTL),
+	}
+	if err := s.DB.Create(&invitation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := s.sendInvitationEmail(&invitation, owner.Organization.Name, token); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListInvitations lists the pending invitations of the caller's organization.
+func (s *OrgService) ListInvitations(userID uint) ([]orgmodel.OrganizationInvitation, error) {
+	owner, err := s.ownerMembership(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invitations []orgmodel.OrganizationInvitation
+	err = s.DB.Where("organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", owner.OrganizationID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation cancels a pending invitation.  Owners only.
+func (s *OrgService) RevokeInvitation(userID, invitationID uint) error {
+	owner, err := s.ownerMembership(userID)
+	if err != nil {
+		return err
+	}
+
+	result := s.DB.Model(&orgmodel.OrganizationInvitation{}).
+		Where("id = ? AND organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationID, owner.OrganizationID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds the caller to the inviting organization.  The caller
+// must be a company user with the invited email address, verified; anyone
+// can register with an address they don't own.  A personal
+// organization with nothing in it is dropped; a user who already works in a
+// real team has to leave it first.
+func (s *OrgService) AcceptInvitation(userID uint, token string) (*orgmodel.OrganizationMember, error) {
+	var user authmodel.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user.UserType != authmodel.UserTypeCompany {
+		return nil, ErrNotCompany
+	}
+
+	var invitation orgmodel.OrganizationInvitation
+	err := s.DB.Preload("Organization").
+		Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvitationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve invitation: %w", err)
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationForOther
+	}
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	var member orgmodel.OrganizationMember
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := FindMembership(tx, userID)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			if current.OrganizationID == invitation.OrganizationID {
+				return ErrAlreadyMember
+			}
+			if err := dropPersonalOrganization(tx, current); err != nil {
+				return err
+			}
+		}
+
+		// The accepted_at guard makes an invitation usable only once.
+		result := tx.Model(&orgmodel.OrganizationInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to accept invitation: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationNotFound
+		}
+
+		member = orgmodel.OrganizationMember{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         userID,
+			Role:           invitation.Role,
+			Organization:   invitation.Organization,
+		}
+		if err := tx.Create(&member).Error; err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// BackfillOrganizations gives every company user without an organization a
+// personal one and assigns their job posts to it.  The two-factor policy that
+// used to live on the company profile is carried over.  It returns how many
+// organizations were created.
+func (s *OrgService) BackfillOrganizations() (int, error) {
+	var users []authmodel.User
+	err := s.DB.Where("user_type = ? AND id NOT IN (?)", authmodel.UserTypeCompany,
+		s.DB.Model(&orgmodel.OrganizationMember{}).Select("user_id")).
+		Find(&users).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find companies without an organization: %w", err)
+	}
+
+	carryMFAPolicy := s.DB.Migrator().HasColumn(&authmodel.CompanyProfile{}, "mfa_required")
+	created := 0
+	for i := range users {
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			member, err := CreatePersonalOrganization(tx, &users[i])
+			if err != nil {
+				return err
+			}
+			if !carryMFAPolicy {
+				return nil
+			}
+			return tx.Model(&orgmodel.Organization{}).
+				Where("id = ? AND EXISTS (SELECT 1 FROM company_profiles WHERE user_id = ? AND mfa_required = ?)", member.OrganizationID, users[i].ID, true).
+				Update("mfa_required", true).Error
+		})
+		if err != nil {
+			return created, fmt.Errorf("failed to create organization for user %d: %w", users[i].ID, err)
+		}
+		created++
+	}
+
+	err = s.DB.Exec("UPDATE job_posts JOIN organization_members ON organization_members.user_id = job_posts.user_id " +
+		"SET job_posts.organization_id = organization_members.organization_id " +
+		"WHERE job_posts.organization_id IS NULL").Error
+	if err != nil {
+		return created, fmt.Errorf("failed to assign job posts to organizations: %w", err)
+	}
+	return created, nil
+}
+
+func (s *OrgService) ownerMembership(userID uint) (*orgmodel.OrganizationMember, error) {
+	member, err := s.GetMembership(userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != orgmodel.RoleOwner {
+		return nil, ErrNotOwner
+	}
+	return member, nil
+}
+
+func findTeammate(tx *gorm.DB, orgID, userID uint) (*orgmodel.OrganizationMember, error) {
+	var member orgmodel.OrganizationMember
+	err := tx.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMemberNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retrieve member: %w", err)
+	}
+	return &member, nil
+}
+
+// ensureAnotherOwner fails if member is the organization's only owner.  It
+// locks every owner row, the member's included, so two owners demoting or
+// removing each other at the same time can't leave the organization without
+// one.
+func ensureAnotherOwner(tx *gorm.DB, member *orgmodel.OrganizationMember) error {
+	var ownerIDs []uint
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Model(&orgmodel.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", member.OrganizationID, orgmodel.RoleOwner).
+		Order("id").
+		Pluck("id", &ownerIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to lock owners: %w", err)
+	}
+	for _, id := range ownerIDs {
+		if id != member.ID {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+// dropPersonalOrganization deletes an organization that only has the given
+// member and no job posts, so the member can join another one.
+func dropPersonalOrganization(tx *gorm.DB, member *orgmodel.OrganizationMember) error {
+	var members, jobPosts int64
+	if err := tx.Model(&orgmodel.OrganizationMember{}).Where("organization_id = ?", member.OrganizationID).Count(&members).Error; err != nil {
+		return fmt.Errorf("failed to count members: %w", err)
+	}
+	if err := tx.Unscoped().Model(&jobmodel.JobPost{}).Where("organization_id = ?", member.OrganizationID).Count(&jobPosts).Error; err != nil {
+		return fmt.Errorf("failed to count job posts: %w", err)
+	}
+	if members > 1 || jobPosts > 0 {
+		return ErrAlreadyMember
+	}
+
+	if err := tx.Delete(member).Error; err != nil {
+		return fmt.Errorf("failed to leave organization: %w", err)
+	}
+	if err := tx.Where("organization_id = ?", member.OrganizationID).Delete(&orgmodel.OrganizationInvitation{}).Error; err != nil {
+		return fmt.Errorf("failed to delete invitations: %w", err)
+	}
+	if err := tx.Delete(&orgmodel.Organization{}, member.OrganizationID).Error; err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	return nil
+}
+
+// sendInvitationEmail sends the invitation token to the invited address.
+func (s *OrgService) sendInvitationEmail(invitation *orgmodel.OrganizationInvitation, orgName, token string) error {
+	if s.Mailer == nil {
+		return fmt.Errorf("no mailer configured")
+	}
+	body := fmt.Sprintf("You have been invited to join %s as %s.\n\n"+
+		"Sign in with a company account for this email address and accept the invitation with this code:\n\n%s\n\n"+
+		"The invitation expires in %d days.",
+		orgName, invitation.Role, token, int(invitationTTL.Hours()/24))
+	return s.Mailer.Send(invitation.Email, "Invitation to join "+orgName, body)
+}
+
+// randomToken returns a random, URL-safe opaque token.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of an opaque token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateTemplate checks the length and the placeholders of a notification
+// template.
+func validateTemplate(template string) error {
+	if utf8.RuneCountInString(template) > maxTemplateLength {
+		return ErrInvalidTemplate
+	}
+	for _, placeholder := range templatePlaceholder.FindAllString(template, -1) {
+		known := false
+		for _, p := range orgmodel.FilledJobPlaceholders {
+			if p == placeholder {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return ErrInvalidTemplate
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package orgservice
+
+import (
+	"backend/pkg/mailer"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/orgmodel"
+	"backend/pkg/testdb"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func newTestService(t *testing.T) (*OrgService, *mailer.MemoryMailer) {
+	t.Helper()
+	m := mailer.NewMemoryMailer()
+	return NewOrgService(testdb.New(t), m), m
+}
+
+// createCompanyUser stores a company user with a personal organization.
+func createCompanyUser(t *testing.T, s *OrgService, email string, verified bool) *authmodel.User {
+	t.Helper()
+	user := &authmodel.User{Name: email, Email: email, Password: "x", UserType: authmodel.UserTypeCompany, EmailVerified: verified}
+	if err := s.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreatePersonalOrganization(s.DB, user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// invite has owner invite email and returns the token from the invitation
+// email.
+func invite(t *testing.T, s *OrgService, m *mailer.MemoryMailer, owner *authmodel.User, email, role string) string {
+	t.Helper()
+	if _, err := s.InviteMember(owner.ID, &orgmodel.InviteMemberRequest{Email: email, Role: role}); err != nil {
+		t.Fatal(err)
+	}
+	mail, ok := m.Last(email)
+	if !ok {
+		t.Fatalf("no invitation was sent to %s", email)
+	}
+	token := regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`).FindString(mail.Body)
+	if token == "" {
+		t.Fatalf("invitation %q has no token", mail.Body)
+	}
+	return token
+}
+
+func TestAcceptInvitationNeedsVerifiedEmail(t *testing.T) {
+	s, m := newTestService(t)
+	owner := createCompanyUser(t, s, "owner@example.com", true)
+	invitee := createCompanyUser(t, s, "invitee@example.com", false)
+	token := invite(t, s, m, owner, invitee.Email, orgmodel.RoleOwner)
+
+	if _, err := s.AcceptInvitation(invitee.ID, token); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("AcceptInvitation() by an unverified user error = %v, want %v", err, ErrEmailNotVerified)
+	}
+
+	if err := s.DB.Model(invitee).Update("email_verified", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	member, err := s.AcceptInvitation(invitee.ID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerMember, err := s.GetMembership(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.OrganizationID != ownerMember.OrganizationID || member.Role != orgmodel.RoleOwner {
+		t.Errorf("member = organization %d as %s, want organization %d as %s",
+			member.OrganizationID, member.Role, ownerMember.OrganizationID, orgmodel.RoleOwner)
+	}
+}
+
+func TestAcceptInvitationForAnotherEmail(t *testing.T) {
+	s, m := newTestService(t)
+	owner := createCompanyUser(t, s, "owner@example.com", true)
+	other := createCompanyUser(t, s, "other@example.com", true)
+	token := invite(t, s, m, owner, "invitee@example.com", orgmodel.RoleRecruiter)
+
+	if _, err := s.AcceptInvitation(other.ID, token); !errors.Is(err, ErrInvitationForOther) {
+		t.Errorf("AcceptInvitation() error = %v, want %v", err, ErrInvitationForOther)
+	}
+}
+
+func TestLastOwnerCantStepDown(t *testing.T) {
+	s, m := newTestService(t)
+	owner := createCompanyUser(t, s, "owner@example.com", true)
+	second := createCompanyUser(t, s, "second@example.com", true)
+	if _, err := s.AcceptInvitation(second.ID, invite(t, s, m, owner, second.Email, orgmodel.RoleOwner)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateMemberRole(second.ID, owner.ID, orgmodel.RoleRecruiter); err != nil {
+		t.Fatalf("UpdateMemberRole() with another owner left error = %v", err)
+	}
+	if err := s.UpdateMemberRole(second.ID, second.ID, orgmodel.RoleRecruiter); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("UpdateMemberRole() of the last owner error = %v, want %v", err, ErrLastOwner)
+	}
+	if err := s.RemoveMember(second.ID, second.ID); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("RemoveMember() of the last owner error = %v, want %v", err, ErrLastOwner)
+	}
+}
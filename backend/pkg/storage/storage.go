@@ -0,0 +1,83 @@
+// Package storage keeps uploaded files such as resumes and profile images.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// IStorage stores files under slash-separated keys like "resumes/<id>.pdf".
+type IStorage interface {
+	Save(key string, data []byte) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// Path returns the local file of key, for tools that can only read files
+	// from disk (e.g. the PDF extractor).
+	Path(key string) string
+}
+
+// LocalStorage stores files below a directory on the local disk.
+type LocalStorage struct {
+	Root string
+}
+
+// NewLocalStorage creates a new LocalStorage rooted at root.
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{Root: root}
+}
+
+// Save writes data to key, creating parent directories as needed.
+func (s *LocalStorage) Save(key string, data []byte) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	filePath := s.Path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+	return nil
+}
+
+// Open opens the file stored at key.  A missing file returns an error
+// satisfying errors.Is(err, os.ErrNotExist).
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	return os.Open(s.Path(key))
+}
+
+// Delete removes the file stored at key.  Deleting a missing file is not an
+// error.
+func (s *LocalStorage) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	if err := os.Remove(s.Path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Path returns the local file of key.
+func (s *LocalStorage) Path(key string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(key))
+}
+
+// validKey rejects keys that could escape the storage root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	return path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
@@ -0,0 +1,83 @@
+// Package testdb opens throwaway databases for the service tests.
+package testdb
+
+import (
+	"backend/pkg/model/auditmodel"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/model/jobmodel"
+	"backend/pkg/model/orgmodel"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// dialector is SQLite with MySQL's ENUM columns as plain text.
+type dialector struct {
+	sqlite.Dialector
+}
+
+func (d dialector) DataTypeOf(field *schema.Field) string {
+	if strings.HasPrefix(strings.ToLower(string(field.DataType)), "enum(") {
+		return "text"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqlite.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+// New opens an in-memory SQLite database with the application's tables.  It
+// is closed when the test ends.  SQLite has no row locks, so tests can't
+// catch missing ones.
+func New(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	db, err := gorm.Open(dialector{sqlite.Dialector{DSN: dsn}}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, so transactions see each other's writes in order.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(
+		&authmodel.User{},
+		&authmodel.CompanyProfile{},
+		&authmodel.Message{},
+		&authmodel.Notification{},
+		&authmodel.PasswordResetOTP{},
+		&authmodel.RefreshToken{},
+		&authmodel.LoginThrottle{},
+		&authmodel.LockoutEvent{},
+		&authmodel.MFARecoveryCode{},
+		&authmodel.APIKey{},
+		&jobmodel.JobPost{},
+		&jobmodel.JobApplication{},
+		&jobmodel.JobPostRevision{},
+		&jobmodel.SavedJob{},
+		&jobmodel.Message{},
+		&orgmodel.Organization{},
+		&orgmodel.OrganizationMember{},
+		&orgmodel.OrganizationInvitation{},
+		&auditmodel.AuditLog{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
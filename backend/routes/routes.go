@@ -1,24 +1,55 @@
 package routes
 
 import (
+	"backend/handler/accounthandler"
+	"backend/handler/audithandler"
 	"backend/handler/authhandler"
+	"backend/handler/companyhandler"
+	"backend/handler/imagehandler"
 	"backend/handler/jobhandler"
 	"backend/handler/messagehandler"
+	"backend/handler/orghandler"
 	"backend/pkg/middleware"
+	"backend/pkg/model/authmodel"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // RegisterAuthRoutes sets up routes for authentication.
 func RegisterAuthRoutes(app *fiber.App, authHandler *authhandler.AuthHandler) {
+	app.Get("/.well-known/jwks.json", authHandler.JWKS) // GET /.well-known/jwks.json
 	authGroup := app.Group("/auth")
-	authGroup.Post("/register", authHandler.Register)            // POST /auth/register
-	authGroup.Post("/login", authHandler.Login)                  // POST /auth/login
-	authGroup.Post("/reset-password", authHandler.ResetPassword) // POST /auth/reset-password
+	authGroup.Post("/register", authHandler.Register)                          // POST /auth/register
+	authGroup.Post("/login", authHandler.Login)                                // POST /auth/login
+	authGroup.Post("/firebase", authHandler.FirebaseLogin)                     // POST /auth/firebase
+	authGroup.Post("/refresh", authHandler.Refresh)                            // POST /auth/refresh
+	authGroup.Post("/logout", authHandler.Logout)                              // POST /auth/logout
+	authGroup.Post("/request-reset", authHandler.RequestPasswordReset)         // POST /auth/request-reset
+	authGroup.Post("/verify-otp", authHandler.VerifyOTP)                       // POST /auth/verify-otp
+	authGroup.Post("/reset-password", authHandler.ResetPassword)               // POST /auth/reset-password (requires a verified OTP)
+	authGroup.Get("/verify-email", authHandler.VerifyEmail)                    // GET /auth/verify-email?token=... (link from the verification email)
+	authGroup.Post("/mfa/verify", authHandler.VerifyMFA)                       // POST /auth/mfa/verify (second login step)
+	authGroup.Post("/mfa/enroll", authHandler.BeginMFAEnrollmentWithChallenge) // POST /auth/mfa/enroll (enrolment required by policy)
+	authGroup.Post("/mfa/confirm", authHandler.CompleteMFAEnrollment)          // POST /auth/mfa/confirm
 	userGroup := app.Group("/api/user")
-	userGroup.Use(middleware.AuthMiddleware)              // Apply JWT middleware
-	userGroup.Get("/profile", authHandler.GetUserProfile) // GET /api/user/profile
+	userGroup.Use(middleware.AuthMiddleware)                                                                   // Apply JWT middleware
+	userGroup.Get("/profile", middleware.RequireScope(authmodel.ScopeProfileRead), authHandler.GetUserProfile) // GET /api/user/profile
 	userGroup.Put("/profile", authHandler.UpdateProfile)
+	userGroup.Post("/logout-all", authHandler.LogoutAll)                        // POST /api/user/logout-all
+	userGroup.Post("/verify-email/resend", authHandler.ResendVerificationEmail) // POST /api/user/verify-email/resend
+
+	// API keys can only be managed with a JWT, never with another key.
+	userGroup.Post("/api-keys", authHandler.CreateAPIKey)       // POST /api/user/api-keys (the key is only shown once)
+	userGroup.Get("/api-keys", authHandler.ListAPIKeys)         // GET /api/user/api-keys
+	userGroup.Delete("/api-keys/:id", authHandler.RevokeAPIKey) // DELETE /api/user/api-keys/:id
+
+	// Two-factor authentication (company accounts)
+	mfaGroup := userGroup.Group("/mfa", middleware.RequireRole(authmodel.UserTypeCompany))
+	mfaGroup.Post("/enroll", authHandler.BeginMFAEnrollment)              // POST /api/user/mfa/enroll
+	mfaGroup.Post("/confirm", authHandler.ConfirmMFAEnrollment)           // POST /api/user/mfa/confirm
+	mfaGroup.Post("/disable", authHandler.DisableMFA)                     // POST /api/user/mfa/disable
+	mfaGroup.Post("/recovery-codes", authHandler.RegenerateRecoveryCodes) // POST /api/user/mfa/recovery-codes
+	mfaGroup.Put("/policy", authHandler.SetMFAPolicy)                     // PUT /api/user/mfa/policy
 }
 
 // RegisterJobRoutes sets up routes for job-related operations.
@@ -26,43 +57,153 @@ func RegisterJobRoutes(app *fiber.App, jobHandler *jobhandler.JobHandler) {
 	jobGroup := app.Group("/api/jobs")
 	jobGroup.Use(middleware.AuthMiddleware)
 
+	company := middleware.RequireRole(authmodel.UserTypeCompany)
+	applicant := middleware.RequireRole(authmodel.UserTypeApplicant)
+	anyUser := middleware.RequireRole(authmodel.UserTypeApplicant, authmodel.UserTypeCompany)
+
+	// Scopes for API key requests; they come before the role checks.
+	jobsRead := middleware.RequireScope(authmodel.ScopeJobsRead)
+	jobsWrite := middleware.RequireScope(authmodel.ScopeJobsWrite)
+	applicationsRead := middleware.RequireScope(authmodel.ScopeApplicationsRead)
+	applicationsWrite := middleware.RequireScope(authmodel.ScopeApplicationsWrite)
+
+	// Bulk import and export (before /:id, which would match /export)
+	jobGroup.Post("/import", jobsWrite, company, middleware.RequireVerifiedEmail, jobHandler.ImportJobPosts) // POST /api/jobs/import?dry_run= (owners and recruiters)
+	jobGroup.Get("/export", jobsRead, company, jobHandler.ExportJobPosts)                                    // GET /api/jobs/export?format=csv|json (organization members)
+
 	// Job Post Routes
-	jobGroup.Post("/", jobHandler.CreateJobPost)                          // POST /api/jobs
-	jobGroup.Get("/:id", jobHandler.GetJobPost)                           // GET /api/jobs/:id
-	jobGroup.Get("/user/:userId", jobHandler.ListJobPostsByUserID)        // GET /api/jobs/user/:userId
-	jobGroup.Put("/:id", jobHandler.UpdateJobPost)                        // PUT /api/jobs/:id
-	jobGroup.Delete("/:id", jobHandler.DeleteJobPost)                     // DELETE /api/jobs/:id
-	jobGroup.Get("/", jobHandler.ListJobPosts)                            // GET /api/jobs
-	jobGroup.Get("/company/:companyId", jobHandler.ListJobPostsByCompany) // GET /api/jobs/company/:companyId  (Note: companyId is actually UserId)
-	jobGroup.Get("/open", jobHandler.ListOpenJobPosts)                    // GET /api/jobs/open
-	jobGroup.Get("/closed", jobHandler.ListClosedJobPosts)                // GET /api/jobs/closed
+	jobGroup.Post("/", jobsWrite, company, middleware.RequireVerifiedEmail, jobHandler.CreateJobPost) // POST /api/jobs (verified email required)
+	jobGroup.Get("/search", jobsRead, anyUser, jobHandler.SearchJobPosts)                             // GET /api/jobs/search?q= (before /:id, which would match it)
+	jobGroup.Get("/:id", jobsRead, anyUser, jobHandler.GetJobPost)                                    // GET /api/jobs/:id
+	jobGroup.Get("/user/:userId", jobsRead, anyUser, jobHandler.ListJobPostsByUserID)                 // GET /api/jobs/user/:userId
+	jobGroup.Put("/:id", jobsWrite, company, jobHandler.UpdateJobPost)                                // PUT /api/jobs/:id (owners and recruiters)
+	jobGroup.Delete("/:id", jobsWrite, company, jobHandler.DeleteJobPost)                             // DELETE /api/jobs/:id (owners and recruiters)
+	jobGroup.Put("/:id/state", jobsWrite, company, jobHandler.ChangeJobPostState)                     // PUT /api/jobs/:id/state (owners and recruiters)
+	jobGroup.Get("/:id/revisions", jobsRead, company, jobHandler.ListJobPostRevisions)                // GET /api/jobs/:id/revisions?from=&to= (organization members)
+	jobGroup.Get("/", jobsRead, anyUser, jobHandler.ListJobPosts)                                     // GET /api/jobs
+	jobGroup.Get("/company/:companyId", jobsRead, anyUser, jobHandler.ListJobPostsByCompany)          // GET /api/jobs/company/:companyId  (Note: companyId is actually UserId)
+	jobGroup.Get("/open", jobsRead, anyUser, jobHandler.ListOpenJobPosts)                             // GET /api/jobs/open
+	jobGroup.Get("/closed", jobsRead, anyUser, jobHandler.ListClosedJobPosts)                         // GET /api/jobs/closed
 
 	// Job Application Routes
-	jobGroup.Post("/:jobId/apply", jobHandler.CreateJobApplication)                   // POST /api/jobs/:jobId/apply
-	jobGroup.Get("/applications/:id", jobHandler.GetJobApplication)                   // GET /api/jobs/applications/:id
-	jobGroup.Put("/applications/:id", jobHandler.UpdateJobApplication)                // PUT /api/jobs/applications/:id
-	jobGroup.Get("/:jobId/applications", jobHandler.ListJobApplicationsForJob)        // GET /api/jobs/:jobId/applications
-	jobGroup.Get("/user/:userId/applications", jobHandler.ListJobApplicationsForUser) // GET /api/jobs/user/:userId/applications
-	jobGroup.Get("/applications", jobHandler.ListJobApplications)                     // GET /api/jobs/applications?status=pending  (and other status values, or no status for all)
-
-	// Saved Job Routes
-	jobGroup.Post("/user/:userId/save/:jobId", jobHandler.SaveJob)           // POST /api/jobs/user/:userId/save/:jobId
-	jobGroup.Delete("/user/:userId/unsave/:jobId", jobHandler.UnsaveJob)     // DELETE /api/jobs/user/:userId/unsave/:jobId
-	jobGroup.Get("/user/:userId/saved", jobHandler.ListSavedJobs)            // GET /api/jobs/user/:userId/saved
-	jobGroup.Get("/user/:userId/saved/:jobId", jobHandler.CheckIfJobIsSaved) // GET /api/jobs/user/:userId/saved/:jobId
+	jobGroup.Post("/:jobId/apply", applicationsWrite, applicant, middleware.RequireVerifiedEmail, jobHandler.CreateJobApplication) // POST /api/jobs/:jobId/apply (verified email required)
+	jobGroup.Get("/applications/:id", applicationsRead, anyUser, jobHandler.GetJobApplication)                                     // GET /api/jobs/applications/:id (applicant or job owner)
+	jobGroup.Put("/applications/:id", applicationsWrite, company, jobHandler.UpdateJobApplication)                                 // PUT /api/jobs/applications/:id (job owner only)
+	jobGroup.Delete("/applications/:id", applicationsWrite, applicant, jobHandler.WithdrawJobApplication)                          // DELETE /api/jobs/applications/:id (the applicant, while pending)
+	jobGroup.Get("/:jobId/applications", applicationsRead, company, jobHandler.ListJobApplicationsForJob)                          // GET /api/jobs/:jobId/applications (job owner only)
+	jobGroup.Get("/user/:userId/applications", applicationsRead, anyUser, jobHandler.ListJobApplicationsForUser)                   // GET /api/jobs/user/:userId/applications (self, or a company for its own jobs)
+	jobGroup.Get("/applications", applicationsRead, anyUser, jobHandler.ListJobApplications)                                       // GET /api/jobs/applications?status=pending  (and other status values, or no status for all)
+
+	// Saved Job Routes (bookmarks are private: the :userId must be the caller)
+	self := middleware.RequireSelf("userId")
+	jobGroup.Post("/user/:userId/save/:jobId", anyUser, self, jobHandler.SaveJob)           // POST /api/jobs/user/:userId/save/:jobId
+	jobGroup.Delete("/user/:userId/unsave/:jobId", anyUser, self, jobHandler.UnsaveJob)     // DELETE /api/jobs/user/:userId/unsave/:jobId
+	jobGroup.Get("/user/:userId/saved", anyUser, self, jobHandler.ListSavedJobs)            // GET /api/jobs/user/:userId/saved
+	jobGroup.Get("/user/:userId/saved/:jobId", anyUser, self, jobHandler.CheckIfJobIsSaved) // GET /api/jobs/user/:userId/saved/:jobId
+
+	// "Me" routes: the user always comes from the token.  The middleware goes
+	// on each route: group middleware matches by prefix, and a Use on /api/me
+	// would also run for /api/messages.
+	meGroup := app.Group("/api/me")
+	me := []fiber.Handler{middleware.AuthMiddleware, anyUser}
+	meGroup.Get("/saved", append(me, jobHandler.ListSavedJobs)...)                     // GET /api/me/saved
+	meGroup.Post("/saved/:jobId", append(me, jobHandler.SaveJob)...)                   // POST /api/me/saved/:jobId
+	meGroup.Get("/saved/:jobId", append(me, jobHandler.CheckIfJobIsSaved)...)          // GET /api/me/saved/:jobId
+	meGroup.Delete("/saved/:jobId", append(me, jobHandler.UnsaveJob)...)               // DELETE /api/me/saved/:jobId
+	meGroup.Get("/applications", append(me, jobHandler.ListJobApplicationsForUser)...) // GET /api/me/applications
+}
+
+// RegisterFeedRoutes sets up the public, read-only job feeds for job
+// aggregators and search engines.
+func RegisterFeedRoutes(app *fiber.App, jobHandler *jobhandler.JobHandler) {
+	feedGroup := app.Group("/feeds/jobs")
+	feedGroup.Get("/rss", jobHandler.JobFeedRSS)              // GET /feeds/jobs/rss (RSS 2.0)
+	feedGroup.Get("/atom", jobHandler.JobFeedAtom)            // GET /feeds/jobs/atom
+	feedGroup.Get("/:id/jsonld", jobHandler.JobPostingJSONLD) // GET /feeds/jobs/:id/jsonld (schema.org JobPosting)
+}
+
+// RegisterCompanyRoutes sets up routes for company profiles.
+func RegisterCompanyRoutes(app *fiber.App, companyHandler *companyhandler.CompanyHandler) {
+	companyGroup := app.Group("/api/companies")
+
+	// The caller's own profile (company accounts only)
+	me := []fiber.Handler{middleware.AuthMiddleware, middleware.RequireRole(authmodel.UserTypeCompany)}
+	companyGroup.Get("/me", append(me, companyHandler.GetMyProfile)...)       // GET /api/companies/me
+	companyGroup.Post("/me", append(me, companyHandler.CreateMyProfile)...)   // POST /api/companies/me
+	companyGroup.Put("/me", append(me, companyHandler.UpdateMyProfile)...)    // PUT /api/companies/me
+	companyGroup.Delete("/me", append(me, companyHandler.DeleteMyProfile)...) // DELETE /api/companies/me
+
+	companyGroup.Get("/:id", companyHandler.GetCompany) // GET /api/companies/:id (public, includes open jobs)
+}
+
+// RegisterOrgRoutes sets up routes for the caller's organization (company
+// accounts only).  Owner-only actions are enforced by the service.
+func RegisterOrgRoutes(app *fiber.App, orgHandler *orghandler.OrgHandler) {
+	orgGroup := app.Group("/api/org")
+	orgGroup.Use(middleware.AuthMiddleware)
+	orgGroup.Use(middleware.RequireRole(authmodel.UserTypeCompany))
+	orgGroup.Get("/", orgHandler.GetMyOrganization)                   // GET /api/org
+	orgGroup.Put("/", orgHandler.UpdateMyOrganization)                // PUT /api/org (owner only)
+	orgGroup.Get("/members", orgHandler.ListMembers)                  // GET /api/org/members
+	orgGroup.Put("/members/:userId", orgHandler.UpdateMember)         // PUT /api/org/members/:userId (owner only)
+	orgGroup.Delete("/members/:userId", orgHandler.RemoveMember)      // DELETE /api/org/members/:userId (owner, or the member leaving)
+	orgGroup.Post("/invitations", orgHandler.InviteMember)            // POST /api/org/invitations (owner only)
+	orgGroup.Get("/invitations", orgHandler.ListInvitations)          // GET /api/org/invitations (owner only)
+	orgGroup.Post("/invitations/accept", orgHandler.AcceptInvitation) // POST /api/org/invitations/accept (the invited user)
+	orgGroup.Delete("/invitations/:id", orgHandler.RevokeInvitation)  // DELETE /api/org/invitations/:id (owner only)
+}
+
+// RegisterImageRoutes sets up routes for avatars and company logos.  Images
+// are only served to signed-in users.
+func RegisterImageRoutes(app *fiber.App, imageHandler *imagehandler.ImageHandler) {
+	anyUser := middleware.RequireRole(authmodel.UserTypeApplicant, authmodel.UserTypeCompany)
+	company := middleware.RequireRole(authmodel.UserTypeCompany)
+
+	// The /api/user group already runs AuthMiddleware; see RegisterAuthRoutes.
+	userGroup := app.Group("/api/user")
+	userGroup.Post("/avatar", imageHandler.UploadAvatar)   // POST /api/user/avatar (multipart, field "image")
+	userGroup.Delete("/avatar", imageHandler.DeleteAvatar) // DELETE /api/user/avatar
+
+	// Group middleware matches by prefix, so the AuthMiddleware of /api/user
+	// also runs for /api/users; anyUser rejects the request should it not.
+	app.Get("/api/users/:id/avatar", anyUser, imageHandler.GetAvatar)                                 // GET /api/users/:id/avatar?size=small|medium|large
+	app.Post("/api/companies/me/logo", middleware.AuthMiddleware, company, imageHandler.UploadLogo)   // POST /api/companies/me/logo (multipart, field "image")
+	app.Delete("/api/companies/me/logo", middleware.AuthMiddleware, company, imageHandler.DeleteLogo) // DELETE /api/companies/me/logo
+	app.Get("/api/companies/:id/logo", middleware.AuthMiddleware, anyUser, imageHandler.GetLogo)      // GET /api/companies/:id/logo?size=small|medium|large
+}
+
+// RegisterAccountRoutes sets up the data export and account deletion routes.
+func RegisterAccountRoutes(app *fiber.App, accountHandler *accounthandler.AccountHandler) {
+	// The /api/user group already runs AuthMiddleware; see RegisterAuthRoutes.
+	userGroup := app.Group("/api/user")
+	userGroup.Get("/export", accountHandler.ExportData) // GET /api/user/export (ZIP of all personal data)
+	userGroup.Delete("", accountHandler.DeleteAccount)  // DELETE /api/user (requires the password)
+}
+
+// RegisterAuditRoutes sets up the audit log query route (organization owners
+// only, enforced by the service).
+func RegisterAuditRoutes(app *fiber.App, auditHandler *audithandler.AuditHandler) {
+	app.Get("/api/audit", middleware.AuthMiddleware, middleware.RequireRole(authmodel.UserTypeCompany), auditHandler.ListAuditLog) // GET /api/audit
 }
 
 func RegisterMessageRoutes(app *fiber.App, messageHandler *messagehandler.MessageHandler) {
 	messageGroup := app.Group("/api/messages")
-	messageGroup.Use(middleware.AuthMiddleware)                        // Protect message routes
+	messageGroup.Use(middleware.AuthMiddleware) // Protect message routes
+	messageGroup.Use(middleware.RequireRole(authmodel.UserTypeApplicant, authmodel.UserTypeCompany))
 	messageGroup.Post("/", messageHandler.SendMessage)                 // POST /api/messages
 	messageGroup.Get("/:userId", messageHandler.ViewMessages)          // GET /api/messages/:userId  (viewMessages)
-	messageGroup.Get("/message/:messageId", messageHandler.GetMessage) // GET /api/messages/message/:messageId
+	messageGroup.Get("/message/:messageId", messageHandler.GetMessage) // GET /api/messages/message/:messageId (participants only)
 }
 
 // RegisterRoutes sets up all routes for the application.  This is the function you call in main.go.
-func RegisterRoutes(app *fiber.App, authHandler *authhandler.AuthHandler, jobHandler *jobhandler.JobHandler, messageHandler *messagehandler.MessageHandler) {
+func RegisterRoutes(app *fiber.App, authHandler *authhandler.AuthHandler, jobHandler *jobhandler.JobHandler, messageHandler *messagehandler.MessageHandler, companyHandler *companyhandler.CompanyHandler, orgHandler *orghandler.OrgHandler, imageHandler *imagehandler.ImageHandler, accountHandler *accounthandler.AccountHandler, auditHandler *audithandler.AuditHandler) {
 	RegisterAuthRoutes(app, authHandler)
 	RegisterJobRoutes(app, jobHandler)
+	RegisterFeedRoutes(app, jobHandler)
+	RegisterCompanyRoutes(app, companyHandler)
+	RegisterOrgRoutes(app, orgHandler)
+	RegisterImageRoutes(app, imageHandler)
+	RegisterAccountRoutes(app, accountHandler)
+	RegisterAuditRoutes(app, auditHandler)
 	RegisterMessageRoutes(app, messageHandler)
 }
@@ -0,0 +1,270 @@
+package routes
+
+import (
+	"backend/handler/accounthandler"
+	"backend/handler/audithandler"
+	"backend/handler/authhandler"
+	"backend/handler/companyhandler"
+	"backend/handler/imagehandler"
+	"backend/handler/jobhandler"
+	"backend/handler/messagehandler"
+	"backend/handler/orghandler"
+	"backend/pkg/keyring"
+	"backend/pkg/middleware"
+	"backend/pkg/model/authmodel"
+	"backend/pkg/service/accountservice"
+	"backend/pkg/service/auditservice"
+	"backend/pkg/service/authservice"
+	"backend/pkg/service/companyservice"
+	"backend/pkg/service/imageservice"
+	"backend/pkg/service/jobservice"
+	"backend/pkg/service/messageservice"
+	"backend/pkg/service/orgservice"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// The services behind the handlers are nil interfaces: a request that gets
+// past the role checks panics in its handler and comes back as 500, which is
+// all these tests need to know.
+type (
+	stubJobService     struct{ jobservice.IJobService }
+	stubAuditService   struct{ auditservice.IAuditService }
+	stubCompanyService struct{ companyservice.ICompanyService }
+	stubOrgService     struct{ orgservice.IOrgService }
+	stubImageService   struct{ imageservice.IImageService }
+	stubAccountService struct{ accountservice.IAccountService }
+	stubMessageService struct{ messageservice.IMessageService }
+)
+
+// guestUserType is a user type no route lets in.
+const guestUserType = "guest"
+
+// guardedRoute is a route behind RequireRole, and RequireSelf for the routes
+// with a :userId of the caller.
+type guardedRoute struct {
+	method, path string
+	roles        []string // User types allowed in
+	self         bool     // The path names user 1, the caller
+}
+
+var (
+	company   = []string{authmodel.UserTypeCompany}
+	applicant = []string{authmodel.UserTypeApplicant}
+	anyUser   = []string{authmodel.UserTypeApplicant, authmodel.UserTypeCompany}
+)
+
+var guardedRoutes = []guardedRoute{
+	// Job posts
+	{"POST", "/api/jobs/import", company, false},
+	{"GET", "/api/jobs/export", company, false},
+	{"POST", "/api/jobs", company, false},
+	{"GET", "/api/jobs/search?q=go", anyUser, false},
+	{"GET", "/api/jobs/1", anyUser, false},
+	{"GET", "/api/jobs/user/1", anyUser, false},
+	{"PUT", "/api/jobs/1", company, false},
+	{"DELETE", "/api/jobs/1", company, false},
+	{"PUT", "/api/jobs/1/state", company, false},
+	{"GET", "/api/jobs/1/revisions", company, false},
+	{"GET", "/api/jobs", anyUser, false},
+	{"GET", "/api/jobs/company/1", anyUser, false},
+	{"GET", "/api/jobs/open", anyUser, false},
+	{"GET", "/api/jobs/closed", anyUser, false},
+
+	// Job applications
+	{"POST", "/api/jobs/1/apply", applicant, false},
+	{"GET", "/api/jobs/applications/1", anyUser, false},
+	{"PUT", "/api/jobs/applications/1", company, false},
+	{"DELETE", "/api/jobs/applications/1", applicant, false},
+	{"GET", "/api/jobs/1/applications", company, false},
+	{"GET", "/api/jobs/user/1/applications", anyUser, false},
+	{"GET", "/api/jobs/applications", anyUser, false},
+
+	// Saved jobs
+	{"POST", "/api/jobs/user/1/save/2", anyUser, true},
+	{"DELETE", "/api/jobs/user/1/unsave/2", anyUser, true},
+	{"GET", "/api/jobs/user/1/saved", anyUser, true},
+	{"GET", "/api/jobs/user/1/saved/2", anyUser, true},
+	{"GET", "/api/me/saved", anyUser, false},
+	{"POST", "/api/me/saved/2", anyUser, false},
+	{"GET", "/api/me/saved/2", anyUser, false},
+	{"DELETE", "/api/me/saved/2", anyUser, false},
+	{"GET", "/api/me/applications", anyUser, false},
+
+	// Two-factor authentication
+	{"POST", "/api/user/mfa/enroll", company, false},
+	{"POST", "/api/user/mfa/confirm", company, false},
+	{"POST", "/api/user/mfa/disable", company, false},
+	{"POST", "/api/user/mfa/recovery-codes", company, false},
+	{"PUT", "/api/user/mfa/policy", company, false},
+
+	// Company profiles and organizations
+	{"GET", "/api/companies/me", company, false},
+	{"POST", "/api/companies/me", company, false},
+	{"PUT", "/api/companies/me", company, false},
+	{"DELETE", "/api/companies/me", company, false},
+	{"GET", "/api/org", company, false},
+	{"PUT", "/api/org", company, false},
+	{"GET", "/api/org/members", company, false},
+	{"PUT", "/api/org/members/2", company, false},
+	{"DELETE", "/api/org/members/2", company, false},
+	{"POST", "/api/org/invitations", company, false},
+	{"GET", "/api/org/invitations", company, false},
+	{"POST", "/api/org/invitations/accept", company, false},
+	{"DELETE", "/api/org/invitations/1", company, false},
+	{"GET", "/api/audit", company, false},
+
+	// Images
+	{"GET", "/api/users/1/avatar", anyUser, false},
+	{"POST", "/api/companies/me/logo", company, false},
+	{"DELETE", "/api/companies/me/logo", company, false},
+	{"GET", "/api/companies/1/logo", anyUser, false},
+
+	// Messages
+	{"POST", "/api/messages", anyUser, false},
+	{"GET", "/api/messages/1", anyUser, false},
+	{"GET", "/api/messages/message/1", anyUser, false},
+}
+
+func newTestApp(t *testing.T) (*fiber.App, *keyring.Keyring) {
+	t.Helper()
+	key := keyring.NewHMACKey([]byte("routes-test-secret"))
+	kr, err := keyring.New(key.ID, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware.UseKeyring(kr)
+
+	app := fiber.New()
+	app.Use(recover.New())
+	RegisterRoutes(app,
+		authhandler.NewAuthHandler(&authservice.AuthService{}, nil),
+		jobhandler.NewJobHandler(stubJobService{}, nil),
+		messagehandler.NewMessageHandler(stubMessageService{}, nil),
+		companyhandler.NewCompanyHandler(stubCompanyService{}),
+		orghandler.NewOrgHandler(stubOrgService{}),
+		imagehandler.NewImageHandler(stubImageService{}),
+		accounthandler.NewAccountHandler(stubAccountService{}),
+		audithandler.NewAuditHandler(stubAuditService{}),
+	)
+	return app, kr
+}
+
+// request sends a route a request as the given user.
+func request(t *testing.T, app *fiber.App, kr *keyring.Keyring, route guardedRoute, userID uint, userType string) int {
+	t.Helper()
+	token, err := kr.Sign(jwt.MapClaims{
+		"user_id":        float64(userID),
+		"user_type":      userType,
+		"sid":            "session",
+		"email_verified": true,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(route.method, route.path, nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestGuardedRoutesLetAllowedRolesIn(t *testing.T) {
+	app, kr := newTestApp(t)
+	for _, route := range guardedRoutes {
+		for _, role := range route.roles {
+			t.Run(fmt.Sprintf("%s %s as %s", route.method, route.path, role), func(t *testing.T) {
+				status := request(t, app, kr, route, 1, role)
+				if status == fiber.StatusUnauthorized || status == fiber.StatusForbidden {
+					t.Errorf("status = %d, want the request to reach the handler", status)
+				}
+			})
+		}
+	}
+}
+
+func TestGuardedRoutesForbidOtherRoles(t *testing.T) {
+	app, kr := newTestApp(t)
+	for _, route := range guardedRoutes {
+		for _, role := range []string{authmodel.UserTypeApplicant, authmodel.UserTypeCompany, guestUserType} {
+			if contains(route.roles, role) {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s %s as %s", route.method, route.path, role), func(t *testing.T) {
+				if status := request(t, app, kr, route, 1, role); status != fiber.StatusForbidden {
+					t.Errorf("status = %d, want %d", status, fiber.StatusForbidden)
+				}
+			})
+		}
+	}
+}
+
+func TestSelfRoutesForbidOtherUsers(t *testing.T) {
+	app, kr := newTestApp(t)
+	for _, route := range guardedRoutes {
+		if !route.self {
+			continue
+		}
+		for _, role := range route.roles {
+			t.Run(fmt.Sprintf("%s %s as %s", route.method, route.path, role), func(t *testing.T) {
+				if status := request(t, app, kr, route, 2, role); status != fiber.StatusForbidden {
+					t.Errorf("status = %d, want %d", status, fiber.StatusForbidden)
+				}
+			})
+		}
+	}
+}
+
+// userRoutes are routes under /api/user, where the group runs AuthMiddleware.
+var userRoutes = []guardedRoute{
+	{"GET", "/api/user/profile", anyUser, false},
+	{"POST", "/api/user/avatar", anyUser, false},
+	{"DELETE", "/api/user/avatar", anyUser, false},
+	{"GET", "/api/user/export", anyUser, false},
+	{"DELETE", "/api/user", anyUser, false},
+}
+
+// countingSessions counts the session checks of AuthMiddleware.
+type countingSessions struct {
+	checks int
+}
+
+func (s *countingSessions) IsSessionActive(userID uint, sessionID string) (bool, error) {
+	s.checks++
+	return true, nil
+}
+
+func TestAuthMiddlewareRunsOnce(t *testing.T) {
+	app, kr := newTestApp(t)
+	sessions := &countingSessions{}
+	middleware.UseSessionValidator(sessions)
+	t.Cleanup(func() { middleware.UseSessionValidator(nil) })
+
+	for _, route := range append(append([]guardedRoute(nil), guardedRoutes...), userRoutes...) {
+		t.Run(fmt.Sprintf("%s %s", route.method, route.path), func(t *testing.T) {
+			sessions.checks = 0
+			request(t, app, kr, route, 1, route.roles[0])
+			if sessions.checks != 1 {
+				t.Errorf("the session was checked %d times, want once", sessions.checks)
+			}
+		})
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -34,17 +34,25 @@ class HomeScreenState extends State<HomeScreen> with WidgetsBindingObserver {
   bool isLoading = true;
   /// Stores the current search query.
   String _searchQuery = '';
+  /// Cursor of the next page of jobs; empty once every page is loaded.
+  String _nextCursor = '';
+  /// Indicates whether a further page is being loaded.
+  bool _isLoadingMore = false;
+  /// Loads the next page as the list nears its end.
+  final ScrollController _scrollController = ScrollController();
 
   @override
   void initState() {
     super.initState();
     WidgetsBinding.instance.addObserver(this);
+    _scrollController.addListener(_onScroll);
     fetchData();
   }
 
   @override
   void dispose() {
     WidgetsBinding.instance.removeObserver(this);
+    _scrollController.dispose();
     super.dispose();
   }
 
@@ -55,49 +63,103 @@ class HomeScreenState extends State<HomeScreen> with WidgetsBindingObserver {
     }
   }
 
-  /// Fetches job data from the API.
+  /// Fetches the first page of jobs from the API, replacing the list.
   Future<void> fetchData() async {
     setState(() => isLoading = true);
+    try {
+      final page = await _fetchPage('');
+      setState(() {
+        jobs = page.jobs;
+        _nextCursor = page.nextCursor;
+        filteredJobs = _filterJobs(_searchQuery);
+        isLoading = false;
+      });
+      _loadMoreIfShort();
+    } catch (e) {
+      print('Error fetching jobs: $e');
+      setState(() => isLoading = false);
+    }
+  }
+
+  /// Fetches the page after the loaded jobs and appends it.
+  Future<void> _loadMore() async {
+    if (isLoading || _isLoadingMore || _nextCursor.isEmpty) return;
+    final cursor = _nextCursor;
+    setState(() => _isLoadingMore = true);
+    try {
+      final page = await _fetchPage(cursor);
+      // A refresh meanwhile started the list over.
+      if (cursor != _nextCursor) {
+        setState(() => _isLoadingMore = false);
+        return;
+      }
+      setState(() {
+        jobs = [...jobs, ...page.jobs];
+        _nextCursor = page.nextCursor;
+        filteredJobs = _filterJobs(_searchQuery);
+        _isLoadingMore = false;
+      });
+      _loadMoreIfShort();
+    } catch (e) {
+      print('Error fetching more jobs: $e');
+      setState(() => _isLoadingMore = false);
+    }
+  }
+
+  void _onScroll() {
+    if (_scrollController.position.extentAfter < 300) {
+      _loadMore();
+    }
+  }
+
+  /// Keeps loading while the list is too short to scroll, e.g. when the
+  /// search hides most of a page.
+  void _loadMoreIfShort() {
+    WidgetsBinding.instance.addPostFrameCallback((_) {
+      if (!mounted || !_scrollController.hasClients) return;
+      if (_scrollController.position.maxScrollExtent <= 0) {
+        _loadMore();
+      }
+    });
+  }
+
+  /// Fetches one page of jobs; an empty cursor asks for the first one.
+  Future<_JobPage> _fetchPage(String cursor) async {
     String baseUrl = dotenv.env['BASE_URL'] ?? 'default_url';
 
     if (!baseUrl.startsWith('http')) {
       baseUrl = 'https://$baseUrl';
     }
 
-    Uri apiUri = Uri.parse('$baseUrl/api/jobs');
+    Uri apiUri = Uri.parse('$baseUrl/api/jobs').replace(queryParameters: {
+      'limit': '50',
+      if (cursor.isNotEmpty) 'cursor': cursor,
+    });
 
-    try {
-      String? token = await _storage.read(key: 'auth_token');
-
-      var response = await http.get(
-        apiUri,
-        headers: {
-          'Authorization': token != null ? 'Bearer $token' : '',
-          'Content-Type': 'application/json; charset=UTF-8',
-        },
+    String? token = await _storage.read(key: 'auth_token');
+
+    var response = await http.get(
+      apiUri,
+      headers: {
+        'Authorization': token != null ? 'Bearer $token' : '',
+        'Content-Type': 'application/json; charset=UTF-8',
+      },
+    );
+    if (response.statusCode == 200 || response.statusCode == 201) {
+      Map<String, dynamic> body = json.decode(response.body);
+      List<dynamic> jsonData = body['jobs'] ?? [];
+      return _JobPage(
+        jsonData.map((data) => Job.fromJson(data)).toList(),
+        body['next_cursor'] ?? '',
       );
-      if (response.statusCode == 200 || response.statusCode == 201) {
-        List<dynamic> jsonData = json.decode(response.body);
-        List<Job> fetchedJobs =
-            jsonData.map((data) => Job.fromJson(data)).toList();
-
-        setState(() {
-          jobs = fetchedJobs;
-          filteredJobs = _filterJobs(_searchQuery);
-          isLoading = false;
-        });
-      } else if (response.statusCode == 401) {
-        Map<String, String> user = await _storage.readAll();
-        print("userinfo : ${user}");
-        await _storage.deleteAll();
-
-        throw Exception('Failed to load jobs : (${response.statusCode})');
-      } else {
-        throw Exception('Failed to load jobs : (${response.statusCode})');
-      }
-    } catch (e) {
-      print('Error fetching jobs: $e');
-      setState(() => isLoading = false);
+    } else if (response.statusCode == 401) {
+      Map<String, String> user = await _storage.readAll();
+      print("userinfo : ${user}");
+      await _storage.deleteAll();
+
+      throw Exception('Failed to load jobs : (${response.statusCode})');
+    } else {
+      throw Exception('Failed to load jobs : (${response.statusCode})');
     }
   }
 
@@ -118,6 +180,7 @@ class HomeScreenState extends State<HomeScreen> with WidgetsBindingObserver {
       _searchQuery = query;
       filteredJobs = _filterJobs(query);
     });
+    _loadMoreIfShort();
     print("_searchQuery  :  ${_searchQuery}");
   }
 
@@ -165,8 +228,19 @@ class HomeScreenState extends State<HomeScreen> with WidgetsBindingObserver {
                                 "Failed   to load jobs or no job at the moment."), // แสดงข้อความเมื่อ jobs เป็น null
                           )
                         : ListView.builder(
-                            itemCount: filteredJobs.length,
+                            controller: _scrollController,
+                            physics: AlwaysScrollableScrollPhysics(),
+                            itemCount: filteredJobs.length +
+                                (_isLoadingMore ? 1 : 0),
                             itemBuilder: (context, index) {
+                              if (index == filteredJobs.length) {
+                                // แสดงตัวหมุนท้ายรายการขณะโหลดหน้าถัดไป
+                                return Padding(
+                                  padding: EdgeInsets.symmetric(vertical: 16),
+                                  child: Center(
+                                      child: CircularProgressIndicator()),
+                                );
+                              }
                               return JobCard(job: filteredJobs[index]);
                             },
                           ),
@@ -179,6 +253,15 @@ class HomeScreenState extends State<HomeScreen> with WidgetsBindingObserver {
   }
 }
 
+/// One page of the job listing.
+class _JobPage {
+  final List<Job> jobs;
+  /// Cursor of the following page; empty on the last page.
+  final String nextCursor;
+
+  _JobPage(this.jobs, this.nextCursor);
+}
+
 /// Represents a job object.
 class Job {
   final int id;
@@ -17,11 +17,17 @@ class ResetPasswordScreen extends StatefulWidget {
   ResetPasswordScreenState createState() => ResetPasswordScreenState();
 }
 
+/// The steps of a password reset: ask for a code, check it, set the password.
+enum _ResetStep { email, otp, password }
+
 /// State for the [ResetPasswordScreen] widget.
 class ResetPasswordScreenState extends State<ResetPasswordScreen> {
   /// Controller for the email input field.
   final TextEditingController _emailController = TextEditingController();
 
+  /// Controller for the OTP input field.
+  final TextEditingController _otpController = TextEditingController();
+
   /// Controller for the password input field.
   final TextEditingController _passwordController = TextEditingController();
 
@@ -29,6 +35,9 @@ class ResetPasswordScreenState extends State<ResetPasswordScreen> {
   final TextEditingController _confirmPasswordController =
   TextEditingController();
 
+  /// The step the user is on.
+  _ResetStep _step = _ResetStep.email;
+
   /// Indicates whether a password reset operation is in progress.
   bool isLoading = false;
 
@@ -41,169 +50,259 @@ class ResetPasswordScreenState extends State<ResetPasswordScreen> {
     _isObscured = true;
   }
 
-  /// Attempts to reset the user's password.
-  ///
-  /// Sends a request to the server with the user's email and new password.
-  /// Displays a success or error message based on the server's response.
-  Future<void> resetPassword() async {
+  /// Posts [body] to the auth endpoint at [path] and returns the response,
+  /// or null when the request failed to go out.
+  Future<http.Response?> _post(String path, Map<String, String> body) async {
     String baseUrl = dotenv.env['BASE_URL'] ?? 'http://your-api-url.com';
-    String apiUrl = '$baseUrl/auth/reset-password';
-
-    // Check if passwords match
-    if (_passwordController.text.trim() !=
-        _confirmPasswordController.text.trim()) {
-      ScaffoldMessenger.of(context)
-          .showSnackBar(SnackBar(content: Text("Passwords do not match")));
-      return;
-    }
 
     setState(() {
       isLoading = true;
     });
-
     try {
       var response = await http.post(
-        Uri.parse(apiUrl),
+        Uri.parse('$baseUrl/auth/$path'),
         headers: {'Content-Type': 'application/json'},
-        body: jsonEncode({
-          "email": _emailController.text.trim(),
-          "new_password": _passwordController.text.trim(),
-        }),
+        body: jsonEncode(body),
       );
 
       print("Response Status: ${response.statusCode}");
       print("Response Body: ${response.body}");
-
-      if (response.statusCode == 200) {
-        ScaffoldMessenger.of(context).showSnackBar(
-            SnackBar(content: Text("Password reset successful!")));
-
-        // Navigate back to the sign-in page after a successful reset.
-        Navigator.pop(context);
-      } else {
-        ScaffoldMessenger.of(context)
-            .showSnackBar(SnackBar(content: Text("Failed to reset password")));
-      }
+      return response;
     } catch (e) {
       print("Error: $e");
-      ScaffoldMessenger.of(context)
-          .showSnackBar(SnackBar(content: Text("An error occurred")));
+      _showMessage("An error occurred");
+      return null;
+    } finally {
+      if (mounted) {
+        setState(() {
+          isLoading = false;
+        });
+      }
     }
+  }
 
-    setState(() {
-      isLoading = false;
+  void _showMessage(String message) {
+    if (!mounted) return;
+    ScaffoldMessenger.of(context)
+        .showSnackBar(SnackBar(content: Text(message)));
+  }
+
+  /// The error the server sent, or [fallback].
+  String _errorOf(http.Response response, String fallback) {
+    try {
+      return jsonDecode(response.body)['error'] ?? fallback;
+    } catch (_) {
+      return fallback;
+    }
+  }
+
+  /// Asks the server to email a one-time code to the user.
+  ///
+  /// The server answers the same way whether or not the email is registered.
+  Future<void> requestOtp() async {
+    if (_emailController.text.trim().isEmpty) {
+      _showMessage("Please enter your email");
+      return;
+    }
+
+    var response = await _post('request-reset', {
+      "email": _emailController.text.trim(),
     });
+    if (response == null) return;
+
+    if (response.statusCode == 200) {
+      _showMessage("If the email is registered, a code has been sent to it");
+      setState(() {
+        _otpController.clear();
+        _step = _ResetStep.otp;
+      });
+    } else {
+      _showMessage(_errorOf(response, "Failed to send the code"));
+    }
+  }
+
+  /// Checks the code from the email before asking for the new password.
+  Future<void> verifyOtp() async {
+    if (_otpController.text.trim().isEmpty) {
+      _showMessage("Please enter the code from the email");
+      return;
+    }
+
+    var response = await _post('verify-otp', {
+      "email": _emailController.text.trim(),
+      "otp": _otpController.text.trim(),
+    });
+    if (response == null) return;
+
+    if (response.statusCode == 200) {
+      setState(() {
+        _step = _ResetStep.password;
+      });
+    } else {
+      _showMessage(_errorOf(response, "Invalid code"));
+    }
+  }
+
+  /// Attempts to reset the user's password.
+  ///
+  /// Sends the user's email, the verified code and the new password to the
+  /// server. Displays a success or error message based on the response.
+  Future<void> resetPassword() async {
+    // Check if passwords match
+    if (_passwordController.text.trim() !=
+        _confirmPasswordController.text.trim()) {
+      _showMessage("Passwords do not match");
+      return;
+    }
+
+    var response = await _post('reset-password', {
+      "email": _emailController.text.trim(),
+      "otp": _otpController.text.trim(),
+      "new_password": _passwordController.text.trim(),
+    });
+    if (response == null) return;
+
+    if (response.statusCode == 200) {
+      _showMessage("Password reset successful!");
+
+      // Navigate back to the sign-in page after a successful reset.
+      if (mounted) Navigator.pop(context);
+    } else {
+      _showMessage(_errorOf(response, "Failed to reset password"));
+    }
   }
 
   @override
   void dispose() {
     _emailController.dispose();
+    _otpController.dispose();
     _passwordController.dispose();
+    _confirmPasswordController.dispose();
     super.dispose();
   }
 
+  InputDecoration _decoration(String label, {Widget? suffixIcon}) {
+    return InputDecoration(
+      suffixIcon: suffixIcon,
+      fillColor: Colors.white,
+      filled: true,
+      labelText: label,
+      border: OutlineInputBorder(),
+      floatingLabelStyle: const TextStyle(color: Colors.black),
+      focusedBorder: const OutlineInputBorder(
+        borderSide: BorderSide(width: 2, color: Colors.grey),
+      ),
+    );
+  }
+
+  Widget _visibilityToggle() {
+    return IconButton(
+      onPressed: () {
+        setState(() {
+          _isObscured = !_isObscured;
+        });
+      },
+      icon: _isObscured
+          ? const Icon(Icons.visibility)
+          : const Icon(Icons.visibility_off),
+      padding: const EdgeInsetsDirectional.only(end: 12),
+    );
+  }
+
+  Widget _button(String label, VoidCallback onPressed) {
+    return SizedBox(
+      height: 52,
+      width: double.infinity,
+      child: ElevatedButton(
+        onPressed: onPressed,
+        style: ElevatedButton.styleFrom(
+          backgroundColor: Color(0xFF3498DB),
+          shape: RoundedRectangleBorder(
+            borderRadius: BorderRadius.circular(10),
+          ),
+        ),
+        child: Text(label, style: TextStyle(color: Colors.white)),
+      ),
+    );
+  }
+
+  /// The fields and button of the current step.
+  List<Widget> _stepFields() {
+    switch (_step) {
+      case _ResetStep.email:
+        return [
+          Text("Enter your email and we'll send you a code to reset your password."),
+          SizedBox(height: 20),
+          // Email input field
+          TextFormField(
+            controller: _emailController,
+            keyboardType: TextInputType.emailAddress,
+            style: TextStyle(color: Colors.black),
+            decoration: _decoration('Email'),
+          ),
+          SizedBox(height: 24),
+          isLoading
+              ? CircularProgressIndicator()
+              : _button('Send Code', requestOtp),
+        ];
+      case _ResetStep.otp:
+        return [
+          Text("Enter the code sent to ${_emailController.text.trim()}."),
+          SizedBox(height: 20),
+          // OTP input field
+          TextFormField(
+            controller: _otpController,
+            keyboardType: TextInputType.number,
+            style: TextStyle(color: Colors.black),
+            decoration: _decoration('Code'),
+          ),
+          SizedBox(height: 24),
+          isLoading
+              ? CircularProgressIndicator()
+              : _button('Verify Code', verifyOtp),
+          TextButton(
+            onPressed: isLoading ? null : requestOtp,
+            child: Text("Send a new code"),
+          ),
+        ];
+      case _ResetStep.password:
+        return [
+          // Password input field
+          TextFormField(
+            controller: _passwordController,
+            obscureText: _isObscured,
+            style: TextStyle(color: Colors.black),
+            decoration:
+            _decoration('Password', suffixIcon: _visibilityToggle()),
+          ),
+          SizedBox(height: 20),
+          // Confirm password input field
+          TextFormField(
+            controller: _confirmPasswordController,
+            obscureText: _isObscured,
+            style: TextStyle(color: Colors.black),
+            decoration: _decoration('Confirm Password',
+                suffixIcon: _visibilityToggle()),
+          ),
+          SizedBox(height: 24),
+          // Show a loading indicator or the reset password button.
+          isLoading
+              ? CircularProgressIndicator()
+              : _button('Reset Password', resetPassword),
+        ];
+    }
+  }
+
   @override
   Widget build(BuildContext context) {
     return Scaffold(
         appBar: AppBar(title: Text("Reset Password")),
         body: SafeArea(
             child: SingleChildScrollView(
-              physics: const NeverScrollableScrollPhysics(),
               child: Padding(
                 padding: EdgeInsets.all(16.0),
                 child: Column(
-                  children: [
-                    // Email input field
-                    TextFormField(
-                      controller: _emailController,
-                      style: TextStyle(color: Colors.black),
-                      decoration: InputDecoration(
-                        fillColor: Colors.white,
-                        filled: true,
-                        labelText: 'Email',
-                        border: OutlineInputBorder(),
-                        floatingLabelStyle: const TextStyle(color: Colors.black),
-                        focusedBorder: const OutlineInputBorder(
-                          borderSide: BorderSide(width: 2, color: Colors.grey),
-                        ),
-                      ),
-                    ),
-                    SizedBox(height: 20),
-                    // Password input field
-                    TextFormField(
-                      controller: _passwordController,
-                      obscureText: _isObscured,
-                      style: TextStyle(color: Colors.black),
-                      decoration: InputDecoration(
-                        suffixIcon: IconButton(
-                          onPressed: () {
-                            setState(() {
-                              _isObscured = !_isObscured;
-                            });
-                          },
-                          icon: _isObscured
-                              ? const Icon(Icons.visibility)
-                              : const Icon(Icons.visibility_off),
-                          padding: const EdgeInsetsDirectional.only(end: 12),
-                        ),
-                        fillColor: Colors.white,
-                        filled: true,
-                        labelText: 'Password',
-                        border: OutlineInputBorder(),
-                        floatingLabelStyle: const TextStyle(color: Colors.black),
-                        focusedBorder: const OutlineInputBorder(
-                          borderSide: BorderSide(width: 2, color: Colors.grey),
-                        ),
-                      ),
-                    ),
-                    SizedBox(height: 20),
-                    // Confirm password input field
-                    TextFormField(
-                      controller: _confirmPasswordController,
-                      obscureText: _isObscured,
-                      style: TextStyle(color: Colors.black),
-                      decoration: InputDecoration(
-                        suffixIcon: IconButton(
-                          onPressed: () {
-                            setState(() {
-                              _isObscured = !_isObscured;
-                            });
-                          },
-                          icon: _isObscured
-                              ? const Icon(Icons.visibility)
-                              : const Icon(Icons.visibility_off),
-                          padding: const EdgeInsetsDirectional.only(end: 12),
-                        ),
-                        fillColor: Colors.white,
-                        filled: true,
-                        labelText: 'Confirm Password',
-                        border: OutlineInputBorder(),
-                        floatingLabelStyle: const TextStyle(color: Colors.black),
-                        focusedBorder: const OutlineInputBorder(
-                          borderSide: BorderSide(width: 2, color: Colors.grey),
-                        ),
-                      ),
-                    ),
-                    SizedBox(height: 24),
-                    // Show a loading indicator or the reset password button.
-                    isLoading
-                        ? CircularProgressIndicator()
-                        : SizedBox(
-                      height: 52,
-                      width: double.infinity,
-                      child: ElevatedButton(
-                        onPressed: resetPassword,
-                        style: ElevatedButton.styleFrom(
-                          backgroundColor: Color(0xFF3498DB),
-                          shape: RoundedRectangleBorder(
-                            borderRadius: BorderRadius.circular(10),
-                          ),
-                        ),
-                        child: const Text('Reset Password',
-                            style: TextStyle(color: Colors.white)),
-                      ),
-                    )
-                  ],
+                  children: _stepFields(),
                 ),
               ),
             )));